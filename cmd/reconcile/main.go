@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"financial-transaction-system/internal/config"
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// reconcile checks the cached accounts.balance projection against the
+// double-entry postings. It exits non-zero when any account disagrees.
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	database, err := db.NewConnection(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	ledgerService := services.NewLedgerService(db.NewLedgerRepository(database), db.NewAccountRepository(database))
+
+	// Single account: print its ledger balance
+	if len(os.Args) > 1 {
+		accountID, err := uuid.Parse(os.Args[1])
+		if err != nil {
+			log.Fatal("Usage: go run ./cmd/reconcile [account-id]")
+		}
+
+		balance, err := ledgerService.GetLedgerBalance(accountID)
+		if err != nil {
+			log.Fatalf("Failed to get ledger balance: %v", err)
+		}
+		fmt.Printf("Account %s ledger balance: %s\n", accountID, balance.StringFixed(2))
+		return
+	}
+
+	mismatches, err := ledgerService.Reconcile()
+	if err != nil {
+		log.Fatalf("Failed to reconcile balances: %v", err)
+	}
+
+	if len(mismatches) == 0 {
+		fmt.Println("All account balances match the ledger")
+		return
+	}
+
+	for _, m := range mismatches {
+		fmt.Printf("MISMATCH %s (%s): cached %s %s, ledger %s %s\n",
+			m.AccountNumber, m.AccountID,
+			m.CachedBalance.StringFixed(2), m.Currency,
+			m.LedgerBalance.StringFixed(2), m.Currency,
+		)
+	}
+	os.Exit(1)
+}
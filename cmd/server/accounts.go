@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"financial-transaction-system/internal/models"
+	"financial-transaction-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func handleCreateAccount(accountService *services.AccountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		var req models.CreateAccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		account, err := accountService.CreateAccount(userID, &req, requestMeta(c))
+		if err != nil {
+			c.JSON(accountErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, account)
+	}
+}
+
+func handleListAccounts(accountService *services.AccountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		accounts, err := accountService.ListAccounts(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"accounts": accounts})
+	}
+}
+
+func handleGetAccount(accountService *services.AccountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		accountID, ok := parseUUIDParam(c, "id", "invalid account ID")
+		if !ok {
+			return
+		}
+
+		account, err := accountService.GetAccount(userID, accountID)
+		if err != nil {
+			c.JSON(accountErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, account)
+	}
+}
+
+func handleGetAccountBalance(accountService *services.AccountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		accountID, ok := parseUUIDParam(c, "id", "invalid account ID")
+		if !ok {
+			return
+		}
+
+		balance, err := accountService.GetBalance(userID, accountID)
+		if err != nil {
+			c.JSON(accountErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, balance)
+	}
+}
+
+func handleUpdateAccount(accountService *services.AccountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		accountID, ok := parseUUIDParam(c, "id", "invalid account ID")
+		if !ok {
+			return
+		}
+
+		var req models.UpdateAccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		account, err := accountService.UpdateAccount(userID, accountID, &req, requestMeta(c))
+		if err != nil {
+			c.JSON(accountErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, account)
+	}
+}
+
+func handleUpdateAccountStatus(accountService *services.AccountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID := c.MustGet("user_id").(uuid.UUID)
+
+		accountID, ok := parseUUIDParam(c, "id", "invalid account ID")
+		if !ok {
+			return
+		}
+
+		var req models.UpdateAccountStatusRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		account, err := accountService.SetStatus(actorID, accountID, &req, requestMeta(c))
+		if err != nil {
+			c.JSON(accountErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, account)
+	}
+}
+
+func accountErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrAccountNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrAccountStatusChange):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrAccountClosed):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// parseUUIDParam reads a UUID path parameter and writes a 400 response when it is malformed
+func parseUUIDParam(c *gin.Context, name, message string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"financial-transaction-system/internal/auth"
+	"financial-transaction-system/internal/models"
+	"financial-transaction-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// apiKeyRoutes lists the only routes an API key may call and the scope each
+// one needs. Every other route, including managing keys themselves, needs a
+// login.
+var apiKeyRoutes = map[string]string{
+	"GET /api/v1/accounts":                  models.APIKeyScopeReadBalances,
+	"GET /api/v1/accounts/:id":              models.APIKeyScopeReadBalances,
+	"GET /api/v1/accounts/:id/balance":      models.APIKeyScopeReadBalances,
+	"GET /api/v1/accounts/:id/transactions": models.APIKeyScopeReadTransactions,
+	"GET /api/v1/accounts/:id/statements":   models.APIKeyScopeReadTransactions,
+	"GET /api/v1/transactions/:id":          models.APIKeyScopeReadTransactions,
+	"GET /api/v1/transactions/:id/history":  models.APIKeyScopeReadTransactions,
+	"POST /api/v1/transactions/transfer":    models.APIKeyScopeInitiateTransfers,
+}
+
+// authenticateAPIKey is the part of authMiddleware for requests that carry an
+// X-API-Key header. It sets the same context keys as a login would, with
+// synthesized claims that have no session and no auth_time, so transfers that
+// need step-up authentication wait for the owner to confirm them.
+func authenticateAPIKey(c *gin.Context, apiKeyService *services.APIKeyService, rawKey string) {
+	key, user, err := apiKeyService.Authenticate(rawKey, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAPIKey):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAPIKeyIPNotAllowed), errors.Is(err, services.ErrAPIKeyBusinessOnly):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			logrus.WithError(err).Error("Failed to check API key")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Authentication is temporarily unavailable"})
+		}
+		c.Abort()
+		return
+	}
+
+	scope, ok := apiKeyRoutes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint cannot be used with an API key"})
+		c.Abort()
+		return
+	}
+	if !hasScope(key.Scopes, scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing the " + scope + " scope"})
+		c.Abort()
+		return
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("session_id", "")
+	c.Set("claims", &auth.Claims{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		TokenType: "api_key",
+	})
+	c.Set("api_key", key)
+	c.Next()
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func apiKeyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrAPIKeyBusinessOnly):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrAPIKeyLimitReached):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidAPIKeyName),
+		errors.Is(err, services.ErrInvalidAPIKeyScope),
+		errors.Is(err, services.ErrInvalidAPIKeyIP),
+		errors.Is(err, services.ErrInvalidAPIKeyExpiry):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondAPIKeyError answers a failed key request; unexpected errors are
+// logged and described only by failure
+func respondAPIKeyError(c *gin.Context, err error, failure string) {
+	status := apiKeyErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		logrus.WithError(err).Error("Failed to " + failure)
+		c.JSON(status, gin.H{"error": "failed to " + failure})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+func handleCreateAPIKey(apiKeyService *services.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		var req models.CreateAPIKeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		response, err := apiKeyService.Create(userID, &req, requestMeta(c))
+		if err != nil {
+			respondAPIKeyError(c, err, "create API key")
+			return
+		}
+
+		c.JSON(http.StatusCreated, response)
+	}
+}
+
+func handleListAPIKeys(apiKeyService *services.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		keys, err := apiKeyService.List(userID)
+		if err != nil {
+			respondAPIKeyError(c, err, "list API keys")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+	}
+}
+
+func handleRevokeAPIKey(apiKeyService *services.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		keyID, ok := parseUUIDParam(c, "id", "invalid API key ID")
+		if !ok {
+			return
+		}
+
+		if err := apiKeyService.Revoke(userID, keyID, requestMeta(c)); err != nil {
+			respondAPIKeyError(c, err, "revoke API key")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"financial-transaction-system/internal/audit"
+	"financial-transaction-system/internal/config"
+	"financial-transaction-system/internal/models"
+	"financial-transaction-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// auditExportWriteTimeout bounds how long a client may take to read one
+// batch of an export; it replaces the server-wide write timeout
+const auditExportWriteTimeout = time.Minute
+
+func handleListAuditLogs(auditService *services.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseAuditLogFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		logs, err := auditService.ListLogs(filter)
+		if err != nil {
+			c.JSON(auditLogErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, logs)
+	}
+}
+
+// handleExportAuditLogs streams the matching entries as JSON Lines or CEF.
+// Once the first batch is written the status is committed, so a later
+// failure can only be logged and the response cut short.
+func handleExportAuditLogs(auditService *services.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseAuditLogFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		format := c.DefaultQuery("format", "jsonl")
+		var contentType string
+		var encode func(*models.AuditLog) ([]byte, error)
+		switch format {
+		case "jsonl":
+			contentType = "application/x-ndjson"
+			encode = func(log *models.AuditLog) ([]byte, error) { return json.Marshal(log) }
+		case "cef":
+			contentType = "text/plain; charset=utf-8"
+			encode = func(log *models.AuditLog) ([]byte, error) { return []byte(audit.FormatCEF(log)), nil }
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be jsonl or cef"})
+			return
+		}
+
+		controller := http.NewResponseController(c.Writer)
+		started := false
+		start := func() {
+			c.Header("Content-Type", contentType)
+			c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-logs.%s"`, format))
+			c.Status(http.StatusOK)
+			started = true
+		}
+
+		err = auditService.ExportLogs(filter, func(logs []*models.AuditLog) error {
+			if !started {
+				start()
+			}
+			_ = controller.SetWriteDeadline(time.Now().Add(auditExportWriteTimeout))
+
+			for _, log := range logs {
+				line, err := encode(log)
+				if err != nil {
+					return err
+				}
+				if _, err := c.Writer.Write(append(line, '\n')); err != nil {
+					return err
+				}
+			}
+			return controller.Flush()
+		})
+		if err != nil {
+			if !started {
+				c.JSON(auditLogErrorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+			logrus.WithError(err).Error("Audit log export aborted")
+			c.Abort()
+			return
+		}
+
+		if !started {
+			start()
+		}
+	}
+}
+
+// parseAuditLogFilter reads the audit log query parameters
+func parseAuditLogFilter(c *gin.Context) (*models.AuditLogFilter, error) {
+	filter := &models.AuditLogFilter{}
+
+	uuidParams := map[string]**uuid.UUID{
+		"user_id":        &filter.UserID,
+		"account_id":     &filter.AccountID,
+		"transaction_id": &filter.TransactionID,
+		"entity_id":      &filter.EntityID,
+	}
+	for name, field := range uuidParams {
+		if value := c.Query(name); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+			*field = &id
+		}
+	}
+
+	if value := c.Query("action"); value != "" {
+		action := models.AuditAction(value)
+		filter.Action = &action
+	}
+
+	if value := c.Query("entity_type"); value != "" {
+		filter.EntityType = &value
+	}
+
+	if value := c.Query("ip_address"); value != "" {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip_address")
+		}
+		filter.IPAddress = &ip
+	}
+
+	if value := c.Query("start_date"); value != "" {
+		start, _, err := parseQueryTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start_date: %w", err)
+		}
+		filter.StartDate = &start
+	}
+
+	if value := c.Query("end_date"); value != "" {
+		end, dateOnly, err := parseQueryTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end_date: %w", err)
+		}
+		if dateOnly {
+			end = end.AddDate(0, 0, 1)
+		}
+		filter.EndDate = &end
+	}
+
+	if value := c.Query("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page")
+		}
+		filter.Page = page
+	}
+
+	if value := c.Query("page_size"); value != "" {
+		pageSize, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page_size")
+		}
+		filter.PageSize = pageSize
+	}
+
+	return filter, nil
+}
+
+func auditLogErrorStatus(err error) int {
+	if errors.Is(err, services.ErrInvalidAuditLogFilter) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// newAuditSigner loads the checkpoint signing key, if one is configured
+func newAuditSigner(cfg *config.Config) (*audit.Signer, error) {
+	if cfg.Audit.SigningKey == "" {
+		return nil, nil
+	}
+	return audit.NewSigner(cfg.Audit.SigningKey)
+}
+
+// publishAuditCheckpoints periodically signs the head of the audit chain
+func publishAuditCheckpoints(auditService *services.AuditService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := auditService.PublishCheckpoint(); err != nil {
+			logrus.WithError(err).Error("Failed to publish audit checkpoint")
+		}
+	}
+}
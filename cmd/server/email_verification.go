@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"financial-transaction-system/internal/config"
+	"financial-transaction-system/internal/mail"
+	"financial-transaction-system/internal/models"
+	"financial-transaction-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+func newMailer(cfg *config.Config) (mail.Mailer, error) {
+	switch cfg.Mail.Backend {
+	case "smtp":
+		return mail.NewSMTPMailer(cfg.Mail.SMTPHost, cfg.Mail.SMTPPort, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword, cfg.Mail.From), nil
+	case "file":
+		return mail.NewFileMailer(cfg.Mail.FileDir, cfg.Mail.From)
+	case "memory":
+		return mail.NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_BACKEND %q", cfg.Mail.Backend)
+	}
+}
+
+// requireVerifiedEmail keeps users who have not verified their email address
+// away from routes that open accounts or move money
+func requireVerifiedEmail(verificationService *services.EmailVerificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		verified, err := verificationService.IsVerified(userID)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+		if !verified {
+			c.JSON(http.StatusForbidden, gin.H{"error": services.ErrEmailNotVerified.Error()})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func handleVerifyEmail(verificationService *services.EmailVerificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.VerifyEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := verificationService.Verify(req.Token, requestMeta(c)); err != nil {
+			respondVerificationError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Email address verified"})
+	}
+}
+
+func handleResendVerification(verificationService *services.EmailVerificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		if err := verificationService.Resend(c.Request.Context(), userID); err != nil {
+			respondVerificationError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+	}
+}
+
+func respondVerificationError(c *gin.Context, err error) {
+	status := verificationErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		logrus.WithError(err).Error("Email verification request failed")
+		c.JSON(status, gin.H{"error": "email verification request failed"})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+func verificationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidVerificationToken):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrEmailAlreadyVerified):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrVerificationThrottled):
+		return http.StatusTooManyRequests
+	case errors.Is(err, services.ErrUserNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"financial-transaction-system/internal/models"
+	"financial-transaction-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func handleListFraudAlerts(fraudService *services.FraudService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseFraudAlertFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		alerts, err := fraudService.ListAlerts(filter)
+		if err != nil {
+			c.JSON(fraudAlertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, alerts)
+	}
+}
+
+func handleGetFraudAlert(fraudService *services.FraudService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alertID, ok := parseUUIDParam(c, "id", "invalid fraud alert ID")
+		if !ok {
+			return
+		}
+
+		alert, err := fraudService.GetAlert(alertID)
+		if err != nil {
+			c.JSON(fraudAlertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, alert)
+	}
+}
+
+func handleAssignFraudAlert(fraudService *services.FraudService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID := c.MustGet("user_id").(uuid.UUID)
+
+		alertID, ok := parseUUIDParam(c, "id", "invalid fraud alert ID")
+		if !ok {
+			return
+		}
+
+		var req models.AssignFraudAlertRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		alert, err := fraudService.AssignAlert(actorID, alertID, &req, requestMeta(c))
+		if err != nil {
+			c.JSON(fraudAlertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, alert)
+	}
+}
+
+func handleUpdateFraudAlert(fraudService *services.FraudService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID := c.MustGet("user_id").(uuid.UUID)
+
+		alertID, ok := parseUUIDParam(c, "id", "invalid fraud alert ID")
+		if !ok {
+			return
+		}
+
+		var req models.UpdateFraudAlertRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		alert, err := fraudService.UpdateAlert(actorID, alertID, &req, requestMeta(c))
+		if err != nil {
+			c.JSON(fraudAlertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, alert)
+	}
+}
+
+// parseFraudAlertFilter reads the alert listing query parameters
+func parseFraudAlertFilter(c *gin.Context) (*models.FraudAlertFilter, error) {
+	filter := &models.FraudAlertFilter{}
+
+	uuidParams := map[string]**uuid.UUID{
+		"user_id":        &filter.UserID,
+		"account_id":     &filter.AccountID,
+		"transaction_id": &filter.TransactionID,
+		"assigned_to":    &filter.AssignedTo,
+	}
+	for name, field := range uuidParams {
+		if value := c.Query(name); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+			*field = &id
+		}
+	}
+
+	if value := c.Query("rule_name"); value != "" {
+		filter.RuleName = &value
+	}
+
+	if value := c.Query("severity"); value != "" {
+		severity := models.FraudSeverity(value)
+		filter.Severity = &severity
+	}
+
+	if value := c.Query("status"); value != "" {
+		status := models.FraudStatus(value)
+		filter.Status = &status
+	}
+
+	intParams := map[string]**int{
+		"min_risk_score": &filter.MinRiskScore,
+		"max_risk_score": &filter.MaxRiskScore,
+	}
+	for name, field := range intParams {
+		if value := c.Query(name); value != "" {
+			score, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+			*field = &score
+		}
+	}
+
+	if value := c.Query("start_date"); value != "" {
+		start, _, err := parseQueryTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start_date: %w", err)
+		}
+		filter.StartDate = &start
+	}
+
+	if value := c.Query("end_date"); value != "" {
+		end, dateOnly, err := parseQueryTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end_date: %w", err)
+		}
+		if dateOnly {
+			end = end.AddDate(0, 0, 1)
+		}
+		filter.EndDate = &end
+	}
+
+	if value := c.Query("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page")
+		}
+		filter.Page = page
+	}
+
+	if value := c.Query("page_size"); value != "" {
+		pageSize, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page_size")
+		}
+		filter.PageSize = pageSize
+	}
+
+	return filter, nil
+}
+
+func fraudAlertErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrFraudAlertNotFound), errors.Is(err, services.ErrAssigneeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrFraudAlertClosed),
+		errors.Is(err, services.ErrInvalidAlertTransition),
+		errors.Is(err, services.ErrInvalidStatusTransition):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrResolutionNotesRequired),
+		errors.Is(err, services.ErrInvalidFraudAlertFilter),
+		errors.Is(err, services.ErrAssigneeNotAnalyst):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrInsufficientFunds):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
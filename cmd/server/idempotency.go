@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"financial-transaction-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// responseRecorder keeps a copy of the response body so it can be replayed
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency middleware: a request carrying an Idempotency-Key header is
+// processed at most once per user and key. Retries get the original response.
+// The request is fingerprinted by its actual path, not the route pattern, so
+// a key reused on another resource, such as refunding a different
+// transaction, is rejected rather than replayed.
+func idempotencyMiddleware(idempotencyService *services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := idempotencyService.Begin(userID, key, c.Request.Method, c.Request.URL.Path, body)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidIdempotencyKey):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			case errors.Is(err, services.ErrIdempotencyKeyMismatch):
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			c.Abort()
+			return
+		}
+
+		if record != nil {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(*record.ResponseStatus, "application/json; charset=utf-8", record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = recorder
+
+		completed := false
+		defer func() {
+			if !completed {
+				if err := idempotencyService.Release(userID, key); err != nil {
+					logrus.WithError(err).Error("Failed to release idempotency key")
+				}
+			}
+		}()
+
+		c.Next()
+
+		// Server errors are not cached so the client can retry with the same key
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		if err := idempotencyService.Complete(userID, key, recorder.Status(), recorder.body.Bytes()); err != nil {
+			logrus.WithError(err).Error("Failed to store idempotent response")
+			return
+		}
+		completed = true
+	}
+}
+
+// purgeIdempotencyKeys periodically deletes expired idempotency keys
+func purgeIdempotencyKeys(idempotencyService *services.IdempotencyService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, err := idempotencyService.PurgeExpired()
+		if err != nil {
+			logrus.WithError(err).Error("Failed to purge expired idempotency keys")
+			continue
+		}
+		if purged > 0 {
+			logrus.WithField("count", purged).Info("Purged expired idempotency keys")
+		}
+	}
+}
@@ -0,0 +1,110 @@
+//go:build integration
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/dbtest"
+	"financial-transaction-system/internal/models"
+	"financial-transaction-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestIdempotencyKeyIsBoundToTheRequestPath(t *testing.T) {
+	database := dbtest.Open(t)
+	user := dbtest.CreateUser(t, database, models.UserRoleAdmin)
+	idempotencyService := services.NewIdempotencyService(db.NewIdempotencyRepository(database), time.Hour)
+
+	calls := map[string]int{}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/admin/transactions/:id/reverse",
+		func(c *gin.Context) { c.Set("user_id", user.ID) },
+		idempotencyMiddleware(idempotencyService),
+		func(c *gin.Context) {
+			calls[c.Param("id")]++
+			c.JSON(http.StatusOK, gin.H{"id": c.Param("id")})
+		},
+	)
+
+	send := func(id uuid.UUID) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/transactions/"+id.String()+"/reverse", nil)
+		req.Header.Set("Idempotency-Key", "reverse-"+user.ID.String())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first, second := uuid.New(), uuid.New()
+
+	if w := send(first); w.Code != http.StatusOK {
+		t.Fatalf("first request: status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w := send(first)
+	if w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry: status %d, replayed %q; want a replayed %d", w.Code, w.Header().Get("Idempotent-Replayed"), http.StatusOK)
+	}
+
+	if w := send(second); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key on another transaction: status %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+
+	if calls[first.String()] != 1 || calls[second.String()] != 0 {
+		t.Errorf("handler ran %d times for the first transaction and %d for the second, want 1 and 0",
+			calls[first.String()], calls[second.String()])
+	}
+}
+
+func TestIdempotencyKeyIsReleasedAfterServerErrors(t *testing.T) {
+	database := dbtest.Open(t)
+	user := dbtest.CreateUser(t, database, models.UserRoleCustomer)
+	idempotencyService := services.NewIdempotencyService(db.NewIdempotencyRepository(database), time.Hour)
+
+	calls := 0
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/transactions/deposit",
+		func(c *gin.Context) { c.Set("user_id", user.ID) },
+		idempotencyMiddleware(idempotencyService),
+		func(c *gin.Context) {
+			calls++
+			if calls == 1 {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database unavailable"})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"attempt": calls})
+		},
+	)
+
+	key := "deposit-" + uuid.NewString()
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/transactions/deposit", nil)
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send(); w.Code != http.StatusInternalServerError {
+		t.Fatalf("first request: status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if w := send(); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after a server error: status %d, replayed %q; want a fresh %d",
+			w.Code, w.Header().Get("Idempotent-Replayed"), http.StatusCreated)
+	}
+	if w := send(); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry after success: status %d, replayed %q; want a replayed %d",
+			w.Code, w.Header().Get("Idempotent-Replayed"), http.StatusCreated)
+	}
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"financial-transaction-system/internal/auth"
+	"financial-transaction-system/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkJWTSecret refuses the placeholder HMAC secret in release mode; it is
+// published in the examples, so anyone could forge tokens signed with it
+func checkJWTSecret(cfg *config.Config) error {
+	if cfg.Server.Mode != gin.ReleaseMode || cfg.JWT.Algorithm != "HS256" {
+		return nil
+	}
+	if cfg.JWT.Secret == config.DefaultJWTSecret {
+		return errors.New("JWT_SECRET is the default placeholder; set a long random secret or use RS256/EdDSA keys")
+	}
+	return nil
+}
+
+// handleJWKS publishes the public keys other services verify tokens with.
+// Keys only change on restart, so clients may cache the set briefly.
+func handleJWKS(jwtManager *auth.JWTManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwtManager.JWKS())
+	}
+}
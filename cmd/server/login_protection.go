@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"financial-transaction-system/internal/auth"
+	"financial-transaction-system/internal/config"
+	"financial-transaction-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// newLoginFailureCounter sets up the configured store for per-IP login
+// failure counts
+func newLoginFailureCounter(cfg *config.Config) (auth.FailureCounter, error) {
+	switch cfg.LoginProtection.Store {
+	case "memory":
+		return auth.NewMemoryFailureCounter(), nil
+	case "redis":
+		client, err := newRedisClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewRedisFailureCounter(client), nil
+	default:
+		return nil, fmt.Errorf("unknown LOGIN_THROTTLE_STORE %q", cfg.LoginProtection.Store)
+	}
+}
+
+// respondLoginError answers a failed login. Refusals carry Retry-After, in
+// seconds, so clients know when to try again.
+func respondLoginError(c *gin.Context, err error) {
+	var blocked *services.LoginBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		retryAfter := int(math.Ceil(time.Until(blocked.RetryAt).Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidCredentials), errors.Is(err, services.ErrAccountDeactivated):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		logrus.WithError(err).Error("Login failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "login failed"})
+	}
+}
+
+func handleGetLoginLockState(loginProtectionService *services.LoginProtectionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := parseUUIDParam(c, "id", "invalid user ID")
+		if !ok {
+			return
+		}
+
+		state, err := loginProtectionService.LockState(userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, state)
+	}
+}
+
+func handleUnlockUser(loginProtectionService *services.LoginProtectionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID := c.MustGet("user_id").(uuid.UUID)
+
+		userID, ok := parseUUIDParam(c, "id", "invalid user ID")
+		if !ok {
+			return
+		}
+
+		state, err := loginProtectionService.Unlock(actorID, userID, requestMeta(c))
+		if err != nil {
+			if errors.Is(err, services.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			logrus.WithError(err).Error("Failed to unlock user")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlock user"})
+			return
+		}
+
+		c.JSON(http.StatusOK, state)
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,11 +14,13 @@ import (
 	"financial-transaction-system/internal/auth"
 	"financial-transaction-system/internal/config"
 	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/fraud"
 	"financial-transaction-system/internal/models"
 	"financial-transaction-system/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 )
 
@@ -28,22 +32,100 @@ func main() {
 
 	setupLogging(cfg)
 
+	if err := checkJWTSecret(cfg); err != nil {
+		logrus.WithError(err).Fatal("Refusing to start in release mode")
+	}
+
 	database, err := db.NewConnection(cfg)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to connect to database")
 	}
 	defer database.Close()
 
-	jwtManager := auth.NewJWTManager(cfg)
+	jwtManager, err := auth.NewJWTManager(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to set up token signing")
+	}
+	denylist, err := newTokenDenylist(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to set up the token denylist")
+	}
+	auditRepo := db.NewAuditLogRepository(database)
+	auditSigner, err := newAuditSigner(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load audit signing key")
+	}
+	auditService := services.NewAuditService(database, auditRepo, auditSigner)
 	userRepo := db.NewUserRepository(database)
-	userService := services.NewUserService(userRepo, jwtManager)
+	mfaCipher, err := newMFACipher(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load MFA encryption key")
+	}
+	if mfaCipher == nil {
+		logrus.Warn("MFA_ENCRYPTION_KEY is not set; two-factor authentication is unavailable")
+	}
+	mfaService := services.NewMFAService(database, db.NewMFARepository(database), userRepo, mfaCipher, cfg.MFA.Issuer, auditService)
+	mailer, err := newMailer(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to set up the mailer")
+	}
+	verificationService := services.NewEmailVerificationService(userRepo, jwtManager, mailer, auditService, cfg.EmailVerification.LinkURL, cfg.GetEmailVerificationResendInterval())
+	loginFailures, err := newLoginFailureCounter(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to set up the login failure counter")
+	}
+	loginProtectionService := services.NewLoginProtectionService(database, userRepo, loginFailures, mailer, auditService, services.LoginProtectionPolicyFromConfig(cfg.LoginProtection))
+	passwordPolicy, err := newPasswordPolicy(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load the password policy")
+	}
+	passwordHasher, err := newPasswordHasher(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to set up password hashing")
+	}
+	passwordPolicyService := services.NewPasswordPolicyService(db.NewPasswordHistoryRepository(database), passwordPolicy, cfg.PasswordPolicy.HistoryCount)
+	refreshTokenRepo := db.NewRefreshTokenRepository(database)
+	userService := services.NewUserService(database, userRepo, refreshTokenRepo, jwtManager, denylist, mfaService, verificationService, passwordPolicyService, passwordHasher, loginProtectionService, auditService)
+	passwordResetService := services.NewPasswordResetService(database, userRepo, db.NewPasswordResetRepository(database), userService, passwordPolicyService, mailer, auditService, cfg.PasswordReset.LinkURL, cfg.GetPasswordResetTokenTTL(), cfg.GetPasswordResetRequestInterval(), loginFailures, cfg.PasswordReset.IPMaxRequests, cfg.GetPasswordResetIPWindow())
+	accountRepo := db.NewAccountRepository(database)
+	accountService := services.NewAccountService(accountRepo, auditService)
+	transactionRepo := db.NewTransactionRepository(database)
+	ledgerRepo := db.NewLedgerRepository(database)
+	ledgerService := services.NewLedgerService(ledgerRepo, accountRepo)
+	fraudRepo := db.NewFraudAlertRepository(database)
+	fraudEngine := fraud.NewEngine(fraud.RulesFromConfig(cfg.Fraud), transactionRepo)
+	stepUpRepo := db.NewStepUpRepository(database)
+	transactionService := services.NewTransactionService(database, accountRepo, transactionRepo, ledgerService, auditService, fraudEngine, fraudRepo, stepUpRepo, services.StepUpPolicyFromConfig(cfg.StepUp))
+	stepUpService := services.NewStepUpService(database, stepUpRepo, userRepo, fraudRepo, mfaService, transactionService, auditService, cfg.StepUp.MaxAttempts)
+	idempotencyRepo := db.NewIdempotencyRepository(database)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, cfg.GetIdempotencyKeyTTL())
+	fraudService := services.NewFraudService(database, fraudRepo, userRepo, accountRepo, transactionRepo, auditService, transactionService)
+	statementRepo := db.NewStatementRepository(database)
+	statementService := services.NewStatementService(database, accountRepo, transactionRepo, ledgerRepo, statementRepo)
+	apiKeyService := services.NewAPIKeyService(database, db.NewAPIKeyRepository(database), accountRepo, userRepo, auditService)
+
+	go purgeIdempotencyKeys(idempotencyService, time.Hour)
+	go purgeRefreshTokens(userService, time.Hour)
+	go purgePasswordResetTokens(passwordResetService, time.Hour)
+	go passwordResetService.ProcessRequests()
+	go expireStepUpChallenges(stepUpService, time.Minute)
+	if auditSigner != nil {
+		go publishAuditCheckpoints(auditService, cfg.GetAuditCheckpointInterval())
+	} else {
+		logrus.Warn("AUDIT_SIGNING_KEY is not set; audit checkpoints will not be published")
+	}
 
 	gin.SetMode(cfg.Server.Mode)
 	router := gin.New()
+	// Only believe X-Forwarded-For from our own proxies: client IPs feed API
+	// key allowlists, login throttling and the audit trail
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logrus.WithError(err).Fatal("Invalid TRUSTED_PROXIES")
+	}
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
-	setupRoutes(router, userService, jwtManager)
+	setupRoutes(router, cfg, userService, mfaService, verificationService, passwordResetService, loginProtectionService, accountService, transactionService, stepUpService, statementService, fraudService, auditService, idempotencyService, apiKeyService, jwtManager, denylist)
 
 	srv := &http.Server{
 		Addr:           fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port),
@@ -96,7 +178,7 @@ func setupLogging(cfg *config.Config) {
 	}
 }
 
-func setupRoutes(router *gin.Engine, userService *services.UserService, jwtManager *auth.JWTManager) {
+func setupRoutes(router *gin.Engine, cfg *config.Config, userService *services.UserService, mfaService *services.MFAService, verificationService *services.EmailVerificationService, passwordResetService *services.PasswordResetService, loginProtectionService *services.LoginProtectionService, accountService *services.AccountService, transactionService *services.TransactionService, stepUpService *services.StepUpService, statementService *services.StatementService, fraudService *services.FraudService, auditService *services.AuditService, idempotencyService *services.IdempotencyService, apiKeyService *services.APIKeyService, jwtManager *auth.JWTManager, denylist auth.TokenDenylist) {
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":    "healthy",
@@ -105,6 +187,8 @@ func setupRoutes(router *gin.Engine, userService *services.UserService, jwtManag
 		})
 	})
 
+	router.GET("/.well-known/jwks.json", handleJWKS(jwtManager))
+
 	v1 := router.Group("/api/v1")
 	{
 		// Public routes
@@ -113,29 +197,108 @@ func setupRoutes(router *gin.Engine, userService *services.UserService, jwtManag
 			auth.POST("/register", handleRegister(userService))
 			auth.POST("/login", handleLogin(userService))
 			auth.POST("/refresh", handleRefreshToken(userService))
+			auth.POST("/mfa/verify", handleVerifyMFA(userService))
+			auth.POST("/verify-email", handleVerifyEmail(verificationService))
+			auth.POST("/forgot-password", handleForgotPassword(passwordResetService))
+			auth.POST("/reset-password", handleResetPassword(passwordResetService))
 		}
 
 		// Protected routes
 		protected := v1.Group("")
-		protected.Use(authMiddleware(jwtManager))
+		protected.Use(authMiddleware(jwtManager, denylist, apiKeyService))
 		{
+			protected.POST("/auth/logout", handleLogout(userService))
+			protected.POST("/auth/logout-all", handleLogoutAll(userService))
+			protected.POST("/auth/verify-email/resend", handleResendVerification(verificationService))
+
+			verified := requireVerifiedEmail(verificationService)
+
 			users := protected.Group("/users")
 			{
 				users.GET("/profile", handleGetProfile(userService))
 				users.PUT("/profile", handleUpdateProfile(userService))
 				users.POST("/change-password", handleChangePassword(userService))
 				users.DELETE("/deactivate", handleDeactivateAccount(userService))
+				users.POST("/mfa/enroll", handleEnrollMFA(mfaService))
+				users.POST("/mfa/confirm", handleConfirmMFA(mfaService))
+				users.POST("/mfa/disable", handleDisableMFA(mfaService))
+				users.POST("/api-keys", handleCreateAPIKey(apiKeyService))
+				users.GET("/api-keys", handleListAPIKeys(apiKeyService))
+				users.DELETE("/api-keys/:id", handleRevokeAPIKey(apiKeyService))
+			}
+
+			accounts := protected.Group("/accounts")
+			{
+				accounts.GET("", handleListAccounts(accountService))
+				accounts.POST("", verified, handleCreateAccount(accountService))
+				accounts.GET("/:id", handleGetAccount(accountService))
+				accounts.PUT("/:id", handleUpdateAccount(accountService))
+				accounts.GET("/:id/balance", handleGetAccountBalance(accountService))
+				accounts.GET("/:id/transactions", handleListAccountTransactions(transactionService))
+				accounts.GET("/:id/statements", handleGetStatement(statementService))
+			}
+
+			idempotent := idempotencyMiddleware(idempotencyService)
+
+			transactions := protected.Group("/transactions")
+			{
+				transactions.POST("/transfer", verified, idempotent, handleTransfer(transactionService))
+				transactions.POST("/deposit", verified, idempotent, handleDeposit(transactionService))
+				transactions.POST("/withdrawal", verified, idempotent, handleWithdrawal(transactionService))
+				transactions.GET("/:id", handleGetTransaction(transactionService))
+				transactions.GET("/:id/history", handleGetTransactionHistory(transactionService))
+				transactions.POST("/step-up/:id/confirm", handleConfirmStepUp(stepUpService))
+			}
+
+			admin := protected.Group("/admin")
+			{
+				adminOnly := requireRole(models.UserRoleAdmin)
+				fraudStaff := requireRole(models.UserRoleFraudAnalyst, models.UserRoleAdmin)
+
+				admin.POST("/transactions/:id/reverse", adminOnly, idempotent, handleReverseTransaction(transactionService))
+				admin.POST("/transactions/:id/refund", adminOnly, idempotent, handleRefundTransaction(transactionService))
+				admin.PUT("/users/:id/role", adminOnly, handleUpdateUserRole(userService))
+				admin.GET("/users/:id/lockout", adminOnly, handleGetLoginLockState(loginProtectionService))
+				admin.POST("/users/:id/unlock", adminOnly, handleUnlockUser(loginProtectionService))
+				admin.PUT("/accounts/:id/status", fraudStaff, handleUpdateAccountStatus(accountService))
+
+				fraudAlerts := admin.Group("/fraud-alerts")
+				fraudAlerts.Use(fraudStaff)
+				{
+					fraudAlerts.GET("", handleListFraudAlerts(fraudService))
+					fraudAlerts.GET("/:id", handleGetFraudAlert(fraudService))
+					fraudAlerts.POST("/:id/assign", handleAssignFraudAlert(fraudService))
+					fraudAlerts.PUT("/:id", handleUpdateFraudAlert(fraudService))
+				}
+
+				// Auditors only read the trail; admins, whose actions it
+				// records, are deliberately not let in
+				auditLogs := admin.Group("/audit-logs")
+				auditLogs.Use(requireRole(models.UserRoleComplianceAuditor))
+				{
+					auditLogs.GET("", handleListAuditLogs(auditService))
+					auditLogs.GET("/export", handleExportAuditLogs(auditService))
+				}
 			}
 		}
 	}
 }
 
 // Auth middleware
-func authMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
+func authMiddleware(jwtManager *auth.JWTManager, denylist auth.TokenDenylist, apiKeyService *services.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			if authHeader != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Send either an Authorization header or an X-API-Key header, not both"})
+				c.Abort()
+				return
+			}
+			authenticateAPIKey(c, apiKeyService, apiKey)
+			return
+		}
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header or X-API-Key required"})
 			c.Abort()
 			return
 		}
@@ -151,13 +314,92 @@ func authMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
 		}
 
 		claims, err := jwtManager.ValidateToken(tokenString)
-		if err != nil {
+		if err != nil || claims.TokenType != "access" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
+		if err := auth.CheckRevoked(c.Request.Context(), denylist, claims); err != nil {
+			if errors.Is(err, auth.ErrRevokedToken) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			} else {
+				// Fail closed: a token cannot be trusted if revocations are unknown
+				logrus.WithError(err).Error("Failed to check token revocation")
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Authentication is temporarily unavailable"})
+			}
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.SessionID)
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
+// newTokenDenylist sets up the configured store for revoked access tokens
+func newTokenDenylist(cfg *config.Config) (auth.TokenDenylist, error) {
+	switch cfg.JWT.DenylistStore {
+	case "memory":
+		return auth.NewMemoryDenylist(), nil
+	case "redis":
+		client, err := newRedisClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewRedisDenylist(client), nil
+	default:
+		return nil, fmt.Errorf("unknown JWT_DENYLIST_STORE %q", cfg.JWT.DenylistStore)
+	}
+}
+
+// newRedisClient connects to the configured redis and checks that it answers
+func newRedisClient(cfg *config.Config) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.GetRedisAddr(),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
+	}
+
+	logrus.Info("Successfully connected to redis")
+	return client, nil
+}
+
+// requestMeta describes the client behind a request for the audit trail and
+// login throttling. The IP is the connection's peer, or what one of the
+// TRUSTED_PROXIES forwarded; the session is only known once authMiddleware
+// has run.
+func requestMeta(c *gin.Context) *models.RequestMeta {
+	return &models.RequestMeta{
+		IPAddress: net.ParseIP(c.ClientIP()),
+		UserAgent: c.Request.UserAgent(),
+		SessionID: c.GetString("session_id"),
+	}
+}
+
+// requireRole only lets users holding one of the given roles through. The role
+// is read from the access token; changing a role revokes the user's tokens.
+func requireRole(roles ...models.UserRole) gin.HandlerFunc {
+	allowed := make(map[models.UserRole]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
+
+	return func(c *gin.Context) {
+		claims := c.MustGet("claims").(*auth.Claims)
+		if !allowed[claims.Role] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -171,9 +413,9 @@ func handleRegister(userService *services.UserService) gin.HandlerFunc {
 			return
 		}
 
-		response, err := userService.Register(&req)
+		response, err := userService.Register(&req, requestMeta(c))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.JSON(http.StatusBadRequest, passwordErrorBody(err))
 			return
 		}
 
@@ -189,12 +431,16 @@ func handleLogin(userService *services.UserService) gin.HandlerFunc {
 			return
 		}
 
-		response, err := userService.Login(&req)
+		response, challenge, err := userService.Login(&req, requestMeta(c))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			respondLoginError(c, err)
 			return
 		}
 
+		if challenge != nil {
+			c.JSON(http.StatusOK, challenge)
+			return
+		}
 		c.JSON(http.StatusOK, response)
 	}
 }
@@ -207,9 +453,14 @@ func handleRefreshToken(userService *services.UserService) gin.HandlerFunc {
 			return
 		}
 
-		response, err := userService.RefreshToken(&req)
+		response, err := userService.RefreshToken(&req, requestMeta(c))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			logrus.WithError(err).Error("Failed to refresh token")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh token"})
 			return
 		}
 
@@ -217,6 +468,51 @@ func handleRefreshToken(userService *services.UserService) gin.HandlerFunc {
 	}
 }
 
+func handleLogout(userService *services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.MustGet("claims").(*auth.Claims)
+
+		if err := userService.Logout(c.Request.Context(), claims, requestMeta(c)); err != nil {
+			logrus.WithError(err).Error("Failed to log out")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+	}
+}
+
+func handleLogoutAll(userService *services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		if err := userService.LogoutAll(c.Request.Context(), userID, requestMeta(c)); err != nil {
+			logrus.WithError(err).Error("Failed to log out all sessions")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out all sessions"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions successfully"})
+	}
+}
+
+// purgeRefreshTokens periodically deletes expired refresh tokens
+func purgeRefreshTokens(userService *services.UserService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, err := userService.PurgeExpiredRefreshTokens()
+		if err != nil {
+			logrus.WithError(err).Error("Failed to purge expired refresh tokens")
+			continue
+		}
+		if purged > 0 {
+			logrus.WithField("count", purged).Info("Purged expired refresh tokens")
+		}
+	}
+}
+
 func handleGetProfile(userService *services.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID := c.MustGet("user_id").(uuid.UUID)
@@ -241,7 +537,7 @@ func handleUpdateProfile(userService *services.UserService) gin.HandlerFunc {
 			return
 		}
 
-		profile, err := userService.UpdateProfile(userID, &req)
+		profile, err := userService.UpdateProfile(userID, &req, requestMeta(c))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -257,7 +553,7 @@ func handleChangePassword(userService *services.UserService) gin.HandlerFunc {
 
 		var req struct {
 			CurrentPassword string `json:"current_password" binding:"required"`
-			NewPassword     string `json:"new_password" binding:"required,min=8"`
+			NewPassword     string `json:"new_password" binding:"required"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -265,9 +561,9 @@ func handleChangePassword(userService *services.UserService) gin.HandlerFunc {
 			return
 		}
 
-		err := userService.ChangePassword(userID, req.CurrentPassword, req.NewPassword)
+		err := userService.ChangePassword(userID, req.CurrentPassword, req.NewPassword, requestMeta(c))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.JSON(http.StatusBadRequest, passwordErrorBody(err))
 			return
 		}
 
@@ -275,11 +571,49 @@ func handleChangePassword(userService *services.UserService) gin.HandlerFunc {
 	}
 }
 
+func handleUpdateUserRole(userService *services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID := c.MustGet("user_id").(uuid.UUID)
+
+		userID, ok := parseUUIDParam(c, "id", "invalid user ID")
+		if !ok {
+			return
+		}
+
+		var req models.UpdateUserRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		profile, err := userService.ChangeRole(actorID, userID, req.Role, requestMeta(c))
+		if err != nil {
+			c.JSON(userRoleErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, profile)
+	}
+}
+
+func userRoleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidRole):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrOwnRoleChange):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func handleDeactivateAccount(userService *services.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID := c.MustGet("user_id").(uuid.UUID)
 
-		err := userService.DeactivateAccount(userID)
+		err := userService.DeactivateAccount(userID, requestMeta(c))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
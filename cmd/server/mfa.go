@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"financial-transaction-system/internal/auth"
+	"financial-transaction-system/internal/config"
+	"financial-transaction-system/internal/models"
+	"financial-transaction-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// newMFACipher loads the key TOTP secrets are encrypted with; without one,
+// two-factor authentication is unavailable
+func newMFACipher(cfg *config.Config) (*auth.SecretCipher, error) {
+	if cfg.MFA.EncryptionKey == "" {
+		return nil, nil
+	}
+	return auth.NewSecretCipher(cfg.MFA.EncryptionKey)
+}
+
+func handleEnrollMFA(mfaService *services.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		enrollment, err := mfaService.Enroll(userID)
+		if err != nil {
+			respondMFAError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, enrollment)
+	}
+}
+
+func handleConfirmMFA(mfaService *services.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		var req models.ConfirmMFARequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		codes, err := mfaService.Confirm(userID, &req, requestMeta(c))
+		if err != nil {
+			respondMFAError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, codes)
+	}
+}
+
+func handleDisableMFA(mfaService *services.MFAService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		var req models.DisableMFARequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := mfaService.Disable(userID, &req, requestMeta(c)); err != nil {
+			respondMFAError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+	}
+}
+
+// handleVerifyMFA exchanges an MFA token from login and a code for tokens
+func handleVerifyMFA(userService *services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.VerifyMFARequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		response, err := userService.VerifyMFA(c.Request.Context(), &req, requestMeta(c))
+		if err != nil {
+			var blocked *services.LoginBlockedError
+			if errors.As(err, &blocked) {
+				respondLoginError(c, err)
+				return
+			}
+			respondMFAError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+func respondMFAError(c *gin.Context, err error) {
+	status := mfaErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		logrus.WithError(err).Error("Two-factor authentication request failed")
+		c.JSON(status, gin.H{"error": "two-factor authentication request failed"})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+func mfaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode),
+		errors.Is(err, services.ErrInvalidMFAToken),
+		errors.Is(err, services.ErrIncorrectPassword):
+		return http.StatusUnauthorized
+	case errors.Is(err, services.ErrMFAAlreadyEnabled),
+		errors.Is(err, services.ErrMFANotEnrolled),
+		errors.Is(err, services.ErrMFANotEnabled):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrMFAUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
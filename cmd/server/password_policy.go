@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"financial-transaction-system/internal/config"
+	"financial-transaction-system/internal/services"
+	"financial-transaction-system/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newPasswordPolicy builds the configured password policy, checking the
+// breached password ranges if they are set
+func newPasswordPolicy(cfg *config.Config) (*utils.PasswordPolicy, error) {
+	policy := &utils.PasswordPolicy{
+		MinLength:          cfg.PasswordPolicy.MinLength,
+		MaxLength:          cfg.PasswordPolicy.MaxLength,
+		RequireUppercase:   cfg.PasswordPolicy.RequireUppercase,
+		RequireLowercase:   cfg.PasswordPolicy.RequireLowercase,
+		RequireDigit:       cfg.PasswordPolicy.RequireDigit,
+		RequireSymbol:      cfg.PasswordPolicy.RequireSymbol,
+		RejectPersonalInfo: cfg.PasswordPolicy.RejectPersonalInfo,
+	}
+
+	if cfg.PasswordPolicy.BreachedRangesDir == "" {
+		return policy, nil
+	}
+	breached, err := utils.OpenBreachedPasswords(cfg.PasswordPolicy.BreachedRangesDir)
+	if err != nil {
+		return nil, err
+	}
+	policy.Breached = breached
+	return policy, nil
+}
+
+// passwordErrorBody describes a failed request, listing each broken rule
+// when a new password was refused by the policy
+func passwordErrorBody(err error) gin.H {
+	var policyErr *services.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return gin.H{"error": services.ErrWeakPassword.Error(), "violations": policyErr.Violations}
+	}
+	return gin.H{"error": err.Error()}
+}
+
+// newPasswordHasher sets up the configured hasher for new passwords
+func newPasswordHasher(cfg *config.Config) (utils.PasswordHasher, error) {
+	switch cfg.PasswordHash.Algorithm {
+	case "argon2id":
+		hash := cfg.PasswordHash
+		if hash.Argon2MemoryKiB <= 0 || hash.Argon2Iterations <= 0 || hash.Argon2Parallelism <= 0 || hash.Argon2Parallelism > math.MaxUint8 {
+			return nil, fmt.Errorf("invalid argon2id parameters")
+		}
+		return utils.Argon2idHasher{
+			MemoryKiB:   uint32(hash.Argon2MemoryKiB),
+			Iterations:  uint32(hash.Argon2Iterations),
+			Parallelism: uint8(hash.Argon2Parallelism),
+		}, nil
+	case "bcrypt":
+		if cfg.PasswordHash.BcryptCost < bcrypt.MinCost || cfg.PasswordHash.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("PASSWORD_BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return utils.BcryptHasher{Cost: cfg.PasswordHash.BcryptCost}, nil
+	default:
+		return nil, fmt.Errorf("unknown PASSWORD_HASH_ALGORITHM %q", cfg.PasswordHash.Algorithm)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"financial-transaction-system/internal/models"
+	"financial-transaction-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// handleForgotPassword answers the same way whatever the email, so it cannot
+// be used to find out which emails are registered. Clients that ask too often
+// get 429 with Retry-After.
+func handleForgotPassword(passwordResetService *services.PasswordResetService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ForgotPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := passwordResetService.RequestReset(c.Request.Context(), req.Email, requestMeta(c)); err != nil {
+			var blocked *services.LoginBlockedError
+			if errors.As(err, &blocked) {
+				respondLoginError(c, err)
+				return
+			}
+			logrus.WithError(err).Error("Failed to queue password reset request")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "password reset is temporarily unavailable"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{"message": "If the email is registered, a password reset link has been sent"})
+	}
+}
+
+func handleResetPassword(passwordResetService *services.PasswordResetService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ResetPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := passwordResetService.ResetPassword(c.Request.Context(), &req, requestMeta(c)); err != nil {
+			if errors.Is(err, services.ErrInvalidResetToken) || errors.Is(err, services.ErrWeakPassword) {
+				c.JSON(http.StatusBadRequest, passwordErrorBody(err))
+				return
+			}
+			logrus.WithError(err).Error("Failed to reset password")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset password"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully; sign in with the new password"})
+	}
+}
+
+// purgePasswordResetTokens periodically deletes expired reset tokens
+func purgePasswordResetTokens(passwordResetService *services.PasswordResetService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, err := passwordResetService.PurgeExpiredTokens()
+		if err != nil {
+			logrus.WithError(err).Error("Failed to purge expired password reset tokens")
+			continue
+		}
+		if purged > 0 {
+			logrus.WithField("count", purged).Info("Purged expired password reset tokens")
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"financial-transaction-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func handleGetStatement(statementService *services.StatementService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		accountID, ok := parseUUIDParam(c, "id", "invalid account ID")
+		if !ok {
+			return
+		}
+
+		if c.Query("start_date") == "" || c.Query("end_date") == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start_date and end_date are required"})
+			return
+		}
+
+		start, _, err := parseQueryTime(c.Query("start_date"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date"})
+			return
+		}
+
+		end, dateOnly, err := parseQueryTime(c.Query("end_date"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date"})
+			return
+		}
+		if dateOnly {
+			end = end.AddDate(0, 0, 1)
+		}
+
+		statement, err := statementService.GetStatement(userID, accountID, start, end)
+		if err != nil {
+			c.JSON(statementErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, statement)
+	}
+}
+
+func statementErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrAccountNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidStatementPeriod):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
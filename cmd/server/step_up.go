@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"financial-transaction-system/internal/auth"
+	"financial-transaction-system/internal/models"
+	"financial-transaction-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// handleConfirmStepUp answers the challenge a held transfer returned, with a
+// TOTP code for users with two-factor authentication or the password otherwise
+func handleConfirmStepUp(stepUpService *services.StepUpService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		challengeID, ok := parseUUIDParam(c, "id", "invalid challenge ID")
+		if !ok {
+			return
+		}
+
+		var req models.ConfirmStepUpRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		response, err := stepUpService.Confirm(userID, challengeID, &req, requestMeta(c))
+		if err != nil {
+			status := stepUpErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				logrus.WithError(err).Error("Failed to confirm step-up challenge")
+				c.JSON(status, gin.H{"error": "failed to confirm step-up challenge"})
+				return
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// sessionAuthTime returns when the user of the request last presented their
+// credentials, or the zero time for tokens that do not say
+func sessionAuthTime(c *gin.Context) time.Time {
+	claims := c.MustGet("claims").(*auth.Claims)
+	if claims.AuthTime == nil {
+		return time.Time{}
+	}
+	return claims.AuthTime.Time
+}
+
+// A wrong proof is answered with 403 rather than 401 so that clients do not
+// mistake it for an expired access token
+func stepUpErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrStepUpChallengeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidMFACode),
+		errors.Is(err, services.ErrIncorrectPassword),
+		errors.Is(err, services.ErrStepUpAttemptsExceeded):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrStepUpChallengeClosed),
+		errors.Is(err, services.ErrStepUpChallengeExpired):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrMFAUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return transactionErrorStatus(err)
+	}
+}
+
+// expireStepUpChallenges periodically cancels transfers whose step-up
+// challenge was not answered in time
+func expireStepUpChallenges(stepUpService *services.StepUpService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		expired, err := stepUpService.ExpireChallenges()
+		if err != nil {
+			logrus.WithError(err).Error("Failed to expire step-up challenges")
+			continue
+		}
+		if expired > 0 {
+			logrus.WithField("count", expired).Info("Expired step-up challenges")
+		}
+	}
+}
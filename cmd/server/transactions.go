@@ -0,0 +1,309 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"financial-transaction-system/internal/models"
+	"financial-transaction-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func handleTransfer(transactionService *services.TransactionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		var req models.TransferRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		response, err := transactionService.Transfer(userID, &req, sessionAuthTime(c), requestMeta(c))
+		if err != nil {
+			c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(createdTransactionStatus(response), response)
+	}
+}
+
+func handleDeposit(transactionService *services.TransactionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		var req models.DepositRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		response, err := transactionService.Deposit(userID, &req, requestMeta(c))
+		if err != nil {
+			c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(createdTransactionStatus(response), response)
+	}
+}
+
+func handleWithdrawal(transactionService *services.TransactionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		var req models.WithdrawalRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		response, err := transactionService.Withdrawal(userID, &req, requestMeta(c))
+		if err != nil {
+			c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(createdTransactionStatus(response), response)
+	}
+}
+
+func handleGetTransaction(transactionService *services.TransactionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		transactionID, ok := parseUUIDParam(c, "id", "invalid transaction ID")
+		if !ok {
+			return
+		}
+
+		response, err := transactionService.GetTransaction(userID, transactionID)
+		if err != nil {
+			c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+func handleListAccountTransactions(transactionService *services.TransactionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		accountID, ok := parseUUIDParam(c, "id", "invalid account ID")
+		if !ok {
+			return
+		}
+
+		filter, err := parseTransactionFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		history, err := transactionService.ListAccountTransactions(userID, accountID, filter)
+		if err != nil {
+			c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, history)
+	}
+}
+
+// parseTransactionFilter reads the history query parameters. Dates may be
+// RFC 3339 timestamps or YYYY-MM-DD; a bare end_date covers that whole day.
+func parseTransactionFilter(c *gin.Context) (*models.TransactionFilter, error) {
+	filter := &models.TransactionFilter{}
+
+	if value := c.Query("type"); value != "" {
+		txnType := models.TransactionType(value)
+		filter.TransactionType = &txnType
+	}
+
+	if value := c.Query("status"); value != "" {
+		status := models.TransactionStatus(value)
+		filter.Status = &status
+	}
+
+	if value := c.Query("start_date"); value != "" {
+		start, _, err := parseQueryTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start_date: %w", err)
+		}
+		filter.StartDate = &start
+	}
+
+	if value := c.Query("end_date"); value != "" {
+		end, dateOnly, err := parseQueryTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end_date: %w", err)
+		}
+		if dateOnly {
+			end = end.AddDate(0, 0, 1)
+		}
+		filter.EndDate = &end
+	}
+
+	if value := c.Query("min_amount"); value != "" {
+		amount, err := decimal.NewFromString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid min_amount")
+		}
+		filter.MinAmount = &amount
+	}
+
+	if value := c.Query("max_amount"); value != "" {
+		amount, err := decimal.NewFromString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_amount")
+		}
+		filter.MaxAmount = &amount
+	}
+
+	if value := c.Query("currency"); value != "" {
+		filter.Currency = &value
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		filter.Cursor = &value
+	}
+
+	if value := c.Query("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page")
+		}
+		filter.Page = page
+	}
+
+	if value := c.Query("page_size"); value != "" {
+		pageSize, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page_size")
+		}
+		filter.PageSize = pageSize
+	}
+
+	return filter, nil
+}
+
+func parseQueryTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+func handleGetTransactionHistory(transactionService *services.TransactionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uuid.UUID)
+
+		transactionID, ok := parseUUIDParam(c, "id", "invalid transaction ID")
+		if !ok {
+			return
+		}
+
+		history, err := transactionService.GetStatusHistory(userID, transactionID)
+		if err != nil {
+			c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"history": history})
+	}
+}
+
+func handleReverseTransaction(transactionService *services.TransactionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID := c.MustGet("user_id").(uuid.UUID)
+
+		transactionID, ok := parseUUIDParam(c, "id", "invalid transaction ID")
+		if !ok {
+			return
+		}
+
+		var req models.ReverseTransactionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		response, err := transactionService.ReverseTransaction(actorID, transactionID, &req, requestMeta(c))
+		if err != nil {
+			c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+func handleRefundTransaction(transactionService *services.TransactionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actorID := c.MustGet("user_id").(uuid.UUID)
+
+		transactionID, ok := parseUUIDParam(c, "id", "invalid transaction ID")
+		if !ok {
+			return
+		}
+
+		var req models.RefundTransactionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		response, err := transactionService.RefundTransaction(actorID, transactionID, &req, requestMeta(c))
+		if err != nil {
+			c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, response)
+	}
+}
+
+// createdTransactionStatus answers 202 for a transaction held for review or
+// step-up authentication and 201 once it has been posted
+func createdTransactionStatus(response *models.TransactionResponse) int {
+	if response.Status == models.TransactionStatusPending {
+		return http.StatusAccepted
+	}
+	return http.StatusCreated
+}
+
+func transactionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrAccountNotFound), errors.Is(err, services.ErrTransactionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInsufficientFunds),
+		errors.Is(err, services.ErrDailyLimitExceeded),
+		errors.Is(err, services.ErrMonthlyLimitExceeded),
+		errors.Is(err, services.ErrAccountNotActive),
+		errors.Is(err, services.ErrRefundNotAllowed),
+		errors.Is(err, services.ErrRefundExceedsAmount),
+		errors.Is(err, services.ErrTransactionBlocked):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrTransactionNotFinal),
+		errors.Is(err, services.ErrTransactionRefunded),
+		errors.Is(err, services.ErrInvalidStatusTransition):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidAmount),
+		errors.Is(err, services.ErrSameAccount),
+		errors.Is(err, services.ErrCurrencyMismatch),
+		errors.Is(err, services.ErrReasonRequired):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// GenesisHash is the prev_hash of the first chained entry
+var GenesisHash = strings.Repeat("0", 64)
+
+// timeFormat fixes the precision of timestamps in the serialization;
+// PostgreSQL stores microseconds, so entries are stamped at that precision
+const timeFormat = "2006-01-02T15:04:05.000000Z"
+
+// canonicalEntry fixes the field order and encoding of an entry for hashing.
+// Adding a field changes every hash, so it needs a new chain version.
+type canonicalEntry struct {
+	Version       int             `json:"v"`
+	Seq           int64           `json:"seq"`
+	ID            uuid.UUID       `json:"id"`
+	UserID        *uuid.UUID      `json:"user_id"`
+	AccountID     *uuid.UUID      `json:"account_id"`
+	TransactionID *uuid.UUID      `json:"transaction_id"`
+	Action        string          `json:"action"`
+	EntityType    string          `json:"entity_type"`
+	EntityID      *uuid.UUID      `json:"entity_id"`
+	OldValues     json.RawMessage `json:"old_values"`
+	NewValues     json.RawMessage `json:"new_values"`
+	IPAddress     *string         `json:"ip_address"`
+	UserAgent     *string         `json:"user_agent"`
+	SessionID     *string         `json:"session_id"`
+	Description   *string         `json:"description"`
+	Metadata      json.RawMessage `json:"metadata"`
+	CreatedAt     string          `json:"created_at"`
+	PrevHash      string          `json:"prev_hash"`
+}
+
+// StampTime returns t at the precision the chain hashes
+func StampTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// Hash computes the chain hash of an entry. Seq, CreatedAt and PrevHash must
+// be set. JSON values are re-encoded with sorted keys so that the hash is the
+// same before and after PostgreSQL normalises them as JSONB.
+func Hash(log *models.AuditLog) (string, error) {
+	if log.PrevHash == nil {
+		return "", fmt.Errorf("audit entry %d has no previous hash", log.Seq)
+	}
+
+	entry := canonicalEntry{
+		Version:       1,
+		Seq:           log.Seq,
+		ID:            log.ID,
+		UserID:        log.UserID,
+		AccountID:     log.AccountID,
+		TransactionID: log.TransactionID,
+		Action:        string(log.Action),
+		EntityType:    log.EntityType,
+		EntityID:      log.EntityID,
+		UserAgent:     log.UserAgent,
+		SessionID:     log.SessionID,
+		Description:   log.Description,
+		CreatedAt:     StampTime(log.CreatedAt).Format(timeFormat),
+		PrevHash:      *log.PrevHash,
+	}
+
+	if log.IPAddress != nil {
+		ip := canonicalIP(*log.IPAddress)
+		entry.IPAddress = &ip
+	}
+
+	var err error
+	if entry.OldValues, err = canonicalJSON(log.OldValues); err != nil {
+		return "", err
+	}
+	if entry.NewValues, err = canonicalJSON(log.NewValues); err != nil {
+		return "", err
+	}
+	if entry.Metadata, err = canonicalJSON(log.Metadata); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize audit entry: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalJSON decodes and re-encodes a value, keeping numbers as written
+func canonicalJSON(data json.RawMessage) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to decode audit values: %w", err)
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit values: %w", err)
+	}
+	return encoded, nil
+}
+
+// canonicalIP formats an address the way it reads back from an INET column
+func canonicalIP(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return v4.String()
+	}
+	return ip.String()
+}
@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func testEntry(seq int64, prevHash string) *models.AuditLog {
+	ip := net.ParseIP("203.0.113.7")
+	return &models.AuditLog{
+		ID:         uuid.New(),
+		Seq:        seq,
+		Action:     models.AuditAction("transaction_created"),
+		EntityType: "transaction",
+		IPAddress:  &ip,
+		NewValues:  json.RawMessage(`{"amount": "10.00", "currency": "USD"}`),
+		CreatedAt:  time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC),
+		PrevHash:   &prevHash,
+	}
+}
+
+// chain links n entries from the genesis hash, the way the repository does
+func chain(t *testing.T, n int) []*models.AuditLog {
+	t.Helper()
+	entries := make([]*models.AuditLog, n)
+	prev := GenesisHash
+	for i := range entries {
+		entry := testEntry(int64(i+1), prev)
+		hash, err := Hash(entry)
+		if err != nil {
+			t.Fatalf("Hash(entry %d): %v", entry.Seq, err)
+		}
+		entry.Hash = &hash
+		entries[i] = entry
+		prev = hash
+	}
+	return entries
+}
+
+func TestHashRequiresPrevHash(t *testing.T) {
+	entry := testEntry(1, GenesisHash)
+	entry.PrevHash = nil
+	if _, err := Hash(entry); err == nil {
+		t.Fatal("Hash of an entry without prev_hash succeeded")
+	}
+}
+
+func TestHashIsStableAcrossStorage(t *testing.T) {
+	entry := testEntry(1, GenesisHash)
+	want, err := Hash(entry)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	// JSONB reorders keys and drops whitespace, INET returns IPv4 in its
+	// short form and timestamps come back at microsecond precision
+	stored := *entry
+	stored.NewValues = json.RawMessage(`{"currency":"USD","amount":"10.00"}`)
+	ip := net.ParseIP("203.0.113.7").To16()
+	stored.IPAddress = &ip
+	stored.CreatedAt = StampTime(entry.CreatedAt).In(time.FixedZone("EST", -5*3600))
+
+	got, err := Hash(&stored)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if got != want {
+		t.Errorf("hash changed after a storage round trip: %s != %s", got, want)
+	}
+}
+
+func TestHashCoversContents(t *testing.T) {
+	base := testEntry(1, GenesisHash)
+	want, err := Hash(base)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	tests := map[string]func(*models.AuditLog){
+		"seq":        func(e *models.AuditLog) { e.Seq = 2 },
+		"action":     func(e *models.AuditLog) { e.Action = models.AuditAction("transaction_completed") },
+		"new values": func(e *models.AuditLog) { e.NewValues = json.RawMessage(`{"amount":"1000.00","currency":"USD"}`) },
+		"created at": func(e *models.AuditLog) { e.CreatedAt = e.CreatedAt.Add(time.Second) },
+		"prev hash": func(e *models.AuditLog) {
+			prev := "1" + GenesisHash[1:]
+			e.PrevHash = &prev
+		},
+	}
+
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			entry := *base
+			change(&entry)
+			got, err := Hash(&entry)
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if got == want {
+				t.Errorf("changing %s left the hash unchanged", name)
+			}
+		})
+	}
+}
+
+func TestCheckpointSignature(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	signer, err := NewSigner(base64.StdEncoding.EncodeToString(seed))
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+
+	checkpoint := &models.AuditCheckpoint{
+		ID:        uuid.New(),
+		Seq:       5,
+		HeadHash:  *chain(t, 5)[4].Hash,
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	signer.Sign(checkpoint)
+
+	if err := VerifyCheckpoint(signer.PublicKey(), checkpoint); err != nil {
+		t.Fatalf("VerifyCheckpoint: %v", err)
+	}
+
+	moved := *checkpoint
+	moved.Seq = 4
+	if err := VerifyCheckpoint(signer.PublicKey(), &moved); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("altered seq: got %v, want ErrInvalidSignature", err)
+	}
+
+	other, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	if err := VerifyCheckpoint(other, checkpoint); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("other key: got %v, want ErrInvalidSignature", err)
+	}
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"financial-transaction-system/internal/models"
+)
+
+var ErrInvalidSignature = errors.New("checkpoint signature does not verify")
+
+// Signer signs checkpoints of the audit chain head with an Ed25519 key
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// NewSigner loads a base64 Ed25519 private key, given either as the 32-byte
+// seed or the 64-byte expanded key
+func NewSigner(encoded string) (*Signer, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit signing key: %w", err)
+	}
+
+	var key ed25519.PrivateKey
+	switch len(data) {
+	case ed25519.SeedSize:
+		key = ed25519.NewKeyFromSeed(data)
+	case ed25519.PrivateKeySize:
+		key = ed25519.PrivateKey(data)
+	default:
+		return nil, fmt.Errorf("invalid audit signing key: expected %d or %d bytes, got %d",
+			ed25519.SeedSize, ed25519.PrivateKeySize, len(data))
+	}
+
+	public := key.Public().(ed25519.PublicKey)
+	return &Signer{key: key, keyID: KeyID(public)}, nil
+}
+
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Sign fills in the key ID and signature of a checkpoint
+func (s *Signer) Sign(checkpoint *models.AuditCheckpoint) {
+	checkpoint.KeyID = s.keyID
+	signature := ed25519.Sign(s.key, checkpointMessage(checkpoint))
+	checkpoint.Signature = base64.StdEncoding.EncodeToString(signature)
+}
+
+// ParsePublicKey loads a base64 Ed25519 public key
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit verify key: %w", err)
+	}
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid audit verify key: expected %d bytes, got %d", ed25519.PublicKeySize, len(data))
+	}
+	return ed25519.PublicKey(data), nil
+}
+
+// KeyID names a public key so checkpoints record which key signed them
+func KeyID(public ed25519.PublicKey) string {
+	sum := sha256.Sum256(public)
+	return hex.EncodeToString(sum[:8])
+}
+
+// VerifyCheckpoint checks a checkpoint's signature against the public key
+func VerifyCheckpoint(public ed25519.PublicKey, checkpoint *models.AuditCheckpoint) error {
+	if checkpoint.KeyID != KeyID(public) {
+		return fmt.Errorf("%w: signed with key %s", ErrInvalidSignature, checkpoint.KeyID)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(checkpoint.Signature)
+	if err != nil || !ed25519.Verify(public, checkpointMessage(checkpoint), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func checkpointMessage(checkpoint *models.AuditCheckpoint) []byte {
+	return []byte(fmt.Sprintf("audit-checkpoint:v1:%d:%s:%s",
+		checkpoint.Seq,
+		checkpoint.HeadHash,
+		StampTime(checkpoint.CreatedAt).Format(timeFormat),
+	))
+}
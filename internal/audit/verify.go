@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"financial-transaction-system/internal/models"
+)
+
+// ChainVerifier walks the audit chain one entry at a time, from the first
+// entry in seq order, and stops at the first broken link. Every checkpoint
+// must match the entry it covers and carry a valid signature; a checkpoint
+// beyond the last entry means the table was truncated. Callers page through
+// the entries with Add and then call Finish with the recorded chain head.
+type ChainVerifier struct {
+	report      models.AuditChainReport
+	prevHash    *string
+	checkpoints []*models.AuditCheckpoint
+	bySeq       map[int64][]*models.AuditCheckpoint
+}
+
+// NewChainVerifier checks the checkpoints' signatures against the public key
+// up front. With a nil key, signatures are not checked.
+func NewChainVerifier(public ed25519.PublicKey, checkpoints []*models.AuditCheckpoint) *ChainVerifier {
+	v := &ChainVerifier{
+		checkpoints: checkpoints,
+		bySeq:       map[int64][]*models.AuditCheckpoint{},
+	}
+
+	for _, checkpoint := range checkpoints {
+		if public != nil {
+			if err := VerifyCheckpoint(public, checkpoint); err != nil {
+				v.broken(checkpoint.Seq, "checkpoint %s: %v", checkpoint.ID, err)
+				return v
+			}
+		}
+		v.bySeq[checkpoint.Seq] = append(v.bySeq[checkpoint.Seq], checkpoint)
+	}
+
+	return v
+}
+
+// Report returns the outcome so far. HeadSeq is the last entry verified,
+// which is where the next page of entries starts.
+func (v *ChainVerifier) Report() *models.AuditChainReport {
+	return &v.report
+}
+
+// Add verifies the next entry and reports whether the chain still holds
+func (v *ChainVerifier) Add(entry *models.AuditLog) bool {
+	if v.report.BrokenAt != nil {
+		return false
+	}
+
+	if entry.Seq != v.report.HeadSeq+1 {
+		return v.broken(v.report.HeadSeq+1, "entry is missing")
+	}
+
+	if entry.Hash == nil {
+		// Entries from before the chain existed may only precede it
+		if v.prevHash != nil {
+			return v.broken(entry.Seq, "entry is not hashed")
+		}
+		v.report.UnchainedEntries++
+	} else {
+		expected := GenesisHash
+		if v.prevHash != nil {
+			expected = *v.prevHash
+		}
+		if entry.PrevHash == nil || *entry.PrevHash != expected {
+			return v.broken(entry.Seq, "prev_hash does not match the hash of entry %d", entry.Seq-1)
+		}
+
+		hash, err := Hash(entry)
+		if err != nil {
+			return v.broken(entry.Seq, "entry cannot be hashed: %v", err)
+		}
+		if hash != *entry.Hash {
+			return v.broken(entry.Seq, "hash does not match the entry's contents")
+		}
+		v.prevHash = entry.Hash
+	}
+
+	for _, checkpoint := range v.bySeq[entry.Seq] {
+		if v.prevHash == nil || checkpoint.HeadHash != *v.prevHash {
+			return v.broken(entry.Seq, "checkpoint %s does not match the entry", checkpoint.ID)
+		}
+		v.report.CheckpointsVerified++
+	}
+
+	v.report.Entries++
+	v.report.HeadSeq = entry.Seq
+	v.report.HeadHash = v.prevHash
+	return true
+}
+
+// Finish checks that the walk reached the chain head recorded as headSeq and
+// headHash and that no checkpoint lies beyond it, and returns the report
+func (v *ChainVerifier) Finish(headSeq int64, headHash *string) *models.AuditChainReport {
+	if v.report.BrokenAt != nil {
+		return &v.report
+	}
+
+	if v.report.CheckpointsVerified < len(v.checkpoints) {
+		last := v.checkpoints[len(v.checkpoints)-1]
+		v.broken(v.report.HeadSeq+1,
+			"checkpoint %s covers entry %d but the chain ends at entry %d", last.ID, last.Seq, v.report.HeadSeq)
+		return &v.report
+	}
+
+	if v.report.HeadSeq != headSeq {
+		v.broken(v.report.HeadSeq+1,
+			"chain head records entry %d but the chain ends at entry %d", headSeq, v.report.HeadSeq)
+		return &v.report
+	}
+	if !equalHashes(headHash, v.report.HeadHash) {
+		v.broken(headSeq, "chain head hash does not match the last entry")
+	}
+
+	return &v.report
+}
+
+// broken records the first broken link and returns false for Add to pass on
+func (v *ChainVerifier) broken(seq int64, format string, args ...interface{}) bool {
+	v.report.BrokenAt = &seq
+	v.report.Problem = fmt.Sprintf(format, args...)
+	return false
+}
+
+func equalHashes(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
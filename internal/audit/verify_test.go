@@ -0,0 +1,263 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func testSigner(t *testing.T) *Signer {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	signer, err := NewSigner(base64.StdEncoding.EncodeToString(private.Seed()))
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	return signer
+}
+
+func signedCheckpoint(signer *Signer, entry *models.AuditLog) *models.AuditCheckpoint {
+	checkpoint := &models.AuditCheckpoint{
+		ID:        uuid.New(),
+		Seq:       entry.Seq,
+		HeadHash:  *entry.Hash,
+		CreatedAt: time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC),
+	}
+	signer.Sign(checkpoint)
+	return checkpoint
+}
+
+// rehash recomputes an entry's hash after it was changed, as someone
+// covering their tracks would
+func rehash(t *testing.T, entry *models.AuditLog) {
+	t.Helper()
+	hash, err := Hash(entry)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	entry.Hash = &hash
+}
+
+func walk(public ed25519.PublicKey, checkpoints []*models.AuditCheckpoint, entries []*models.AuditLog, headSeq int64, headHash *string) *models.AuditChainReport {
+	verifier := NewChainVerifier(public, checkpoints)
+	for _, entry := range entries {
+		if !verifier.Add(entry) {
+			break
+		}
+	}
+	return verifier.Finish(headSeq, headHash)
+}
+
+func TestChainVerifier(t *testing.T) {
+	signer := testSigner(t)
+	otherSigner := testSigner(t)
+
+	tests := []struct {
+		name string
+		// build returns the entries, checkpoints and recorded head to verify
+		build    func(t *testing.T) ([]*models.AuditLog, []*models.AuditCheckpoint, int64, *string)
+		public   ed25519.PublicKey
+		brokenAt int64
+		problem  string
+	}{
+		{
+			name: "intact with checkpoints",
+			build: func(t *testing.T) ([]*models.AuditLog, []*models.AuditCheckpoint, int64, *string) {
+				entries := chain(t, 5)
+				checkpoints := []*models.AuditCheckpoint{signedCheckpoint(signer, entries[2]), signedCheckpoint(signer, entries[4])}
+				return entries, checkpoints, 5, entries[4].Hash
+			},
+			public: signer.PublicKey(),
+		},
+		{
+			name: "entries from before chaining",
+			build: func(t *testing.T) ([]*models.AuditLog, []*models.AuditCheckpoint, int64, *string) {
+				entries := chain(t, 5)
+				entries[0].PrevHash, entries[0].Hash = nil, nil
+				entries[1].PrevHash, entries[1].Hash = nil, nil
+				genesis := GenesisHash
+				entries[2].PrevHash = &genesis
+				rehash(t, entries[2])
+				for i := 3; i < len(entries); i++ {
+					entries[i].PrevHash = entries[i-1].Hash
+					rehash(t, entries[i])
+				}
+				return entries, nil, 5, entries[4].Hash
+			},
+		},
+		{
+			name: "edited entry",
+			build: func(t *testing.T) ([]*models.AuditLog, []*models.AuditCheckpoint, int64, *string) {
+				entries := chain(t, 5)
+				entries[2].NewValues = json.RawMessage(`{"amount":"0.01","currency":"USD"}`)
+				return entries, nil, 5, entries[4].Hash
+			},
+			brokenAt: 3,
+			problem:  "hash does not match",
+		},
+		{
+			name: "edited and rehashed entry",
+			build: func(t *testing.T) ([]*models.AuditLog, []*models.AuditCheckpoint, int64, *string) {
+				entries := chain(t, 5)
+				entries[1].NewValues = json.RawMessage(`{"amount":"0.01","currency":"USD"}`)
+				rehash(t, entries[1])
+				return entries, nil, 5, entries[4].Hash
+			},
+			brokenAt: 3,
+			problem:  "prev_hash does not match",
+		},
+		{
+			name: "deleted entry",
+			build: func(t *testing.T) ([]*models.AuditLog, []*models.AuditCheckpoint, int64, *string) {
+				entries := chain(t, 5)
+				return append(entries[:2:2], entries[3:]...), nil, 5, entries[4].Hash
+			},
+			brokenAt: 3,
+			problem:  "entry is missing",
+		},
+		{
+			name: "unhashed entry after the chain started",
+			build: func(t *testing.T) ([]*models.AuditLog, []*models.AuditCheckpoint, int64, *string) {
+				entries := chain(t, 5)
+				entries[3].PrevHash, entries[3].Hash = nil, nil
+				return entries, nil, 5, entries[4].Hash
+			},
+			brokenAt: 4,
+			problem:  "not hashed",
+		},
+		{
+			name: "checkpoint does not match its entry",
+			build: func(t *testing.T) ([]*models.AuditLog, []*models.AuditCheckpoint, int64, *string) {
+				entries := chain(t, 5)
+				forged := *entries[2]
+				forged.Hash = entries[3].Hash
+				return entries, []*models.AuditCheckpoint{signedCheckpoint(signer, &forged)}, 5, entries[4].Hash
+			},
+			public:   signer.PublicKey(),
+			brokenAt: 3,
+			problem:  "does not match the entry",
+		},
+		{
+			name: "checkpoint signed with another key",
+			build: func(t *testing.T) ([]*models.AuditLog, []*models.AuditCheckpoint, int64, *string) {
+				entries := chain(t, 5)
+				return entries, []*models.AuditCheckpoint{signedCheckpoint(otherSigner, entries[3])}, 5, entries[4].Hash
+			},
+			public:   signer.PublicKey(),
+			brokenAt: 4,
+			problem:  "checkpoint",
+		},
+		{
+			name: "signatures not checked without a key",
+			build: func(t *testing.T) ([]*models.AuditLog, []*models.AuditCheckpoint, int64, *string) {
+				entries := chain(t, 5)
+				return entries, []*models.AuditCheckpoint{signedCheckpoint(otherSigner, entries[3])}, 5, entries[4].Hash
+			},
+		},
+		{
+			name: "truncated below a checkpoint",
+			build: func(t *testing.T) ([]*models.AuditLog, []*models.AuditCheckpoint, int64, *string) {
+				entries := chain(t, 5)
+				checkpoint := signedCheckpoint(signer, entries[4])
+				return entries[:3], []*models.AuditCheckpoint{checkpoint}, 3, entries[2].Hash
+			},
+			public:   signer.PublicKey(),
+			brokenAt: 4,
+			problem:  "covers entry 5 but the chain ends at entry 3",
+		},
+		{
+			name: "chain ends before the head",
+			build: func(t *testing.T) ([]*models.AuditLog, []*models.AuditCheckpoint, int64, *string) {
+				entries := chain(t, 5)
+				return entries[:4], nil, 5, entries[4].Hash
+			},
+			brokenAt: 5,
+			problem:  "chain head records entry 5",
+		},
+		{
+			name: "head hash does not match",
+			build: func(t *testing.T) ([]*models.AuditLog, []*models.AuditCheckpoint, int64, *string) {
+				entries := chain(t, 5)
+				return entries, nil, 5, entries[3].Hash
+			},
+			brokenAt: 5,
+			problem:  "chain head hash",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, checkpoints, headSeq, headHash := tt.build(t)
+			report := walk(tt.public, checkpoints, entries, headSeq, headHash)
+
+			if tt.brokenAt == 0 {
+				if report.BrokenAt != nil {
+					t.Fatalf("intact chain reported broken at %d: %s", *report.BrokenAt, report.Problem)
+				}
+				if report.HeadSeq != headSeq || !equalHashes(report.HeadHash, headHash) {
+					t.Errorf("report ends at entry %d, want %d", report.HeadSeq, headSeq)
+				}
+				if report.CheckpointsVerified != len(checkpoints) {
+					t.Errorf("verified %d checkpoints, want %d", report.CheckpointsVerified, len(checkpoints))
+				}
+				return
+			}
+
+			if report.BrokenAt == nil {
+				t.Fatalf("broken chain verified; want a break at entry %d", tt.brokenAt)
+			}
+			if *report.BrokenAt != tt.brokenAt {
+				t.Errorf("broken at %d (%s), want %d", *report.BrokenAt, report.Problem, tt.brokenAt)
+			}
+			if !strings.Contains(report.Problem, tt.problem) {
+				t.Errorf("problem %q does not mention %q", report.Problem, tt.problem)
+			}
+		})
+	}
+}
+
+func TestChainVerifierCountsEntries(t *testing.T) {
+	entries := chain(t, 4)
+	entries[0].PrevHash, entries[0].Hash = nil, nil
+	genesis := GenesisHash
+	entries[1].PrevHash = &genesis
+	rehash(t, entries[1])
+	for i := 2; i < len(entries); i++ {
+		entries[i].PrevHash = entries[i-1].Hash
+		rehash(t, entries[i])
+	}
+
+	report := walk(nil, nil, entries, 4, entries[3].Hash)
+	if report.Entries != 4 || report.UnchainedEntries != 1 {
+		t.Errorf("counted %d entries, %d unchained; want 4 and 1", report.Entries, report.UnchainedEntries)
+	}
+}
+
+func TestChainVerifierStopsAtFirstBreak(t *testing.T) {
+	entries := chain(t, 5)
+	entries[1].NewValues = json.RawMessage(`{}`)
+	entries[3].NewValues = json.RawMessage(`{}`)
+
+	verifier := NewChainVerifier(nil, nil)
+	for _, entry := range entries {
+		verifier.Add(entry)
+	}
+	report := verifier.Finish(5, entries[4].Hash)
+
+	if report.BrokenAt == nil || *report.BrokenAt != 2 {
+		t.Fatalf("broken at %v, want 2", report.BrokenAt)
+	}
+	if report.Entries != 1 || report.HeadSeq != 1 {
+		t.Errorf("verified %d entries up to %d, want 1 up to 1", report.Entries, report.HeadSeq)
+	}
+}
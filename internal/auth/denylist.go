@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrRevokedToken = errors.New("token has been revoked")
+
+// TokenDenylist records access tokens that were revoked before they expired.
+// Entries only need to outlive the tokens they deny.
+type TokenDenylist interface {
+	// Revoke denies the token with the given jti until it expires
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+	// RevokeIssuedBefore denies every token of the user issued at or before
+	// the given time. The entry is kept for ttl, the longest token lifetime.
+	RevokeIssuedBefore(ctx context.Context, userID uuid.UUID, before time.Time, ttl time.Duration) error
+	// TokensValidAfter returns the user's cut-off, or the zero time if none
+	TokensValidAfter(ctx context.Context, userID uuid.UUID) (time.Time, error)
+}
+
+// CheckRevoked returns ErrRevokedToken if the token was revoked on its own or
+// by a logout of all the user's sessions
+func CheckRevoked(ctx context.Context, denylist TokenDenylist, claims *Claims) error {
+	if claims.ID != "" {
+		revoked, err := denylist.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrRevokedToken
+		}
+	}
+
+	validAfter, err := denylist.TokensValidAfter(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+	// iat only has second precision, so a token issued in the same second as
+	// the cut-off is denied as well
+	if !validAfter.IsZero() && (claims.IssuedAt == nil || !claims.IssuedAt.After(validAfter.Truncate(time.Second))) {
+		return ErrRevokedToken
+	}
+
+	return nil
+}
+
+// MemoryDenylist keeps the denylist in process memory. It suits tests and
+// single-node deployments; entries are lost on restart.
+type MemoryDenylist struct {
+	mu         sync.Mutex
+	tokens     map[string]time.Time
+	validAfter map[uuid.UUID]memoryCutoff
+}
+
+type memoryCutoff struct {
+	before    time.Time
+	expiresAt time.Time
+}
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{
+		tokens:     make(map[string]time.Time),
+		validAfter: make(map[uuid.UUID]memoryCutoff),
+	}
+}
+
+func (d *MemoryDenylist) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pruneLocked(time.Now())
+	d.tokens[tokenID] = expiresAt
+	return nil
+}
+
+func (d *MemoryDenylist) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	expiresAt, ok := d.tokens[tokenID]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (d *MemoryDenylist) RevokeIssuedBefore(ctx context.Context, userID uuid.UUID, before time.Time, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.pruneLocked(now)
+	d.validAfter[userID] = memoryCutoff{before: before, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (d *MemoryDenylist) TokensValidAfter(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cutoff, ok := d.validAfter[userID]
+	if !ok || !time.Now().Before(cutoff.expiresAt) {
+		return time.Time{}, nil
+	}
+	return cutoff.before, nil
+}
+
+// pruneLocked drops entries whose tokens have expired anyway
+func (d *MemoryDenylist) pruneLocked(now time.Time) {
+	for tokenID, expiresAt := range d.tokens {
+		if !now.Before(expiresAt) {
+			delete(d.tokens, tokenID)
+		}
+	}
+	for userID, cutoff := range d.validAfter {
+		if !now.Before(cutoff.expiresAt) {
+			delete(d.validAfter, userID)
+		}
+	}
+}
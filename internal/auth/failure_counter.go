@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FailureCounter counts failures per key in fixed windows, such as failed
+// logins per client IP. A window starts with the first failure and the count
+// resets when it ends.
+type FailureCounter interface {
+	// Increment adds a failure and returns the count in the current window
+	Increment(ctx context.Context, key string, window time.Duration) (int64, error)
+	// Count returns the failures in the current window and when it ends
+	Count(ctx context.Context, key string) (int64, time.Time, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// MemoryFailureCounter keeps counts in process memory. It suits tests and
+// single-node deployments; counts are lost on restart.
+type MemoryFailureCounter struct {
+	mu      sync.Mutex
+	windows map[string]failureWindow
+}
+
+type failureWindow struct {
+	count     int64
+	expiresAt time.Time
+}
+
+func NewMemoryFailureCounter() *MemoryFailureCounter {
+	return &MemoryFailureCounter{windows: make(map[string]failureWindow)}
+}
+
+func (m *MemoryFailureCounter) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.pruneLocked(now)
+	w, ok := m.windows[key]
+	if !ok {
+		w = failureWindow{expiresAt: now.Add(window)}
+	}
+	w.count++
+	m.windows[key] = w
+	return w.count, nil
+}
+
+func (m *MemoryFailureCounter) Count(ctx context.Context, key string) (int64, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, ok := m.windows[key]
+	if !ok || !time.Now().Before(w.expiresAt) {
+		return 0, time.Time{}, nil
+	}
+	return w.count, w.expiresAt, nil
+}
+
+func (m *MemoryFailureCounter) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.windows, key)
+	return nil
+}
+
+// pruneLocked drops windows that have ended
+func (m *MemoryFailureCounter) pruneLocked(now time.Time) {
+	for key, w := range m.windows {
+		if !now.Before(w.expiresAt) {
+			delete(m.windows, key)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package auth
 
 import (
+	"crypto"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"financial-transaction-system/internal/config"
@@ -17,53 +20,180 @@ var (
 	ErrInvalidClaims = errors.New("invalid token claims")
 )
 
+// mfaTokenExpiry bounds how long a password check stays good for while the
+// user fetches their second factor
+const mfaTokenExpiry = 5 * time.Minute
+
+// JWTManager issues and checks tokens. With HS256 one shared secret does
+// both; with RS256 or EdDSA tokens are signed with a private key and carry
+// its kid, and any of the configured public keys may verify them, so keys can
+// be rotated without ending sessions.
 type JWTManager struct {
-	secretKey     string
-	tokenExpiry   time.Duration
-	refreshExpiry time.Duration
+	method             jwt.SigningMethod
+	signingKey         interface{}
+	signingKeyID       string
+	verificationKeys   map[string]verificationKey
+	jwks               JSONWebKeySet
+	tokenExpiry        time.Duration
+	refreshExpiry      time.Duration
+	verificationExpiry time.Duration
 }
 
 type Claims struct {
-	UserID    uuid.UUID `json:"user_id"`
-	Email     string    `json:"email"`
-	TokenType string    `json:"token_type"` // "access" or "refresh"
+	UserID    uuid.UUID       `json:"user_id"`
+	Email     string          `json:"email"`
+	Role      models.UserRole `json:"role"`
+	TokenType string          `json:"token_type"` // "access", "refresh", "mfa_pending", "email_verification" or "api_key"
+	SessionID string          `json:"sid"`        // shared by every token issued from one login
+	// AuthTime is when the user last proved their credentials; unlike
+	// IssuedAt it is carried over when tokens are refreshed
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func NewJWTManager(cfg *config.Config) *JWTManager {
-	return &JWTManager{
-		secretKey:     cfg.JWT.Secret,
-		tokenExpiry:   cfg.GetJWTExpiry(),
-		refreshExpiry: cfg.GetJWTRefreshExpiry(),
+func NewJWTManager(cfg *config.Config) (*JWTManager, error) {
+	j := &JWTManager{
+		verificationKeys:   make(map[string]verificationKey),
+		jwks:               JSONWebKeySet{Keys: []JSONWebKey{}},
+		tokenExpiry:        cfg.GetJWTExpiry(),
+		refreshExpiry:      cfg.GetJWTRefreshExpiry(),
+		verificationExpiry: cfg.GetEmailVerificationTokenTTL(),
+	}
+
+	switch cfg.JWT.Algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		if cfg.JWT.Secret == "" {
+			return nil, errors.New("JWT_SECRET must be set for HS256")
+		}
+		j.method = jwt.SigningMethodHS256
+		j.signingKey = []byte(cfg.JWT.Secret)
+		j.verificationKeys[""] = verificationKey{method: j.method, key: j.signingKey}
+		return j, nil
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg():
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALGORITHM %q", cfg.JWT.Algorithm)
+	}
+
+	if cfg.JWT.SigningKeyPath == "" {
+		return nil, fmt.Errorf("JWT_SIGNING_KEY_PATH must be set for %s", cfg.JWT.Algorithm)
+	}
+	signer, err := loadPrivateKey(cfg.JWT.SigningKeyPath, cfg.JWT.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := j.addVerificationKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	j.signingKey = signer
+	j.signingKeyID = kid
+	j.method = j.verificationKeys[kid].method
+
+	for _, path := range strings.Split(cfg.JWT.VerificationKeyPaths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := loadPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := j.addVerificationKey(key); err != nil {
+			return nil, err
+		}
 	}
+
+	return j, nil
 }
 
+// addVerificationKey accepts tokens signed by the key's private half and
+// publishes it in the JWKS. It returns the key's kid.
+func (j *JWTManager) addVerificationKey(key crypto.PublicKey) (string, error) {
+	method, err := signingMethodFor(key)
+	if err != nil {
+		return "", err
+	}
+	jwk, err := publicJWK(key)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := j.verificationKeys[jwk.Kid]; ok {
+		return jwk.Kid, nil
+	}
+
+	j.verificationKeys[jwk.Kid] = verificationKey{method: method, key: key}
+	j.jwks.Keys = append(j.jwks.Keys, jwk)
+	return jwk.Kid, nil
+}
+
+// JWKS returns the public keys tokens may be verified with. It is empty with
+// HS256, whose secret can never be published.
+func (j *JWTManager) JWKS() JSONWebKeySet {
+	return j.jwks
+}
+
+// GenerateTokens starts a new session for a user who has just authenticated
 func (j *JWTManager) GenerateTokens(user *models.User) (*models.LoginResponse, error) {
-	accessToken, err := j.generateToken(user, "access", j.tokenExpiry)
+	return j.GenerateSessionTokens(user, uuid.NewString(), time.Now())
+}
+
+// GenerateSessionTokens issues a token pair within an existing session. The
+// caller must record the refresh token's ID before handing it out.
+func (j *JWTManager) GenerateSessionTokens(user *models.User, sessionID string, authTime time.Time) (*models.LoginResponse, error) {
+	now := time.Now()
+
+	accessToken, _, err := j.generateToken(user, "access", sessionID, now, authTime, j.tokenExpiry)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := j.generateToken(user, "refresh", j.refreshExpiry)
+	refreshToken, refreshID, err := j.generateToken(user, "refresh", sessionID, now, authTime, j.refreshExpiry)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.LoginResponse{
-		User:         *user,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresAt:    time.Now().Add(j.tokenExpiry).Unix(),
+		User:             *user,
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresAt:        now.Add(j.tokenExpiry).Unix(),
+		SessionID:        sessionID,
+		RefreshTokenID:   refreshID,
+		RefreshExpiresAt: now.Add(j.refreshExpiry),
 	}, nil
 }
 
-func (j *JWTManager) generateToken(user *models.User, tokenType string, expiry time.Duration) (string, error) {
+// GenerateMFAToken issues the short-lived token a user with two-factor
+// authentication receives after the password check. It only grants the
+// exchange for real tokens together with a valid code.
+func (j *JWTManager) GenerateMFAToken(user *models.User) (string, time.Time, error) {
 	now := time.Now()
+	token, _, err := j.generateToken(user, "mfa_pending", "", now, time.Time{}, mfaTokenExpiry)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, now.Add(mfaTokenExpiry), nil
+}
+
+// GenerateEmailVerificationToken issues the token a verification email
+// carries. It names the address it was sent to, so it cannot verify another.
+func (j *JWTManager) GenerateEmailVerificationToken(user *models.User) (string, error) {
+	token, _, err := j.generateToken(user, "email_verification", "", time.Now(), time.Time{}, j.verificationExpiry)
+	return token, err
+}
+
+// generateToken signs a token with a fresh jti and returns it with that ID. A
+// zero authTime leaves the auth_time claim out.
+func (j *JWTManager) generateToken(user *models.User, tokenType, sessionID string, now, authTime time.Time, expiry time.Duration) (string, uuid.UUID, error) {
+	tokenID := uuid.New()
 	claims := &Claims{
 		UserID:    user.ID,
 		Email:     user.Email,
+		Role:      user.Role,
 		TokenType: tokenType,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID.String(),
 			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
@@ -72,17 +202,23 @@ func (j *JWTManager) generateToken(user *models.User, tokenType string, expiry t
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secretKey))
+	if !authTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(authTime)
+	}
+
+	token := jwt.NewWithClaims(j.method, claims)
+	if j.signingKeyID != "" {
+		token.Header["kid"] = j.signingKeyID
+	}
+	signed, err := token.SignedString(j.signingKey)
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+	return signed, tokenID, nil
 }
 
 func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(j.secretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, j.keyFor)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -99,7 +235,23 @@ func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
-func (j *JWTManager) RefreshToken(refreshTokenString string) (*models.LoginResponse, error) {
+// keyFor picks the key a token is verified with by its kid, and only lets it
+// verify the signing method that key is meant for
+func (j *JWTManager) keyFor(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.verificationKeys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.key, nil
+}
+
+// ParseRefreshToken validates a refresh token. Whether it may still be used
+// is decided against the server-side record of its jti and session.
+func (j *JWTManager) ParseRefreshToken(refreshTokenString string) (*Claims, error) {
 	claims, err := j.ValidateToken(refreshTokenString)
 	if err != nil {
 		return nil, err
@@ -109,12 +261,54 @@ func (j *JWTManager) RefreshToken(refreshTokenString string) (*models.LoginRespo
 		return nil, ErrInvalidToken
 	}
 
-	user := &models.User{
-		ID:    claims.UserID,
-		Email: claims.Email,
+	// Tokens issued before rotation was introduced carry no jti or session
+	if _, err := uuid.Parse(claims.ID); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if _, err := uuid.Parse(claims.SessionID); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// AccessTokenExpiry is the lifetime of an access token, and so how long a
+// revocation has to be remembered
+func (j *JWTManager) AccessTokenExpiry() time.Duration {
+	return j.tokenExpiry
+}
+
+// EmailVerificationTokenExpiry is how long an emailed verification token is valid
+func (j *JWTManager) EmailVerificationTokenExpiry() time.Duration {
+	return j.verificationExpiry
+}
+
+// ParseMFAToken validates a token issued by GenerateMFAToken
+func (j *JWTManager) ParseMFAToken(mfaTokenString string) (*Claims, error) {
+	claims, err := j.ValidateToken(mfaTokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != "mfa_pending" || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// ParseEmailVerificationToken validates a token issued by GenerateEmailVerificationToken
+func (j *JWTManager) ParseEmailVerificationToken(tokenString string) (*Claims, error) {
+	claims, err := j.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
 	}
 
-	return j.GenerateTokens(user)
+	if claims.TokenType != "email_verification" {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
 }
 
 func (j *JWTManager) ExtractUserID(tokenString string) (uuid.UUID, error) {
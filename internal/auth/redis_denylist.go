@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	revokedTokenKeyPrefix = "auth:revoked_token:"
+	validAfterKeyPrefix   = "auth:tokens_valid_after:"
+)
+
+// RedisDenylist shares the denylist between every server instance. Keys
+// expire together with the tokens they deny.
+type RedisDenylist struct {
+	client *redis.Client
+}
+
+func NewRedisDenylist(client *redis.Client) *RedisDenylist {
+	return &RedisDenylist{client: client}
+}
+
+func (d *RedisDenylist) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	if err := d.client.Set(ctx, revokedTokenKeyPrefix+tokenID, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+func (d *RedisDenylist) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	count, err := d.client.Exists(ctx, revokedTokenKeyPrefix+tokenID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (d *RedisDenylist) RevokeIssuedBefore(ctx context.Context, userID uuid.UUID, before time.Time, ttl time.Duration) error {
+	err := d.client.Set(ctx, validAfterKeyPrefix+userID.String(), before.UnixNano(), ttl).Err()
+	if err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	return nil
+}
+
+func (d *RedisDenylist) TokensValidAfter(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	nanos, err := d.client.Get(ctx, validAfterKeyPrefix+userID.String()).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to get user token cut-off: %w", err)
+	}
+	return time.Unix(0, nanos), nil
+}
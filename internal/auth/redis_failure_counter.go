@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const failureCounterKeyPrefix = "auth:failures:"
+
+// RedisFailureCounter shares failure counts between every server instance.
+// Each window is a key that expires when the window ends.
+type RedisFailureCounter struct {
+	client *redis.Client
+}
+
+func NewRedisFailureCounter(client *redis.Client) *RedisFailureCounter {
+	return &RedisFailureCounter{client: client}
+}
+
+func (r *RedisFailureCounter) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, failureCounterKeyPrefix+key)
+		// NX only sets the expiry when the window starts
+		pipe.ExpireNX(ctx, failureCounterKeyPrefix+key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count failure: %w", err)
+	}
+	return incr.Val(), nil
+}
+
+func (r *RedisFailureCounter) Count(ctx context.Context, key string) (int64, time.Time, error) {
+	var get *redis.StringCmd
+	var ttl *redis.DurationCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, failureCounterKeyPrefix+key)
+		ttl = pipe.PTTL(ctx, failureCounterKeyPrefix+key)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, time.Time{}, fmt.Errorf("failed to get failure count: %w", err)
+	}
+
+	count, err := get.Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, time.Time{}, nil
+		}
+		return 0, time.Time{}, fmt.Errorf("failed to get failure count: %w", err)
+	}
+	return count, time.Now().Add(ttl.Val()), nil
+}
+
+func (r *RedisFailureCounter) Reset(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, failureCounterKeyPrefix+key).Err(); err != nil {
+		return fmt.Errorf("failed to reset failure count: %w", err)
+	}
+	return nil
+}
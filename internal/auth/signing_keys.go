@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeyBits is the smallest RSA key accepted for signing or verifying
+const minRSAKeyBits = 2048
+
+// JSONWebKey is the public half of a token signing key, as served in the
+// JWKS document. RSA keys fill N and E, Ed25519 keys Crv and X.
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet is the document other services fetch to verify tokens
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// verificationKey is a key tokens are checked against, with the one signing
+// method it may be used with
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    interface{}
+}
+
+// loadPrivateKey reads a PEM private key for the algorithm: an RSA key in
+// PKCS #1 or PKCS #8 form for RS256, an Ed25519 key in PKCS #8 form for EdDSA
+func loadPrivateKey(path, algorithm string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to parse private key: %w", path, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported private key type %T", path, key)
+	}
+	method, err := signingMethodFor(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if method.Alg() != algorithm {
+		return nil, fmt.Errorf("%s: key is for %s, not %s", path, method.Alg(), algorithm)
+	}
+
+	return signer, nil
+}
+
+// loadPublicKey reads a PEM public key in PKIX form, or PKCS #1 for RSA
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to parse public key: %w", path, err)
+	}
+
+	if _, err := signingMethodFor(key); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+	return block, nil
+}
+
+// signingMethodFor returns the only signing method a public key is used with
+func signingMethodFor(key crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// publicJWK describes a public key as a JSON Web Key. Its kid is the RFC 7638
+// thumbprint, so every holder of the key derives the same ID.
+func publicJWK(key crypto.PublicKey) (JSONWebKey, error) {
+	var jwk JSONWebKey
+	var members interface{}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		jwk = JSONWebKey{
+			Kty: "RSA",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+		// Thumbprint members in lexicographic order, as RFC 7638 requires
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case ed25519.PublicKey:
+		jwk = JSONWebKey{
+			Kty: "OKP",
+			Alg: jwt.SigningMethodEdDSA.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(k),
+		}
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		return JSONWebKey{}, errors.New("unsupported public key type")
+	}
+
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return JSONWebKey{}, err
+	}
+	sum := sha256.Sum256(canonical)
+	jwk.Kid = base64.RawURLEncoding.EncodeToString(sum[:])
+	jwk.Use = "sig"
+	return jwk, nil
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of the RFC 6238 test vectors, base32 encoded
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.code {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		offset int64
+		ok     bool
+	}{
+		{"current step", 0, true},
+		{"previous step", -1, true},
+		{"next step", 1, true},
+		{"two steps old", -2, false},
+		{"two steps ahead", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := totpCode(key, current+tt.offset)
+			step, ok := ValidateTOTP(rfc6238Secret, code, now)
+			if ok != tt.ok {
+				t.Fatalf("ValidateTOTP ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && step != current+tt.offset {
+				t.Errorf("ValidateTOTP step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	if _, ok := ValidateTOTP(strings.ToLower(rfc6238Secret), "050471", now); !ok {
+		t.Error("lowercase secret was rejected")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, "50471", now); ok {
+		t.Error("five-digit code was accepted")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, "0504710", now); ok {
+		t.Error("seven-digit code was accepted")
+	}
+	if _, ok := ValidateTOTP("not base32!", "050471", now); ok {
+		t.Error("code was accepted against an undecodable secret")
+	}
+}
+
+func TestGenerateTOTPSecretValidates(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != totpSecretBytes {
+		t.Fatalf("secret %q does not decode to %d bytes", secret, totpSecretBytes)
+	}
+
+	now := time.Now()
+	code := totpCode(key, now.Unix()/totpPeriod)
+	if _, ok := ValidateTOTP(secret, code, now); !ok {
+		t.Error("current code for a generated secret was rejected")
+	}
+}
+
+func TestHashRecoveryCodeIgnoresFormatting(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(3)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes: %v", err)
+	}
+
+	for _, code := range codes {
+		if len(code) != 9 || code[4] != '-' {
+			t.Errorf("recovery code %q is not formatted as xxxx-xxxx", code)
+		}
+		want := HashRecoveryCode(code)
+		for _, typed := range []string{
+			strings.ToUpper(code),
+			strings.Replace(code, "-", "", 1),
+			strings.Replace(code, "-", " ", 1),
+		} {
+			if got := HashRecoveryCode(typed); got != want {
+				t.Errorf("HashRecoveryCode(%q) differs from HashRecoveryCode(%q)", typed, code)
+			}
+		}
+	}
+}
@@ -4,25 +4,41 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	RabbitMQ RabbitMQConfig
-	JWT      JWTConfig
-	Fraud    FraudConfig
-	Logging  LoggingConfig
+	Server            ServerConfig
+	Database          DatabaseConfig
+	Redis             RedisConfig
+	RabbitMQ          RabbitMQConfig
+	JWT               JWTConfig
+	Fraud             FraudConfig
+	Idempotency       IdempotencyConfig
+	Audit             AuditConfig
+	MFA               MFAConfig
+	StepUp            StepUpConfig
+	LoginProtection   LoginProtectionConfig
+	PasswordPolicy    PasswordPolicyConfig
+	PasswordHash      PasswordHashConfig
+	Mail              MailConfig
+	EmailVerification EmailVerificationConfig
+	PasswordReset     PasswordResetConfig
+	Logging           LoggingConfig
 }
 
+// ServerConfig holds the HTTP listener settings. TrustedProxies lists the
+// addresses and CIDR ranges of the reverse proxies whose X-Forwarded-For
+// headers are believed; with none, the client IP is the connection's remote
+// address.
 type ServerConfig struct {
-	Host string
-	Port string
-	Mode string
+	Host           string
+	Port           string
+	Mode           string
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -49,10 +65,22 @@ type RabbitMQConfig struct {
 	VHost    string
 }
 
+// DefaultJWTSecret is the placeholder JWT_SECRET shipped in the examples. It
+// is public, so the server refuses to run with it in release mode.
+const DefaultJWTSecret = "your-super-secret-jwt-key-change-in-production"
+
+// JWTConfig selects how tokens are signed. HS256 uses Secret. RS256 and EdDSA
+// sign with the PEM private key at SigningKeyPath and also accept tokens
+// signed by the PEM public keys in VerificationKeyPaths, a comma-separated
+// list used while rotating keys.
 type JWTConfig struct {
-	Secret             string
-	ExpiryHours        int
-	RefreshExpiryHours int
+	Secret               string
+	Algorithm            string // "HS256", "RS256" or "EdDSA"
+	SigningKeyPath       string
+	VerificationKeyPaths string
+	ExpiryHours          int
+	RefreshExpiryHours   int
+	DenylistStore        string // "redis" or "memory"
 }
 
 type FraudConfig struct {
@@ -62,6 +90,109 @@ type FraudConfig struct {
 	VelocityWindowMinutes int
 }
 
+type IdempotencyConfig struct {
+	KeyTTLHours int
+}
+
+// AuditConfig holds the base64 Ed25519 keys used to sign and verify audit
+// chain checkpoints. Without a signing key no checkpoints are published.
+type AuditConfig struct {
+	SigningKey                string
+	VerifyKey                 string
+	CheckpointIntervalMinutes int
+}
+
+// MFAConfig holds the base64 AES-256 key TOTP secrets are encrypted with and
+// the issuer name shown in authenticator apps. Without a key two-factor
+// authentication cannot be enrolled.
+type MFAConfig struct {
+	EncryptionKey string
+	Issuer        string
+}
+
+// StepUpConfig controls which transfers need step-up authentication. A zero
+// TransferThreshold turns the amount check off.
+type StepUpConfig struct {
+	TransferThreshold   float64
+	FreshAuthMinutes    int
+	ChallengeTTLMinutes int
+	MaxAttempts         int
+}
+
+// LoginProtectionConfig limits password guessing. An account is locked for
+// LockoutBaseMinutes after MaxFailedAttempts failures within the failure
+// window, twice as long on each further lock up to LockoutMaxMinutes. A client
+// IP with IPMaxFailures failures within its window is refused outright.
+type LoginProtectionConfig struct {
+	MaxFailedAttempts    int
+	FailureWindowMinutes int
+	LockoutBaseMinutes   int
+	LockoutMaxMinutes    int
+	IPMaxFailures        int
+	IPWindowMinutes      int
+	Store                string // "redis" or "memory", for the per-IP counts
+}
+
+// PasswordPolicyConfig lists the rules new passwords must follow. MaxLength
+// is in bytes; bcrypt ignores anything past 72. BreachedRangesDir names a
+// directory of Pwned Passwords range files, and HistoryCount how many of the
+// user's latest passwords, the current one included, cannot be reused. Empty
+// or zero values turn those checks off.
+type PasswordPolicyConfig struct {
+	MinLength          int
+	MaxLength          int
+	RequireUppercase   bool
+	RequireLowercase   bool
+	RequireDigit       bool
+	RequireSymbol      bool
+	RejectPersonalInfo bool
+	BreachedRangesDir  string
+	HistoryCount       int
+}
+
+// PasswordHashConfig selects how new passwords are hashed: "argon2id" with
+// the Argon2* parameters or "bcrypt" at BcryptCost. Stored hashes made with
+// another algorithm or weaker parameters are upgraded at the user's next login.
+type PasswordHashConfig struct {
+	Algorithm         string
+	BcryptCost        int
+	Argon2MemoryKiB   int
+	Argon2Iterations  int
+	Argon2Parallelism int
+}
+
+// MailConfig selects how email is delivered: "smtp" through the SMTP_*
+// relay, "file" as .eml files in FileDir, or "memory" to keep it in process
+type MailConfig struct {
+	Backend      string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+	FileDir      string
+}
+
+// EmailVerificationConfig controls verification emails. LinkURL is the page
+// the emailed link opens, with the token appended as ?token=.
+type EmailVerificationConfig struct {
+	TokenTTLHours         int
+	ResendIntervalSeconds int
+	LinkURL               string
+}
+
+// PasswordResetConfig controls password reset emails. A user is sent at most
+// one token per RequestIntervalSeconds; LinkURL works as for verification. A
+// client IP may ask for IPMaxRequests resets per IPWindowMinutes; zero lifts
+// the limit.
+type PasswordResetConfig struct {
+	TokenTTLMinutes        int
+	RequestIntervalSeconds int
+	LinkURL                string
+	IPMaxRequests          int
+	IPWindowMinutes        int
+}
+
 type LoggingConfig struct {
 	Level  string
 	Format string
@@ -72,9 +203,10 @@ func Load() (*Config, error) {
 
 	config := &Config{
 		Server: ServerConfig{
-			Host: getEnv("SERVER_HOST", "0.0.0.0"),
-			Port: getEnv("SERVER_PORT", "8080"),
-			Mode: getEnv("GIN_MODE", "debug"),
+			Host:           getEnv("SERVER_HOST", "0.0.0.0"),
+			Port:           getEnv("SERVER_PORT", "8080"),
+			Mode:           getEnv("GIN_MODE", "debug"),
+			TrustedProxies: getEnvAsList("TRUSTED_PROXIES"),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -98,9 +230,13 @@ func Load() (*Config, error) {
 			VHost:    getEnv("RABBITMQ_VHOST", "financial_vhost"),
 		},
 		JWT: JWTConfig{
-			Secret:             getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
-			ExpiryHours:        getEnvAsInt("JWT_EXPIRY_HOURS", 24),
-			RefreshExpiryHours: getEnvAsInt("JWT_REFRESH_EXPIRY_HOURS", 168),
+			Secret:               getEnv("JWT_SECRET", DefaultJWTSecret),
+			Algorithm:            getEnv("JWT_ALGORITHM", "HS256"),
+			SigningKeyPath:       getEnv("JWT_SIGNING_KEY_PATH", ""),
+			VerificationKeyPaths: getEnv("JWT_VERIFICATION_KEY_PATHS", ""),
+			ExpiryHours:          getEnvAsInt("JWT_EXPIRY_HOURS", 24),
+			RefreshExpiryHours:   getEnvAsInt("JWT_REFRESH_EXPIRY_HOURS", 168),
+			DenylistStore:        getEnv("JWT_DENYLIST_STORE", "redis"),
 		},
 		Fraud: FraudConfig{
 			MaxDailyAmount:        getEnvAsFloat("FRAUD_MAX_DAILY_AMOUNT", 50000.00),
@@ -108,6 +244,72 @@ func Load() (*Config, error) {
 			VelocityThreshold:     getEnvAsInt("FRAUD_VELOCITY_THRESHOLD", 5),
 			VelocityWindowMinutes: getEnvAsInt("FRAUD_VELOCITY_WINDOW_MINUTES", 10),
 		},
+		Idempotency: IdempotencyConfig{
+			KeyTTLHours: getEnvAsInt("IDEMPOTENCY_KEY_TTL_HOURS", 24),
+		},
+		Audit: AuditConfig{
+			SigningKey:                getEnv("AUDIT_SIGNING_KEY", ""),
+			VerifyKey:                 getEnv("AUDIT_VERIFY_KEY", ""),
+			CheckpointIntervalMinutes: getEnvAsInt("AUDIT_CHECKPOINT_INTERVAL_MINUTES", 60),
+		},
+		MFA: MFAConfig{
+			EncryptionKey: getEnv("MFA_ENCRYPTION_KEY", ""),
+			Issuer:        getEnv("MFA_ISSUER", "Financial Transaction System"),
+		},
+		StepUp: StepUpConfig{
+			TransferThreshold:   getEnvAsFloat("STEP_UP_TRANSFER_THRESHOLD", 5000.00),
+			FreshAuthMinutes:    getEnvAsInt("STEP_UP_FRESH_AUTH_MINUTES", 5),
+			ChallengeTTLMinutes: getEnvAsInt("STEP_UP_CHALLENGE_TTL_MINUTES", 10),
+			MaxAttempts:         getEnvAsInt("STEP_UP_MAX_ATTEMPTS", 3),
+		},
+		LoginProtection: LoginProtectionConfig{
+			MaxFailedAttempts:    getEnvAsInt("LOGIN_MAX_FAILED_ATTEMPTS", 5),
+			FailureWindowMinutes: getEnvAsInt("LOGIN_FAILURE_WINDOW_MINUTES", 15),
+			LockoutBaseMinutes:   getEnvAsInt("LOGIN_LOCKOUT_BASE_MINUTES", 5),
+			LockoutMaxMinutes:    getEnvAsInt("LOGIN_LOCKOUT_MAX_MINUTES", 1440),
+			IPMaxFailures:        getEnvAsInt("LOGIN_IP_MAX_FAILURES", 20),
+			IPWindowMinutes:      getEnvAsInt("LOGIN_IP_WINDOW_MINUTES", 15),
+			Store:                getEnv("LOGIN_THROTTLE_STORE", "redis"),
+		},
+		PasswordPolicy: PasswordPolicyConfig{
+			MinLength:          getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+			MaxLength:          getEnvAsInt("PASSWORD_MAX_LENGTH", 72),
+			RequireUppercase:   getEnvAsBool("PASSWORD_REQUIRE_UPPERCASE", true),
+			RequireLowercase:   getEnvAsBool("PASSWORD_REQUIRE_LOWERCASE", true),
+			RequireDigit:       getEnvAsBool("PASSWORD_REQUIRE_DIGIT", true),
+			RequireSymbol:      getEnvAsBool("PASSWORD_REQUIRE_SYMBOL", false),
+			RejectPersonalInfo: getEnvAsBool("PASSWORD_REJECT_PERSONAL_INFO", true),
+			BreachedRangesDir:  getEnv("PASSWORD_BREACHED_RANGES_DIR", ""),
+			HistoryCount:       getEnvAsInt("PASSWORD_HISTORY_COUNT", 5),
+		},
+		PasswordHash: PasswordHashConfig{
+			Algorithm:         getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+			BcryptCost:        getEnvAsInt("PASSWORD_BCRYPT_COST", 12),
+			Argon2MemoryKiB:   getEnvAsInt("PASSWORD_ARGON2_MEMORY_KIB", 19456),
+			Argon2Iterations:  getEnvAsInt("PASSWORD_ARGON2_ITERATIONS", 2),
+			Argon2Parallelism: getEnvAsInt("PASSWORD_ARGON2_PARALLELISM", 1),
+		},
+		Mail: MailConfig{
+			Backend:      getEnv("MAIL_BACKEND", "smtp"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			From:         getEnv("SMTP_FROM", "noreply@financial-system.com"),
+			FileDir:      getEnv("MAIL_FILE_DIR", "./mail"),
+		},
+		EmailVerification: EmailVerificationConfig{
+			TokenTTLHours:         getEnvAsInt("EMAIL_VERIFICATION_TOKEN_TTL_HOURS", 24),
+			ResendIntervalSeconds: getEnvAsInt("EMAIL_VERIFICATION_RESEND_INTERVAL_SECONDS", 60),
+			LinkURL:               getEnv("EMAIL_VERIFICATION_URL", ""),
+		},
+		PasswordReset: PasswordResetConfig{
+			TokenTTLMinutes:        getEnvAsInt("PASSWORD_RESET_TOKEN_TTL_MINUTES", 30),
+			RequestIntervalSeconds: getEnvAsInt("PASSWORD_RESET_REQUEST_INTERVAL_SECONDS", 60),
+			LinkURL:                getEnv("PASSWORD_RESET_URL", ""),
+			IPMaxRequests:          getEnvAsInt("PASSWORD_RESET_IP_MAX_REQUESTS", 5),
+			IPWindowMinutes:        getEnvAsInt("PASSWORD_RESET_IP_WINDOW_MINUTES", 15),
+		},
 		Logging: LoggingConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
 			Format: getEnv("LOG_FORMAT", "json"),
@@ -150,6 +352,34 @@ func (c *Config) GetJWTRefreshExpiry() time.Duration {
 	return time.Duration(c.JWT.RefreshExpiryHours) * time.Hour
 }
 
+func (c *Config) GetIdempotencyKeyTTL() time.Duration {
+	return time.Duration(c.Idempotency.KeyTTLHours) * time.Hour
+}
+
+func (c *Config) GetEmailVerificationTokenTTL() time.Duration {
+	return time.Duration(c.EmailVerification.TokenTTLHours) * time.Hour
+}
+
+func (c *Config) GetEmailVerificationResendInterval() time.Duration {
+	return time.Duration(c.EmailVerification.ResendIntervalSeconds) * time.Second
+}
+
+func (c *Config) GetPasswordResetTokenTTL() time.Duration {
+	return time.Duration(c.PasswordReset.TokenTTLMinutes) * time.Minute
+}
+
+func (c *Config) GetPasswordResetRequestInterval() time.Duration {
+	return time.Duration(c.PasswordReset.RequestIntervalSeconds) * time.Second
+}
+
+func (c *Config) GetPasswordResetIPWindow() time.Duration {
+	return time.Duration(c.PasswordReset.IPWindowMinutes) * time.Minute
+}
+
+func (c *Config) GetAuditCheckpointInterval() time.Duration {
+	return time.Duration(c.Audit.CheckpointIntervalMinutes) * time.Minute
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -172,3 +402,23 @@ func getEnvAsFloat(name string, defaultVal float64) float64 {
 	}
 	return defaultVal
 }
+
+func getEnvAsBool(name string, defaultVal bool) bool {
+	valueStr := getEnv(name, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultVal
+}
+
+// getEnvAsList splits a comma-separated variable, skipping empty entries. It
+// returns nil when the variable is unset.
+func getEnvAsList(name string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(name, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
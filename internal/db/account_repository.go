@@ -0,0 +1,353 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrAccountNotFound = errors.New("account not found")
+	ErrNegativeBalance = errors.New("balance cannot go below zero")
+)
+
+const accountColumns = `id, user_id, account_number, account_type, account_name, balance, available_balance,
+		       currency, status, daily_limit, monthly_limit, is_primary, created_at, updated_at`
+
+type AccountRepository struct {
+	db *Database
+}
+
+func NewAccountRepository(db *Database) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAccount(row rowScanner) (*models.Account, error) {
+	account := &models.Account{}
+	err := row.Scan(
+		&account.ID,
+		&account.UserID,
+		&account.AccountNumber,
+		&account.AccountType,
+		&account.AccountName,
+		&account.Balance,
+		&account.AvailableBalance,
+		&account.Currency,
+		&account.Status,
+		&account.DailyLimit,
+		&account.MonthlyLimit,
+		&account.IsPrimary,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// Create inserts a new account. The account number is generated by the database.
+// When the account is primary, any other primary account of the same user is demoted.
+func (r *AccountRepository) Create(account *models.Account) error {
+	return r.db.WithTransaction(func(tx *sql.Tx) error {
+		if account.IsPrimary {
+			if err := clearPrimaryTx(tx, account.UserID); err != nil {
+				return err
+			}
+		}
+
+		query := `
+			INSERT INTO accounts (id, user_id, account_number, account_type, account_name, currency,
+			                      daily_limit, monthly_limit, is_primary)
+			VALUES ($1, $2, generate_account_number(), $3, $4, $5, $6, $7, $8)
+			RETURNING account_number, balance, available_balance, status, created_at, updated_at`
+
+		err := tx.QueryRow(
+			query,
+			account.ID,
+			account.UserID,
+			account.AccountType,
+			account.AccountName,
+			account.Currency,
+			account.DailyLimit,
+			account.MonthlyLimit,
+			account.IsPrimary,
+		).Scan(
+			&account.AccountNumber,
+			&account.Balance,
+			&account.AvailableBalance,
+			&account.Status,
+			&account.CreatedAt,
+			&account.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create account: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *AccountRepository) GetByID(id uuid.UUID) (*models.Account, error) {
+	query := fmt.Sprintf(`SELECT %s FROM accounts WHERE id = $1`, accountColumns)
+
+	account, err := scanAccount(r.db.DB.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	return account, nil
+}
+
+func (r *AccountRepository) GetByUserID(userID uuid.UUID) ([]*models.Account, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM accounts
+		WHERE user_id = $1
+		ORDER BY is_primary DESC, created_at ASC`, accountColumns)
+
+	rows, err := r.db.DB.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []*models.Account{}
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+
+	return accounts, nil
+}
+
+func (r *AccountRepository) CountByUserID(userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.DB.QueryRow(`SELECT COUNT(*) FROM accounts WHERE user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count accounts: %w", err)
+	}
+	return count, nil
+}
+
+func (r *AccountRepository) Update(id uuid.UUID, req *models.UpdateAccountRequest) (*models.Account, error) {
+	var account *models.Account
+
+	err := r.db.WithTransaction(func(tx *sql.Tx) error {
+		setParts := []string{}
+		args := []interface{}{}
+		argIndex := 1
+
+		if req.AccountName != nil {
+			setParts = append(setParts, fmt.Sprintf("account_name = $%d", argIndex))
+			args = append(args, *req.AccountName)
+			argIndex++
+		}
+
+		if req.Status != nil {
+			setParts = append(setParts, fmt.Sprintf("status = $%d", argIndex))
+			args = append(args, *req.Status)
+			argIndex++
+		}
+
+		if req.DailyLimit != nil {
+			setParts = append(setParts, fmt.Sprintf("daily_limit = $%d", argIndex))
+			args = append(args, *req.DailyLimit)
+			argIndex++
+		}
+
+		if req.MonthlyLimit != nil {
+			setParts = append(setParts, fmt.Sprintf("monthly_limit = $%d", argIndex))
+			args = append(args, *req.MonthlyLimit)
+			argIndex++
+		}
+
+		if req.IsPrimary != nil {
+			if *req.IsPrimary {
+				var userID uuid.UUID
+				err := tx.QueryRow(`SELECT user_id FROM accounts WHERE id = $1`, id).Scan(&userID)
+				if err != nil {
+					if err == sql.ErrNoRows {
+						return ErrAccountNotFound
+					}
+					return fmt.Errorf("failed to get account: %w", err)
+				}
+				if err := clearPrimaryTx(tx, userID); err != nil {
+					return err
+				}
+			}
+			setParts = append(setParts, fmt.Sprintf("is_primary = $%d", argIndex))
+			args = append(args, *req.IsPrimary)
+			argIndex++
+		}
+
+		if len(setParts) == 0 {
+			query := fmt.Sprintf(`SELECT %s FROM accounts WHERE id = $1`, accountColumns)
+			found, err := scanAccount(tx.QueryRow(query, id))
+			if err != nil {
+				if err == sql.ErrNoRows {
+					return ErrAccountNotFound
+				}
+				return fmt.Errorf("failed to get account: %w", err)
+			}
+			account = found
+			return nil
+		}
+
+		setParts = append(setParts, fmt.Sprintf("updated_at = $%d", argIndex))
+		args = append(args, time.Now())
+		argIndex++
+
+		query := fmt.Sprintf(`
+			UPDATE accounts
+			SET %s
+			WHERE id = $%d
+			RETURNING %s`,
+			strings.Join(setParts, ", "),
+			argIndex,
+			accountColumns,
+		)
+		args = append(args, id)
+
+		updated, err := scanAccount(tx.QueryRow(query, args...))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrAccountNotFound
+			}
+			return fmt.Errorf("failed to update account: %w", err)
+		}
+		account = updated
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+func clearPrimaryTx(tx *sql.Tx, userID uuid.UUID) error {
+	_, err := tx.Exec(`UPDATE accounts SET is_primary = false, updated_at = $1 WHERE user_id = $2 AND is_primary = true`, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to clear primary account: %w", err)
+	}
+	return nil
+}
+
+// LockForUpdateTx locks the given accounts with SELECT ... FOR UPDATE.
+// Rows are always locked in ascending ID order so that two transactions
+// touching the same pair of accounts cannot deadlock each other.
+func (r *AccountRepository) LockForUpdateTx(tx *sql.Tx, ids ...uuid.UUID) (map[uuid.UUID]*models.Account, error) {
+	ordered := make([]uuid.UUID, 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			ordered = append(ordered, id)
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return bytes.Compare(ordered[i][:], ordered[j][:]) < 0
+	})
+
+	query := fmt.Sprintf(`SELECT %s FROM accounts WHERE id = $1 FOR UPDATE`, accountColumns)
+
+	accounts := make(map[uuid.UUID]*models.Account, len(ordered))
+	for _, id := range ordered {
+		account, err := scanAccount(tx.QueryRow(query, id))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, ErrAccountNotFound
+			}
+			return nil, fmt.Errorf("failed to lock account: %w", err)
+		}
+		accounts[id] = account
+	}
+
+	return accounts, nil
+}
+
+// AdjustBalanceTx applies a signed delta to both the ledger and available balance.
+// The row must already be locked by LockForUpdateTx.
+func (r *AccountRepository) AdjustBalanceTx(tx *sql.Tx, id uuid.UUID, delta decimal.Decimal) error {
+	query := `
+		UPDATE accounts
+		SET balance = balance + $1, available_balance = available_balance + $1, updated_at = $2
+		WHERE id = $3`
+
+	result, err := tx.Exec(query, delta, time.Now(), id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23514" { // check_violation on the non-negative balance constraints
+				return ErrNegativeBalance
+			}
+		}
+		return fmt.Errorf("failed to update balance: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrAccountNotFound
+	}
+
+	return nil
+}
+
+// AdjustAvailableBalanceTx applies a signed delta to the available balance
+// only, placing or releasing a hold. The row must already be locked.
+func (r *AccountRepository) AdjustAvailableBalanceTx(tx *sql.Tx, id uuid.UUID, delta decimal.Decimal) error {
+	query := `
+		UPDATE accounts
+		SET available_balance = available_balance + $1, updated_at = $2
+		WHERE id = $3`
+
+	result, err := tx.Exec(query, delta, time.Now(), id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23514" { // check_violation on the non-negative balance constraints
+				return ErrNegativeBalance
+			}
+		}
+		return fmt.Errorf("failed to update available balance: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrAccountNotFound
+	}
+
+	return nil
+}
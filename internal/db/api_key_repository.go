@@ -0,0 +1,161 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+type APIKeyRepository struct {
+	db *Database
+}
+
+func NewAPIKeyRepository(db *Database) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, allowed_ips, expires_at,
+		       last_used_at, host(last_used_ip), revoked_at, created_at`
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		pq.Array(&key.Scopes),
+		pq.Array(&key.AllowedIPs),
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.LastUsedIP,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepository) CreateTx(tx *sql.Tx, key *models.APIKey) error {
+	query := `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, allowed_ips, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at`
+
+	err := tx.QueryRow(
+		query,
+		key.ID,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		pq.Array(key.Scopes),
+		pq.Array(key.AllowedIPs),
+		key.ExpiresAt,
+	).Scan(&key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return nil
+}
+
+// CountActiveByUserTx counts a user's keys that are neither revoked nor expired
+func (r *APIKeyRepository) CountActiveByUserTx(tx *sql.Tx, userID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $2)`
+
+	var count int
+	if err := tx.QueryRow(query, userID, time.Now()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count API keys: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *APIKeyRepository) GetByPrefix(prefix string) (*models.APIKey, error) {
+	query := fmt.Sprintf(`SELECT %s FROM api_keys WHERE prefix = $1`, apiKeyColumns)
+
+	key, err := scanAPIKey(r.db.DB.QueryRow(query, prefix))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// ListByUser returns all of a user's keys, including revoked and expired ones, newest first
+func (r *APIKeyRepository) ListByUser(userID uuid.UUID) ([]*models.APIKey, error) {
+	query := fmt.Sprintf(`SELECT %s FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`, apiKeyColumns)
+
+	rows, err := r.db.DB.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeTx revokes one of a user's keys. Keys that belong to someone else or
+// are already revoked are reported as not found.
+func (r *APIKeyRepository) RevokeTx(tx *sql.Tx, id, userID uuid.UUID) (*models.APIKey, error) {
+	query := fmt.Sprintf(`
+		UPDATE api_keys SET revoked_at = $1
+		WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL
+		RETURNING %s`, apiKeyColumns)
+
+	key, err := scanAPIKey(tx.QueryRow(query, time.Now(), id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// TouchLastUsed records when and where a key was last used. Writes are
+// skipped while the stored time is more recent than interval, so a busy key
+// does not update its row on every request.
+func (r *APIKeyRepository) TouchLastUsed(id uuid.UUID, ip string, interval time.Duration) error {
+	now := time.Now()
+	query := `
+		UPDATE api_keys SET last_used_at = $1, last_used_ip = NULLIF($2, '')::inet
+		WHERE id = $3 AND (last_used_at IS NULL OR last_used_at < $4)`
+
+	if _, err := r.db.DB.Exec(query, now, ip, id, now.Add(-interval)); err != nil {
+		return fmt.Errorf("failed to record API key use: %w", err)
+	}
+
+	return nil
+}
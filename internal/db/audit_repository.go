@@ -0,0 +1,402 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"financial-transaction-system/internal/audit"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const auditLogColumns = `id, seq, user_id, account_id, transaction_id, action, entity_type, entity_id,
+		       old_values, new_values, ip_address, user_agent, session_id, description, metadata,
+		       created_at, prev_hash, hash`
+
+type AuditLogRepository struct {
+	db *Database
+}
+
+func NewAuditLogRepository(db *Database) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+func scanAuditLog(row rowScanner) (*models.AuditLog, error) {
+	log := &models.AuditLog{}
+	var oldValues, newValues, metadata []byte
+	var ipAddress sql.NullString
+	err := row.Scan(
+		&log.ID,
+		&log.Seq,
+		&log.UserID,
+		&log.AccountID,
+		&log.TransactionID,
+		&log.Action,
+		&log.EntityType,
+		&log.EntityID,
+		&oldValues,
+		&newValues,
+		&ipAddress,
+		&log.UserAgent,
+		&log.SessionID,
+		&log.Description,
+		&metadata,
+		&log.CreatedAt,
+		&log.PrevHash,
+		&log.Hash,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(oldValues) > 0 {
+		log.OldValues = oldValues
+	}
+	if len(newValues) > 0 {
+		log.NewValues = newValues
+	}
+	if len(metadata) > 0 {
+		log.Metadata = metadata
+	}
+	if ipAddress.Valid {
+		if ip := net.ParseIP(ipAddress.String); ip != nil {
+			log.IPAddress = &ip
+		}
+	}
+
+	return log, nil
+}
+
+// CreateTx writes an audit entry inside an existing database transaction so
+// that it commits or rolls back together with the change it describes. The
+// entry is linked to the chain head just before the transaction commits:
+// the head stays locked from then until the commit so that entries are
+// chained in commit order, and the returned entry's seq, timestamp and
+// hashes are filled in at that point. Every audited transaction still
+// commits one at a time, so the chain caps audited writes at roughly one
+// commit round trip each, but the lock no longer spans the rest of the work.
+func (r *AuditLogRepository) CreateTx(tx *sql.Tx, req *models.CreateAuditLogRequest) (*models.AuditLog, error) {
+	oldValues, err := marshalAuditJSON(req.OldValues)
+	if err != nil {
+		return nil, err
+	}
+	newValues, err := marshalAuditJSON(req.NewValues)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := marshalAuditJSON(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	log := &models.AuditLog{
+		ID:            uuid.New(),
+		UserID:        req.UserID,
+		AccountID:     req.AccountID,
+		TransactionID: req.TransactionID,
+		Action:        req.Action,
+		EntityType:    req.EntityType,
+		EntityID:      req.EntityID,
+		OldValues:     oldValues,
+		NewValues:     newValues,
+		IPAddress:     req.IPAddress,
+		UserAgent:     req.UserAgent,
+		SessionID:     req.SessionID,
+		Description:   req.Description,
+		Metadata:      metadata,
+	}
+
+	if err := r.db.BeforeCommit(tx, func(tx *sql.Tx) error { return r.appendTx(tx, log) }); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// appendTx links log to the chain head and inserts it
+func (r *AuditLogRepository) appendTx(tx *sql.Tx, log *models.AuditLog) error {
+	var headSeq int64
+	var headHash sql.NullString
+	err := tx.QueryRow(`SELECT seq, hash FROM audit_chain_head WHERE id FOR UPDATE`).Scan(&headSeq, &headHash)
+	if err != nil {
+		return fmt.Errorf("failed to lock audit chain head: %w", err)
+	}
+
+	prevHash := audit.GenesisHash
+	if headHash.Valid {
+		prevHash = headHash.String
+	}
+
+	log.Seq = headSeq + 1
+	log.CreatedAt = audit.StampTime(time.Now())
+	log.PrevHash = &prevHash
+
+	hash, err := audit.Hash(log)
+	if err != nil {
+		return err
+	}
+	log.Hash = &hash
+
+	var ipAddress *string
+	if log.IPAddress != nil {
+		ip := log.IPAddress.String()
+		ipAddress = &ip
+	}
+
+	query := `
+		INSERT INTO audit_logs (id, seq, user_id, account_id, transaction_id, action, entity_type, entity_id,
+		                        old_values, new_values, ip_address, user_agent, session_id, description, metadata,
+		                        created_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`
+
+	_, err = tx.Exec(
+		query,
+		log.ID,
+		log.Seq,
+		log.UserID,
+		log.AccountID,
+		log.TransactionID,
+		log.Action,
+		log.EntityType,
+		log.EntityID,
+		nullableJSON(log.OldValues),
+		nullableJSON(log.NewValues),
+		ipAddress,
+		log.UserAgent,
+		log.SessionID,
+		log.Description,
+		nullableJSON(log.Metadata),
+		log.CreatedAt,
+		log.PrevHash,
+		log.Hash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	_, err = tx.Exec(`UPDATE audit_chain_head SET seq = $1, hash = $2, updated_at = $3 WHERE id`, log.Seq, log.Hash, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to advance audit chain head: %w", err)
+	}
+
+	return nil
+}
+
+// GetChainHead returns the sequence number and hash of the latest entry; the
+// hash is nil until the first chained entry is written
+func (r *AuditLogRepository) GetChainHead() (int64, *string, error) {
+	var seq int64
+	var hash *string
+	if err := r.db.DB.QueryRow(`SELECT seq, hash FROM audit_chain_head WHERE id`).Scan(&seq, &hash); err != nil {
+		return 0, nil, fmt.Errorf("failed to get audit chain head: %w", err)
+	}
+	return seq, hash, nil
+}
+
+// ListAfterSeq returns up to limit entries matching the filter with
+// afterSeq < seq <= throughSeq, in chain order. A nil filter matches every entry.
+func (r *AuditLogRepository) ListAfterSeq(filter *models.AuditLogFilter, afterSeq, throughSeq int64, limit int) ([]*models.AuditLog, error) {
+	conditions, args := auditLogFilterConditions(filter)
+	args = append(args, afterSeq, throughSeq)
+	conditions = append(conditions,
+		fmt.Sprintf("seq > $%d", len(args)-1),
+		fmt.Sprintf("seq <= $%d", len(args)),
+	)
+
+	query := fmt.Sprintf(`SELECT %s FROM audit_logs %s ORDER BY seq LIMIT $%d`,
+		auditLogColumns,
+		whereClause(conditions),
+		len(args)+1,
+	)
+	args = append(args, limit)
+
+	return r.queryAuditLogs(query, args...)
+}
+
+// List returns the entries matching the filter, newest first
+func (r *AuditLogRepository) List(filter *models.AuditLogFilter, limit, offset int) ([]*models.AuditLog, error) {
+	conditions, args := auditLogFilterConditions(filter)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM audit_logs
+		%s
+		ORDER BY seq DESC
+		LIMIT $%d OFFSET $%d`,
+		auditLogColumns,
+		whereClause(conditions),
+		len(args)+1,
+		len(args)+2,
+	)
+	args = append(args, limit, offset)
+
+	return r.queryAuditLogs(query, args...)
+}
+
+// Count returns the number of entries matching the filter
+func (r *AuditLogRepository) Count(filter *models.AuditLogFilter) (int64, error) {
+	conditions, args := auditLogFilterConditions(filter)
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM audit_logs %s`, whereClause(conditions))
+
+	var count int64
+	if err := r.db.DB.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *AuditLogRepository) queryAuditLogs(query string, args ...interface{}) ([]*models.AuditLog, error) {
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []*models.AuditLog{}
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit log: %w", err)
+		}
+		logs = append(logs, log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+
+	return logs, nil
+}
+
+func auditLogFilterConditions(filter *models.AuditLogFilter) ([]string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+	if filter == nil {
+		return conditions, args
+	}
+
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.UserID != nil {
+		add("user_id = $%d", *filter.UserID)
+	}
+	if filter.AccountID != nil {
+		add("account_id = $%d", *filter.AccountID)
+	}
+	if filter.TransactionID != nil {
+		add("transaction_id = $%d", *filter.TransactionID)
+	}
+	if filter.Action != nil {
+		add("action = $%d", *filter.Action)
+	}
+	if filter.EntityType != nil {
+		add("entity_type = $%d", *filter.EntityType)
+	}
+	if filter.EntityID != nil {
+		add("entity_id = $%d", *filter.EntityID)
+	}
+	if filter.StartDate != nil {
+		add("created_at >= $%d", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		add("created_at < $%d", *filter.EndDate)
+	}
+	if filter.IPAddress != nil {
+		add("ip_address = $%d::inet", filter.IPAddress.String())
+	}
+
+	return conditions, args
+}
+
+func (r *AuditLogRepository) CreateCheckpoint(checkpoint *models.AuditCheckpoint) error {
+	query := `
+		INSERT INTO audit_checkpoints (id, seq, head_hash, key_id, signature, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.DB.Exec(
+		query,
+		checkpoint.ID,
+		checkpoint.Seq,
+		checkpoint.HeadHash,
+		checkpoint.KeyID,
+		checkpoint.Signature,
+		checkpoint.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create audit checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// LatestCheckpointSeq returns the chain position of the newest checkpoint, or 0
+func (r *AuditLogRepository) LatestCheckpointSeq() (int64, error) {
+	var seq int64
+	if err := r.db.DB.QueryRow(`SELECT COALESCE(MAX(seq), 0) FROM audit_checkpoints`).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("failed to get latest audit checkpoint: %w", err)
+	}
+	return seq, nil
+}
+
+// ListCheckpoints returns every checkpoint, oldest first
+func (r *AuditLogRepository) ListCheckpoints() ([]*models.AuditCheckpoint, error) {
+	query := `
+		SELECT id, seq, head_hash, key_id, signature, created_at
+		FROM audit_checkpoints
+		ORDER BY seq, created_at`
+
+	rows, err := r.db.DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	checkpoints := []*models.AuditCheckpoint{}
+	for rows.Next() {
+		checkpoint := &models.AuditCheckpoint{}
+		err := rows.Scan(
+			&checkpoint.ID,
+			&checkpoint.Seq,
+			&checkpoint.HeadHash,
+			&checkpoint.KeyID,
+			&checkpoint.Signature,
+			&checkpoint.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit checkpoint: %w", err)
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list audit checkpoints: %w", err)
+	}
+
+	return checkpoints, nil
+}
+
+func marshalAuditJSON(value interface{}) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit values: %w", err)
+	}
+	return data, nil
+}
+
+// nullableJSON stores an empty payload as SQL NULL rather than invalid JSON
+func nullableJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return []byte(data)
+}
@@ -1,8 +1,10 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"sync"
 	"time"
 
 	"financial-transaction-system/internal/config"
@@ -13,6 +15,9 @@ import (
 
 type Database struct {
 	DB *sql.DB
+
+	hooksMu      sync.Mutex
+	beforeCommit map[*sql.Tx][]func(*sql.Tx) error
 }
 
 func NewConnection(cfg *config.Config) (*Database, error) {
@@ -48,19 +53,35 @@ func (d *Database) Health() error {
 
 // Transaction wrapper for database operations
 func (d *Database) WithTransaction(fn func(*sql.Tx) error) error {
-	tx, err := d.DB.Begin()
+	return d.WithTransactionOptions(nil, fn)
+}
+
+// WithTransactionOptions runs fn in a transaction with the given isolation level and access mode
+func (d *Database) WithTransactionOptions(opts *sql.TxOptions, fn func(*sql.Tx) error) error {
+	tx, err := d.DB.BeginTx(context.Background(), opts)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
+	d.hooksMu.Lock()
+	if d.beforeCommit == nil {
+		d.beforeCommit = map[*sql.Tx][]func(*sql.Tx) error{}
+	}
+	d.beforeCommit[tx] = nil
+	d.hooksMu.Unlock()
+
 	defer func() {
+		d.hooksMu.Lock()
+		delete(d.beforeCommit, tx)
+		d.hooksMu.Unlock()
+
 		if p := recover(); p != nil {
 			_ = tx.Rollback()
 			panic(p) // re-throw after rollback
 		}
 	}()
 
-	if err := fn(tx); err != nil {
+	if err := d.runBeforeCommit(tx, fn); err != nil {
 		if rbErr := tx.Rollback(); rbErr != nil {
 			logrus.WithError(rbErr).Error("Failed to rollback transaction")
 		}
@@ -73,3 +94,41 @@ func (d *Database) WithTransaction(fn func(*sql.Tx) error) error {
 
 	return nil
 }
+
+// BeforeCommit defers fn until the transaction tx has done everything else,
+// just before it commits. Hooks run in the order they were added. Work that
+// takes a contended lock goes here so that the lock is held only for the
+// commit itself. For a transaction not started by WithTransactionOptions,
+// fn runs immediately.
+func (d *Database) BeforeCommit(tx *sql.Tx, fn func(*sql.Tx) error) error {
+	d.hooksMu.Lock()
+	hooks, ok := d.beforeCommit[tx]
+	if ok {
+		d.beforeCommit[tx] = append(hooks, fn)
+	}
+	d.hooksMu.Unlock()
+
+	if !ok {
+		return fn(tx)
+	}
+	return nil
+}
+
+// runBeforeCommit runs fn and then the hooks it added with BeforeCommit
+func (d *Database) runBeforeCommit(tx *sql.Tx, fn func(*sql.Tx) error) error {
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	for i := 0; ; i++ {
+		d.hooksMu.Lock()
+		hooks := d.beforeCommit[tx]
+		d.hooksMu.Unlock()
+		if i >= len(hooks) {
+			return nil
+		}
+		if err := hooks[i](tx); err != nil {
+			return err
+		}
+	}
+}
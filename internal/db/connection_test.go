@@ -0,0 +1,68 @@
+//go:build integration
+
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"financial-transaction-system/internal/dbtest"
+)
+
+func TestBeforeCommitRunsHooksLastInOrder(t *testing.T) {
+	database := dbtest.Open(t)
+
+	var steps []string
+	err := database.WithTransaction(func(tx *sql.Tx) error {
+		steps = append(steps, "begin")
+		if err := database.BeforeCommit(tx, func(*sql.Tx) error {
+			steps = append(steps, "first hook")
+			return database.BeforeCommit(tx, func(*sql.Tx) error {
+				steps = append(steps, "nested hook")
+				return nil
+			})
+		}); err != nil {
+			return err
+		}
+		if err := database.BeforeCommit(tx, func(*sql.Tx) error {
+			steps = append(steps, "second hook")
+			return nil
+		}); err != nil {
+			return err
+		}
+		steps = append(steps, "end")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction: %v", err)
+	}
+
+	want := []string{"begin", "end", "first hook", "second hook", "nested hook"}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("ran %v, want %v", steps, want)
+	}
+}
+
+func TestBeforeCommitSkippedOnRollback(t *testing.T) {
+	database := dbtest.Open(t)
+	failed := errors.New("failed")
+
+	ran := false
+	err := database.WithTransaction(func(tx *sql.Tx) error {
+		if err := database.BeforeCommit(tx, func(*sql.Tx) error {
+			ran = true
+			return nil
+		}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("WithTransaction: got %v, want %v", err, failed)
+	}
+	if ran {
+		t.Error("hook ran for a transaction that rolled back")
+	}
+}
@@ -0,0 +1,281 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var ErrFraudAlertNotFound = errors.New("fraud alert not found")
+
+const fraudAlertColumns = `id, user_id, account_id, transaction_id, rule_name, severity, status, risk_score,
+		       description, details, resolved_by, resolved_at, resolution_notes, assigned_to, assigned_at,
+		       created_at, updated_at`
+
+type FraudAlertRepository struct {
+	db *Database
+}
+
+func NewFraudAlertRepository(db *Database) *FraudAlertRepository {
+	return &FraudAlertRepository{db: db}
+}
+
+func scanFraudAlert(row rowScanner) (*models.FraudAlert, error) {
+	alert := &models.FraudAlert{}
+	var details []byte
+	err := row.Scan(
+		&alert.ID,
+		&alert.UserID,
+		&alert.AccountID,
+		&alert.TransactionID,
+		&alert.RuleName,
+		&alert.Severity,
+		&alert.Status,
+		&alert.RiskScore,
+		&alert.Description,
+		&details,
+		&alert.ResolvedBy,
+		&alert.ResolvedAt,
+		&alert.ResolutionNotes,
+		&alert.AssignedTo,
+		&alert.AssignedAt,
+		&alert.CreatedAt,
+		&alert.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(details) > 0 {
+		alert.Details = details
+	}
+	return alert, nil
+}
+
+// CreateTx records an alert in the same database transaction as the
+// transaction it was raised for
+func (r *FraudAlertRepository) CreateTx(tx *sql.Tx, req *models.CreateFraudAlertRequest) (*models.FraudAlert, error) {
+	var details json.RawMessage
+	if req.Details != nil {
+		data, err := json.Marshal(req.Details)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal fraud alert details: %w", err)
+		}
+		details = data
+	}
+
+	alert := &models.FraudAlert{
+		ID:            uuid.New(),
+		UserID:        req.UserID,
+		AccountID:     req.AccountID,
+		TransactionID: req.TransactionID,
+		RuleName:      req.RuleName,
+		Severity:      req.Severity,
+		Status:        models.FraudStatusOpen,
+		RiskScore:     req.RiskScore,
+		Description:   req.Description,
+		Details:       details,
+	}
+
+	query := `
+		INSERT INTO fraud_alerts (id, user_id, account_id, transaction_id, rule_name, severity, status,
+		                          risk_score, description, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING created_at, updated_at`
+
+	err := tx.QueryRow(
+		query,
+		alert.ID,
+		alert.UserID,
+		alert.AccountID,
+		alert.TransactionID,
+		alert.RuleName,
+		alert.Severity,
+		alert.Status,
+		alert.RiskScore,
+		alert.Description,
+		nullableJSON(alert.Details),
+	).Scan(&alert.CreatedAt, &alert.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fraud alert: %w", err)
+	}
+
+	return alert, nil
+}
+
+func (r *FraudAlertRepository) GetByID(id uuid.UUID) (*models.FraudAlert, error) {
+	query := fmt.Sprintf(`SELECT %s FROM fraud_alerts WHERE id = $1`, fraudAlertColumns)
+
+	alert, err := scanFraudAlert(r.db.DB.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrFraudAlertNotFound
+		}
+		return nil, fmt.Errorf("failed to get fraud alert: %w", err)
+	}
+
+	return alert, nil
+}
+
+// LockForUpdateTx loads an alert and locks its row until the database transaction ends
+func (r *FraudAlertRepository) LockForUpdateTx(tx *sql.Tx, id uuid.UUID) (*models.FraudAlert, error) {
+	query := fmt.Sprintf(`SELECT %s FROM fraud_alerts WHERE id = $1 FOR UPDATE`, fraudAlertColumns)
+
+	alert, err := scanFraudAlert(tx.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrFraudAlertNotFound
+		}
+		return nil, fmt.Errorf("failed to lock fraud alert: %w", err)
+	}
+
+	return alert, nil
+}
+
+// UpdateTx saves the case-management fields of a locked alert
+func (r *FraudAlertRepository) UpdateTx(tx *sql.Tx, alert *models.FraudAlert) error {
+	query := `
+		UPDATE fraud_alerts
+		SET status = $1, assigned_to = $2, assigned_at = $3, resolved_by = $4, resolved_at = $5,
+		    resolution_notes = $6, updated_at = $7
+		WHERE id = $8
+		RETURNING updated_at`
+
+	err := tx.QueryRow(
+		query,
+		alert.Status,
+		alert.AssignedTo,
+		alert.AssignedAt,
+		alert.ResolvedBy,
+		alert.ResolvedAt,
+		alert.ResolutionNotes,
+		time.Now(),
+		alert.ID,
+	).Scan(&alert.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrFraudAlertNotFound
+		}
+		return fmt.Errorf("failed to update fraud alert: %w", err)
+	}
+
+	return nil
+}
+
+// CountByTransactionTx counts the alerts raised for a transaction and how many
+// of those were closed as false positives
+func (r *FraudAlertRepository) CountByTransactionTx(tx *sql.Tx, transactionID uuid.UUID) (total int, cleared int, err error) {
+	query := `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE status = 'false_positive')
+		FROM fraud_alerts
+		WHERE transaction_id = $1`
+
+	if err := tx.QueryRow(query, transactionID).Scan(&total, &cleared); err != nil {
+		return 0, 0, fmt.Errorf("failed to count fraud alerts: %w", err)
+	}
+
+	return total, cleared, nil
+}
+
+// List returns the alerts matching the filter, newest first
+func (r *FraudAlertRepository) List(filter *models.FraudAlertFilter, limit, offset int) ([]*models.FraudAlert, error) {
+	conditions, args := fraudAlertFilterConditions(filter)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM fraud_alerts
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d`,
+		fraudAlertColumns,
+		whereClause(conditions),
+		len(args)+1,
+		len(args)+2,
+	)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fraud alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []*models.FraudAlert{}
+	for rows.Next() {
+		alert, err := scanFraudAlert(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fraud alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list fraud alerts: %w", err)
+	}
+
+	return alerts, nil
+}
+
+// Count returns the number of alerts matching the filter
+func (r *FraudAlertRepository) Count(filter *models.FraudAlertFilter) (int64, error) {
+	conditions, args := fraudAlertFilterConditions(filter)
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM fraud_alerts %s`, whereClause(conditions))
+
+	var count int64
+	if err := r.db.DB.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count fraud alerts: %w", err)
+	}
+
+	return count, nil
+}
+
+func fraudAlertFilterConditions(filter *models.FraudAlertFilter) ([]string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.UserID != nil {
+		add("user_id = $%d", *filter.UserID)
+	}
+	if filter.AccountID != nil {
+		add("account_id = $%d", *filter.AccountID)
+	}
+	if filter.TransactionID != nil {
+		add("transaction_id = $%d", *filter.TransactionID)
+	}
+	if filter.RuleName != nil {
+		add("rule_name = $%d", *filter.RuleName)
+	}
+	if filter.Severity != nil {
+		add("severity = $%d", *filter.Severity)
+	}
+	if filter.Status != nil {
+		add("status = $%d", *filter.Status)
+	}
+	if filter.MinRiskScore != nil {
+		add("risk_score >= $%d", *filter.MinRiskScore)
+	}
+	if filter.MaxRiskScore != nil {
+		add("risk_score <= $%d", *filter.MaxRiskScore)
+	}
+	if filter.AssignedTo != nil {
+		add("assigned_to = $%d", *filter.AssignedTo)
+	}
+	if filter.StartDate != nil {
+		add("created_at >= $%d", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		add("created_at < $%d", *filter.EndDate)
+	}
+
+	return conditions, args
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+
+type IdempotencyRepository struct {
+	db *Database
+}
+
+func NewIdempotencyRepository(db *Database) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims a key for a new request. It returns false when a live
+// record for the key already exists; an expired record is taken over.
+func (r *IdempotencyRepository) Reserve(record *models.IdempotencyKey) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_method, request_path, request_fingerprint, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE
+		SET request_method = EXCLUDED.request_method,
+		    request_path = EXCLUDED.request_path,
+		    request_fingerprint = EXCLUDED.request_fingerprint,
+		    response_status = NULL,
+		    response_body = NULL,
+		    created_at = NOW(),
+		    expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= NOW()
+		RETURNING created_at`
+
+	err := r.db.DB.QueryRow(
+		query,
+		record.UserID,
+		record.Key,
+		record.RequestMethod,
+		record.RequestPath,
+		record.RequestFingerprint,
+		record.ExpiresAt,
+	).Scan(&record.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *IdempotencyRepository) Get(userID uuid.UUID, key string) (*models.IdempotencyKey, error) {
+	record := &models.IdempotencyKey{}
+	query := `
+		SELECT user_id, idempotency_key, request_method, request_path, request_fingerprint,
+		       response_status, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2`
+
+	err := r.db.DB.QueryRow(query, userID, key).Scan(
+		&record.UserID,
+		&record.Key,
+		&record.RequestMethod,
+		&record.RequestPath,
+		&record.RequestFingerprint,
+		&record.ResponseStatus,
+		&record.ResponseBody,
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrIdempotencyKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return record, nil
+}
+
+func (r *IdempotencyRepository) Complete(userID uuid.UUID, key string, status int, body []byte) error {
+	query := `UPDATE idempotency_keys SET response_status = $1, response_body = $2 WHERE user_id = $3 AND idempotency_key = $4`
+
+	result, err := r.db.DB.Exec(query, status, body, userID, key)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrIdempotencyKeyNotFound
+	}
+
+	return nil
+}
+
+// Delete releases an in-flight key so the client can retry with it
+func (r *IdempotencyRepository) Delete(userID uuid.UUID, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2 AND response_status IS NULL`
+
+	if _, err := r.db.DB.Exec(query, userID, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepository) DeleteExpired() (int64, error) {
+	result, err := r.db.DB.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+
+	return result.RowsAffected()
+}
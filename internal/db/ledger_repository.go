@@ -0,0 +1,215 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type LedgerRepository struct {
+	db *Database
+}
+
+func NewLedgerRepository(db *Database) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// CreateEntryTx inserts a journal entry and its postings inside an existing
+// database transaction. The deferred balance trigger rejects the commit if
+// the postings do not balance.
+func (r *LedgerRepository) CreateEntryTx(tx *sql.Tx, entry *models.JournalEntry) error {
+	query := `
+		INSERT INTO journal_entries (id, transaction_id, entry_type, description)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at`
+
+	err := tx.QueryRow(query, entry.ID, entry.TransactionID, entry.EntryType, entry.Description).Scan(&entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create journal entry: %w", err)
+	}
+
+	postingQuery := `
+		INSERT INTO postings (id, journal_entry_id, account_id, system_account_id, direction, amount, currency)
+		VALUES ($1, $2, $3, (SELECT id FROM system_accounts WHERE code = NULLIF($4, '')), $5, $6, $7)
+		RETURNING created_at`
+
+	for i := range entry.Postings {
+		posting := &entry.Postings[i]
+		posting.JournalEntryID = entry.ID
+
+		err := tx.QueryRow(
+			postingQuery,
+			posting.ID,
+			posting.JournalEntryID,
+			posting.AccountID,
+			string(posting.SystemAccount),
+			posting.Direction,
+			posting.Amount,
+			posting.Currency,
+		).Scan(&posting.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create posting: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetEntriesByTransactionIDTx loads the journal entries posted for a transaction, with their postings
+func (r *LedgerRepository) GetEntriesByTransactionIDTx(tx *sql.Tx, transactionID uuid.UUID) ([]*models.JournalEntry, error) {
+	query := `
+		SELECT je.id, je.transaction_id, je.entry_type, je.description, je.created_at,
+		       p.id, p.account_id, COALESCE(sa.code, ''), p.direction, p.amount, p.currency, p.created_at
+		FROM journal_entries je
+		JOIN postings p ON p.journal_entry_id = je.id
+		LEFT JOIN system_accounts sa ON sa.id = p.system_account_id
+		WHERE je.transaction_id = $1
+		ORDER BY je.created_at, je.id, p.created_at, p.id`
+
+	rows, err := tx.Query(query, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get journal entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.JournalEntry{}
+	byID := map[uuid.UUID]*models.JournalEntry{}
+	for rows.Next() {
+		var entry models.JournalEntry
+		var posting models.Posting
+		err := rows.Scan(
+			&entry.ID,
+			&entry.TransactionID,
+			&entry.EntryType,
+			&entry.Description,
+			&entry.CreatedAt,
+			&posting.ID,
+			&posting.AccountID,
+			&posting.SystemAccount,
+			&posting.Direction,
+			&posting.Amount,
+			&posting.Currency,
+			&posting.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan journal entry: %w", err)
+		}
+
+		existing, ok := byID[entry.ID]
+		if !ok {
+			existing = &entry
+			byID[entry.ID] = existing
+			entries = append(entries, existing)
+		}
+		posting.JournalEntryID = existing.ID
+		existing.Postings = append(existing.Postings, posting)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get journal entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetAccountBalance derives an account's balance from its postings
+func (r *LedgerRepository) GetAccountBalance(accountID uuid.UUID) (decimal.Decimal, error) {
+	query := `SELECT ledger_balance FROM account_ledger_balances WHERE account_id = $1`
+
+	var balance decimal.Decimal
+	if err := r.db.DB.QueryRow(query, accountID).Scan(&balance); err != nil {
+		if err == sql.ErrNoRows {
+			return decimal.Zero, ErrAccountNotFound
+		}
+		return decimal.Zero, fmt.Errorf("failed to get ledger balance: %w", err)
+	}
+
+	return balance, nil
+}
+
+// GetAccountBalanceAtTx derives an account's balance from the postings made before the given time
+func (r *LedgerRepository) GetAccountBalanceAtTx(tx *sql.Tx, accountID uuid.UUID, before time.Time) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END), 0)
+		FROM postings
+		WHERE account_id = $1 AND created_at < $2`
+
+	var balance decimal.Decimal
+	if err := tx.QueryRow(query, accountID, before).Scan(&balance); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get ledger balance: %w", err)
+	}
+
+	return balance, nil
+}
+
+// GetAccountMovementsTx totals, per journal entry, the postings made to an
+// account in [start, end), in posting order
+func (r *LedgerRepository) GetAccountMovementsTx(tx *sql.Tx, accountID uuid.UUID, start, end time.Time) ([]models.AccountMovement, error) {
+	query := `
+		SELECT je.id, je.entry_type, je.transaction_id,
+		       COALESCE(SUM(p.amount) FILTER (WHERE p.direction = 'credit'), 0),
+		       COALESCE(SUM(p.amount) FILTER (WHERE p.direction = 'debit'), 0),
+		       MIN(p.created_at)
+		FROM postings p
+		JOIN journal_entries je ON je.id = p.journal_entry_id
+		WHERE p.account_id = $1 AND p.created_at >= $2 AND p.created_at < $3
+		GROUP BY je.id, je.entry_type, je.transaction_id
+		ORDER BY MIN(p.created_at), je.id`
+
+	rows, err := tx.Query(query, accountID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account movements: %w", err)
+	}
+	defer rows.Close()
+
+	movements := []models.AccountMovement{}
+	for rows.Next() {
+		var m models.AccountMovement
+		if err := rows.Scan(&m.JournalEntryID, &m.EntryType, &m.TransactionID, &m.Credits, &m.Debits, &m.PostedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan account movement: %w", err)
+		}
+		movements = append(movements, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get account movements: %w", err)
+	}
+
+	return movements, nil
+}
+
+// FindBalanceMismatches lists accounts whose cached balance differs from their postings
+func (r *LedgerRepository) FindBalanceMismatches() ([]models.BalanceMismatch, error) {
+	query := `
+		SELECT a.id, a.account_number, a.currency, a.balance, l.ledger_balance
+		FROM accounts a
+		JOIN account_ledger_balances l ON l.account_id = a.id
+		WHERE a.balance <> l.ledger_balance
+		ORDER BY a.account_number`
+
+	rows, err := r.db.DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile balances: %w", err)
+	}
+	defer rows.Close()
+
+	mismatches := []models.BalanceMismatch{}
+	for rows.Next() {
+		var m models.BalanceMismatch
+		if err := rows.Scan(&m.AccountID, &m.AccountNumber, &m.Currency, &m.CachedBalance, &m.LedgerBalance); err != nil {
+			return nil, fmt.Errorf("failed to scan balance mismatch: %w", err)
+		}
+		mismatches = append(mismatches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to reconcile balances: %w", err)
+	}
+
+	return mismatches, nil
+}
@@ -0,0 +1,180 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMFANotFound       = errors.New("mfa enrollment not found")
+	ErrMFAAlreadyEnabled = errors.New("mfa is already enabled")
+)
+
+type MFARepository struct {
+	db *Database
+}
+
+func NewMFARepository(db *Database) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+const userMFAColumns = `user_id, secret_ciphertext, enabled_at, last_used_step, created_at, updated_at`
+
+func scanUserMFA(row rowScanner) (*models.UserMFA, error) {
+	mfa := &models.UserMFA{}
+	err := row.Scan(
+		&mfa.UserID,
+		&mfa.SecretCiphertext,
+		&mfa.EnabledAt,
+		&mfa.LastUsedStep,
+		&mfa.CreatedAt,
+		&mfa.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return mfa, nil
+}
+
+func (r *MFARepository) GetByUserID(userID uuid.UUID) (*models.UserMFA, error) {
+	query := fmt.Sprintf(`SELECT %s FROM user_mfa WHERE user_id = $1`, userMFAColumns)
+
+	mfa, err := scanUserMFA(r.db.DB.QueryRow(query, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMFANotFound
+		}
+		return nil, fmt.Errorf("failed to get mfa enrollment: %w", err)
+	}
+
+	return mfa, nil
+}
+
+// LockForUpdateTx loads an enrollment and locks it until the transaction ends,
+// so that one code cannot be accepted twice by concurrent requests
+func (r *MFARepository) LockForUpdateTx(tx *sql.Tx, userID uuid.UUID) (*models.UserMFA, error) {
+	query := fmt.Sprintf(`SELECT %s FROM user_mfa WHERE user_id = $1 FOR UPDATE`, userMFAColumns)
+
+	mfa, err := scanUserMFA(tx.QueryRow(query, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMFANotFound
+		}
+		return nil, fmt.Errorf("failed to lock mfa enrollment: %w", err)
+	}
+
+	return mfa, nil
+}
+
+// SaveEnrollment stores a new, unconfirmed secret, replacing any earlier
+// unconfirmed one. A confirmed enrollment is never overwritten.
+func (r *MFARepository) SaveEnrollment(userID uuid.UUID, secretCiphertext []byte) error {
+	query := `
+		INSERT INTO user_mfa (user_id, secret_ciphertext)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_ciphertext = EXCLUDED.secret_ciphertext, last_used_step = NULL
+		WHERE user_mfa.enabled_at IS NULL`
+
+	result, err := r.db.DB.Exec(query, userID, secretCiphertext)
+	if err != nil {
+		return fmt.Errorf("failed to save mfa enrollment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+
+	return nil
+}
+
+// EnableTx confirms an enrollment with the time step of its first code
+func (r *MFARepository) EnableTx(tx *sql.Tx, userID uuid.UUID, step int64) error {
+	query := `UPDATE user_mfa SET enabled_at = $1, last_used_step = $2 WHERE user_id = $3`
+
+	if _, err := tx.Exec(query, time.Now(), step, userID); err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MFARepository) UpdateLastUsedStepTx(tx *sql.Tx, userID uuid.UUID, step int64) error {
+	query := `UPDATE user_mfa SET last_used_step = $1 WHERE user_id = $2`
+
+	if _, err := tx.Exec(query, step, userID); err != nil {
+		return fmt.Errorf("failed to update mfa step: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTx removes the enrollment together with its recovery codes
+func (r *MFARepository) DeleteTx(tx *sql.Tx, userID uuid.UUID) error {
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete mfa enrollment: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodesTx discards the user's recovery codes and stores new ones
+func (r *MFARepository) ReplaceRecoveryCodesTx(tx *sql.Tx, userID uuid.UUID, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, codeHash := range codeHashes {
+		_, err := tx.Exec(`INSERT INTO mfa_recovery_codes (id, user_id, code_hash) VALUES ($1, $2, $3)`, uuid.New(), userID, codeHash)
+		if err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCodeTx spends an unused recovery code; it reports false if the
+// code does not exist or was already used
+func (r *MFARepository) UseRecoveryCodeTx(tx *sql.Tx, userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = $1
+		WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`
+
+	result, err := tx.Exec(query, time.Now(), userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// CountUnusedRecoveryCodesTx returns how many recovery codes the user has left
+func (r *MFARepository) CountUnusedRecoveryCodesTx(tx *sql.Tx, userID uuid.UUID) (int, error) {
+	var count int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
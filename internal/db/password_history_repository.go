@@ -0,0 +1,66 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type PasswordHistoryRepository struct {
+	db *Database
+}
+
+func NewPasswordHistoryRepository(db *Database) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{db: db}
+}
+
+// ListRecentTx returns the hashes of a user's previous passwords, newest first
+func (r *PasswordHistoryRepository) ListRecentTx(tx *sql.Tx, userID uuid.UUID, limit int) ([]string, error) {
+	query := `
+		SELECT password_hash FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	rows, err := tx.Query(query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list password history: %w", err)
+	}
+	defer rows.Close()
+
+	hashes := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("failed to scan password history: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate password history: %w", err)
+	}
+
+	return hashes, nil
+}
+
+// AddTx records a password the user no longer uses and drops all but the
+// newest keep entries
+func (r *PasswordHistoryRepository) AddTx(tx *sql.Tx, userID uuid.UUID, passwordHash string, keep int) error {
+	if _, err := tx.Exec(`INSERT INTO password_history (user_id, password_hash) VALUES ($1, $2)`, userID, passwordHash); err != nil {
+		return fmt.Errorf("failed to record password history: %w", err)
+	}
+
+	query := `
+		DELETE FROM password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2
+		)`
+
+	if _, err := tx.Exec(query, userID, keep); err != nil {
+		return fmt.Errorf("failed to prune password history: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var ErrPasswordResetTokenNotFound = errors.New("password reset token not found")
+
+type PasswordResetRepository struct {
+	db *Database
+}
+
+func NewPasswordResetRepository(db *Database) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// CreateUnlessRecent stores a token unless the user was issued one less than
+// interval ago, in which case it reports false and stores nothing
+func (r *PasswordResetRepository) CreateUnlessRecent(token *models.PasswordResetToken, interval time.Duration) (bool, error) {
+	now := time.Now()
+	query := `
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at)
+		SELECT $1, $2, $3, $4, $5
+		WHERE NOT EXISTS (
+			SELECT 1 FROM password_reset_tokens WHERE user_id = $2 AND created_at > $6
+		)`
+
+	result, err := r.db.DB.Exec(query, token.ID, token.UserID, token.TokenHash, token.ExpiresAt, now, now.Add(-interval))
+	if err != nil {
+		return false, fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	token.CreatedAt = now
+	return rowsAffected > 0, nil
+}
+
+// LockByHashTx loads a token by its hash and locks it until the transaction
+// ends, so that it cannot be spent twice
+func (r *PasswordResetRepository) LockByHashTx(tx *sql.Tx, tokenHash string) (*models.PasswordResetToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1
+		FOR UPDATE`
+
+	token := &models.PasswordResetToken{}
+	err := tx.QueryRow(query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPasswordResetTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to lock password reset token: %w", err)
+	}
+
+	return token, nil
+}
+
+// SpendAllForUserTx uses up every outstanding reset token of the user
+func (r *PasswordResetRepository) SpendAllForUserTx(tx *sql.Tx, userID uuid.UUID) error {
+	query := `UPDATE password_reset_tokens SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL`
+
+	if _, err := tx.Exec(query, time.Now(), userID); err != nil {
+		return fmt.Errorf("failed to spend password reset tokens: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes tokens that can no longer be used
+func (r *PasswordResetRepository) DeleteExpired() (int64, error) {
+	result, err := r.db.DB.Exec(`DELETE FROM password_reset_tokens WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge password reset tokens: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// EnqueueRequest queues a reset email for the address. A request already
+// waiting for the same address is kept instead.
+func (r *PasswordResetRepository) EnqueueRequest(email string) error {
+	query := `
+		INSERT INTO password_reset_requests (email)
+		VALUES ($1)
+		ON CONFLICT ((LOWER(email))) DO NOTHING`
+
+	if _, err := r.db.DB.Exec(query, email); err != nil {
+		return fmt.Errorf("failed to queue password reset request: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimRequestTx takes the oldest queued request and removes it from the
+// queue when the transaction commits. Requests claimed by other workers are
+// skipped. It reports false when the queue is empty.
+func (r *PasswordResetRepository) ClaimRequestTx(tx *sql.Tx) (string, bool, error) {
+	query := `
+		DELETE FROM password_reset_requests
+		WHERE id = (
+			SELECT id FROM password_reset_requests
+			ORDER BY requested_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING email`
+
+	var email string
+	if err := tx.QueryRow(query).Scan(&email); err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to claim password reset request: %w", err)
+	}
+
+	return email, true, nil
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var ErrRefreshTokenNotFound = errors.New("refresh token not found")
+
+type RefreshTokenRepository struct {
+	db *Database
+}
+
+func NewRefreshTokenRepository(db *Database) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+func (r *RefreshTokenRepository) CreateTx(tx *sql.Tx, token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, user_id, family_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at`
+
+	err := tx.QueryRow(query, token.ID, token.UserID, token.FamilyID, token.ExpiresAt).Scan(&token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// LockForUpdateTx loads a refresh token and locks its row until the database
+// transaction ends, so that two concurrent refreshes cannot both rotate it
+func (r *RefreshTokenRepository) LockForUpdateTx(tx *sql.Tx, id uuid.UUID) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, family_id, replaced_by, expires_at, used_at, revoked_at, revoked_reason, created_at
+		FROM refresh_tokens
+		WHERE id = $1
+		FOR UPDATE`
+
+	token := &models.RefreshToken{}
+	err := tx.QueryRow(query, id).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.ReplacedBy,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.RevokedAt,
+		&token.RevokedReason,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to lock refresh token: %w", err)
+	}
+
+	return token, nil
+}
+
+// MarkUsedTx records that a token was rotated into its replacement
+func (r *RefreshTokenRepository) MarkUsedTx(tx *sql.Tx, id, replacedBy uuid.UUID) error {
+	query := `UPDATE refresh_tokens SET used_at = $1, replaced_by = $2 WHERE id = $3`
+
+	if _, err := tx.Exec(query, time.Now(), replacedBy, id); err != nil {
+		return fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeFamilyTx revokes every live token descended from the same login
+func (r *RefreshTokenRepository) RevokeFamilyTx(tx *sql.Tx, familyID uuid.UUID, reason string) (int64, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $1, revoked_reason = $2
+		WHERE family_id = $3 AND revoked_at IS NULL`
+
+	result, err := tx.Exec(query, time.Now(), reason, familyID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// RevokeAllForUser revokes every live refresh token the user holds
+func (r *RefreshTokenRepository) RevokeAllForUser(userID uuid.UUID, reason string) (int64, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $1, revoked_reason = $2
+		WHERE user_id = $3 AND revoked_at IS NULL AND expires_at > $1`
+
+	result, err := r.db.DB.Exec(query, time.Now(), reason, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// RevokeAllForUserTx revokes the user's live refresh tokens inside an
+// existing database transaction
+func (r *RefreshTokenRepository) RevokeAllForUserTx(tx *sql.Tx, userID uuid.UUID, reason string) (int64, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $1, revoked_reason = $2
+		WHERE user_id = $3 AND revoked_at IS NULL AND expires_at > $1`
+
+	result, err := tx.Exec(query, time.Now(), reason, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteExpired removes tokens that can no longer be presented
+func (r *RefreshTokenRepository) DeleteExpired() (int64, error) {
+	result, err := r.db.DB.Exec(`DELETE FROM refresh_tokens WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge refresh tokens: %w", err)
+	}
+
+	return result.RowsAffected()
+}
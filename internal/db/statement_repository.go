@@ -0,0 +1,98 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var ErrStatementNotFound = errors.New("statement not found")
+
+type StatementRepository struct {
+	db *Database
+}
+
+func NewStatementRepository(db *Database) *StatementRepository {
+	return &StatementRepository{db: db}
+}
+
+// GetByPeriod loads an issued statement for exactly the given period
+func (r *StatementRepository) GetByPeriod(accountID uuid.UUID, start, end time.Time) (*models.TransactionStatement, error) {
+	query := `
+		SELECT s.account_id, a.account_number, s.period_start, s.period_end, s.opening_balance, s.closing_balance,
+		       s.total_credits, s.total_debits, s.total_fees, s.transaction_count, s.transactions, s.issued_at
+		FROM account_statements s
+		JOIN accounts a ON a.id = s.account_id
+		WHERE s.account_id = $1 AND s.period_start = $2 AND s.period_end = $3`
+
+	statement := &models.TransactionStatement{}
+	var transactions []byte
+	var issuedAt time.Time
+	err := r.db.DB.QueryRow(query, accountID, start, end).Scan(
+		&statement.AccountID,
+		&statement.AccountNumber,
+		&statement.StartDate,
+		&statement.EndDate,
+		&statement.OpeningBalance,
+		&statement.ClosingBalance,
+		&statement.Summary.TotalCredits,
+		&statement.Summary.TotalDebits,
+		&statement.Summary.TotalFees,
+		&statement.Summary.TransactionCount,
+		&transactions,
+		&issuedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrStatementNotFound
+		}
+		return nil, fmt.Errorf("failed to get statement: %w", err)
+	}
+
+	if err := json.Unmarshal(transactions, &statement.Transactions); err != nil {
+		return nil, fmt.Errorf("failed to decode statement transactions: %w", err)
+	}
+	statement.IssuedAt = &issuedAt
+
+	return statement, nil
+}
+
+// Create issues a statement and returns it as stored. If one was already
+// issued for the period, that statement is returned unchanged.
+func (r *StatementRepository) Create(statement *models.TransactionStatement) (*models.TransactionStatement, error) {
+	transactions, err := json.Marshal(statement.Transactions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode statement transactions: %w", err)
+	}
+
+	query := `
+		INSERT INTO account_statements (account_id, period_start, period_end, opening_balance, closing_balance,
+		                                total_credits, total_debits, total_fees, transaction_count, transactions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (account_id, period_start, period_end) DO NOTHING`
+
+	_, err = r.db.DB.Exec(
+		query,
+		statement.AccountID,
+		statement.StartDate,
+		statement.EndDate,
+		statement.OpeningBalance,
+		statement.ClosingBalance,
+		statement.Summary.TotalCredits,
+		statement.Summary.TotalDebits,
+		statement.Summary.TotalFees,
+		statement.Summary.TransactionCount,
+		transactions,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create statement: %w", err)
+	}
+
+	return r.GetByPeriod(statement.AccountID, statement.StartDate, statement.EndDate)
+}
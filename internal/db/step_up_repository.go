@@ -0,0 +1,148 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ErrStepUpChallengeNotFound = errors.New("step-up challenge not found")
+
+type StepUpRepository struct {
+	db *Database
+}
+
+func NewStepUpRepository(db *Database) *StepUpRepository {
+	return &StepUpRepository{db: db}
+}
+
+const stepUpChallengeColumns = `id, user_id, transaction_id, reasons, status, attempts, expires_at, completed_at, created_at`
+
+func scanStepUpChallenge(row rowScanner) (*models.StepUpChallenge, error) {
+	challenge := &models.StepUpChallenge{}
+	err := row.Scan(
+		&challenge.ID,
+		&challenge.UserID,
+		&challenge.TransactionID,
+		pq.Array(&challenge.Reasons),
+		&challenge.Status,
+		&challenge.Attempts,
+		&challenge.ExpiresAt,
+		&challenge.CompletedAt,
+		&challenge.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+func (r *StepUpRepository) CreateTx(tx *sql.Tx, challenge *models.StepUpChallenge) error {
+	query := `
+		INSERT INTO step_up_challenges (id, user_id, transaction_id, reasons, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING attempts, created_at`
+
+	err := tx.QueryRow(
+		query,
+		challenge.ID,
+		challenge.UserID,
+		challenge.TransactionID,
+		pq.Array(challenge.Reasons),
+		challenge.Status,
+		challenge.ExpiresAt,
+	).Scan(&challenge.Attempts, &challenge.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create step-up challenge: %w", err)
+	}
+
+	return nil
+}
+
+// LockForUpdateTx loads a challenge and locks it until the transaction ends,
+// so that concurrent confirmations are counted one at a time
+func (r *StepUpRepository) LockForUpdateTx(tx *sql.Tx, id uuid.UUID) (*models.StepUpChallenge, error) {
+	query := fmt.Sprintf(`SELECT %s FROM step_up_challenges WHERE id = $1 FOR UPDATE`, stepUpChallengeColumns)
+
+	challenge, err := scanStepUpChallenge(tx.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrStepUpChallengeNotFound
+		}
+		return nil, fmt.Errorf("failed to lock step-up challenge: %w", err)
+	}
+
+	return challenge, nil
+}
+
+// HasPendingTx reports whether a transaction is still waiting for step-up authentication
+func (r *StepUpRepository) HasPendingTx(tx *sql.Tx, transactionID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM step_up_challenges WHERE transaction_id = $1 AND status = 'pending')`
+
+	var pending bool
+	if err := tx.QueryRow(query, transactionID).Scan(&pending); err != nil {
+		return false, fmt.Errorf("failed to check step-up challenge: %w", err)
+	}
+
+	return pending, nil
+}
+
+func (r *StepUpRepository) IncrementAttemptsTx(tx *sql.Tx, challenge *models.StepUpChallenge) error {
+	query := `UPDATE step_up_challenges SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`
+
+	if err := tx.QueryRow(query, challenge.ID).Scan(&challenge.Attempts); err != nil {
+		return fmt.Errorf("failed to record step-up attempt: %w", err)
+	}
+
+	return nil
+}
+
+// CompleteTx closes a pending challenge with its final status
+func (r *StepUpRepository) CompleteTx(tx *sql.Tx, challenge *models.StepUpChallenge, status models.StepUpStatus) error {
+	now := time.Now()
+	query := `UPDATE step_up_challenges SET status = $1, completed_at = $2 WHERE id = $3 AND status = 'pending'`
+
+	if _, err := tx.Exec(query, status, now, challenge.ID); err != nil {
+		return fmt.Errorf("failed to complete step-up challenge: %w", err)
+	}
+
+	challenge.Status = status
+	challenge.CompletedAt = &now
+	return nil
+}
+
+// ListExpiredIDs returns pending challenges whose deadline has passed, oldest first
+func (r *StepUpRepository) ListExpiredIDs(limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT id FROM step_up_challenges
+		WHERE status = 'pending' AND expires_at < $1
+		ORDER BY expires_at
+		LIMIT $2`
+
+	rows, err := r.db.DB.Query(query, time.Now(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired step-up challenges: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan step-up challenge: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate step-up challenges: %w", err)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,449 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrTransactionNotFound      = errors.New("transaction not found")
+	ErrTransactionStatusChanged = errors.New("transaction status was changed concurrently")
+)
+
+const transactionColumns = `id, transaction_number, from_account_id, to_account_id, transaction_type, amount, currency,
+		       exchange_rate, fee, description, reference_number, status, original_transaction_id,
+		       processed_at, created_at, updated_at`
+
+type TransactionRepository struct {
+	db *Database
+}
+
+func NewTransactionRepository(db *Database) *TransactionRepository {
+	return &TransactionRepository{db: db}
+}
+
+func scanTransaction(row rowScanner) (*models.Transaction, error) {
+	txn := &models.Transaction{}
+	err := row.Scan(
+		&txn.ID,
+		&txn.TransactionNumber,
+		&txn.FromAccountID,
+		&txn.ToAccountID,
+		&txn.TransactionType,
+		&txn.Amount,
+		&txn.Currency,
+		&txn.ExchangeRate,
+		&txn.Fee,
+		&txn.Description,
+		&txn.ReferenceNumber,
+		&txn.Status,
+		&txn.OriginalTransactionID,
+		&txn.ProcessedAt,
+		&txn.CreatedAt,
+		&txn.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// CreateTx inserts a transaction inside an existing database transaction.
+// The transaction number is generated by the database.
+func (r *TransactionRepository) CreateTx(tx *sql.Tx, txn *models.Transaction) error {
+	query := `
+		INSERT INTO transactions (id, transaction_number, from_account_id, to_account_id, transaction_type, amount,
+		                          currency, exchange_rate, fee, description, reference_number, status,
+		                          original_transaction_id, processed_at)
+		VALUES ($1, generate_transaction_number(), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING transaction_number, created_at, updated_at`
+
+	err := tx.QueryRow(
+		query,
+		txn.ID,
+		txn.FromAccountID,
+		txn.ToAccountID,
+		txn.TransactionType,
+		txn.Amount,
+		txn.Currency,
+		txn.ExchangeRate,
+		txn.Fee,
+		txn.Description,
+		txn.ReferenceNumber,
+		txn.Status,
+		txn.OriginalTransactionID,
+		txn.ProcessedAt,
+	).Scan(&txn.TransactionNumber, &txn.CreatedAt, &txn.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TransactionRepository) GetByID(id uuid.UUID) (*models.Transaction, error) {
+	query := fmt.Sprintf(`SELECT %s FROM transactions WHERE id = $1`, transactionColumns)
+
+	txn, err := scanTransaction(r.db.DB.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	return txn, nil
+}
+
+// List returns the transactions matching the filter, newest first. When after
+// is set, only rows that sort after that keyset position are returned and
+// offset should be zero.
+func (r *TransactionRepository) List(filter *models.TransactionFilter, after *models.TransactionCursor, limit, offset int) ([]*models.Transaction, error) {
+	conditions, args := transactionFilterConditions(filter)
+
+	if after != nil {
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < ($%d, $%d)", len(args)+1, len(args)+2))
+		args = append(args, after.CreatedAt, after.ID)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM transactions
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d`,
+		transactionColumns,
+		whereClause(conditions),
+		len(args)+1,
+		len(args)+2,
+	)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []*models.Transaction{}
+	for rows.Next() {
+		txn, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, txn)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// Count returns the number of transactions matching the filter
+func (r *TransactionRepository) Count(filter *models.TransactionFilter) (int64, error) {
+	conditions, args := transactionFilterConditions(filter)
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM transactions %s`, whereClause(conditions))
+
+	var count int64
+	if err := r.db.DB.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count transactions: %w", err)
+	}
+
+	return count, nil
+}
+
+func transactionFilterConditions(filter *models.TransactionFilter) ([]string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter.AccountID != nil {
+		conditions = append(conditions, fmt.Sprintf("(from_account_id = $%d OR to_account_id = $%d)", argIndex, argIndex))
+		args = append(args, *filter.AccountID)
+		argIndex++
+	}
+
+	if filter.TransactionType != nil {
+		conditions = append(conditions, fmt.Sprintf("transaction_type = $%d", argIndex))
+		args = append(args, *filter.TransactionType)
+		argIndex++
+	}
+
+	if filter.Status != nil {
+		conditions = append(conditions, fmt.Sprintf("status = $%d", argIndex))
+		args = append(args, *filter.Status)
+		argIndex++
+	}
+
+	if filter.StartDate != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", argIndex))
+		args = append(args, *filter.StartDate)
+		argIndex++
+	}
+
+	if filter.EndDate != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", argIndex))
+		args = append(args, *filter.EndDate)
+		argIndex++
+	}
+
+	if filter.MinAmount != nil {
+		conditions = append(conditions, fmt.Sprintf("amount >= $%d", argIndex))
+		args = append(args, *filter.MinAmount)
+		argIndex++
+	}
+
+	if filter.MaxAmount != nil {
+		conditions = append(conditions, fmt.Sprintf("amount <= $%d", argIndex))
+		args = append(args, *filter.MaxAmount)
+		argIndex++
+	}
+
+	if filter.Currency != nil {
+		conditions = append(conditions, fmt.Sprintf("currency = $%d", argIndex))
+		args = append(args, *filter.Currency)
+	}
+
+	return conditions, args
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+// GetByIDsTx loads the given transactions, keyed by ID
+func (r *TransactionRepository) GetByIDsTx(tx *sql.Tx, ids []uuid.UUID) (map[uuid.UUID]*models.Transaction, error) {
+	transactions := map[uuid.UUID]*models.Transaction{}
+	if len(ids) == 0 {
+		return transactions, nil
+	}
+
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM transactions WHERE id = ANY($1::uuid[])`, transactionColumns)
+
+	rows, err := tx.Query(query, pq.Array(values))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		txn, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions[txn.ID] = txn
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// LockForUpdateTx loads a transaction and locks its row until the database transaction ends
+func (r *TransactionRepository) LockForUpdateTx(tx *sql.Tx, id uuid.UUID) (*models.Transaction, error) {
+	query := fmt.Sprintf(`SELECT %s FROM transactions WHERE id = $1 FOR UPDATE`, transactionColumns)
+
+	txn, err := scanTransaction(tx.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("failed to lock transaction: %w", err)
+	}
+
+	return txn, nil
+}
+
+// UpdateStatusTx moves a transaction from one status to another. The update
+// only applies while the row is still in the expected status, and
+// processed_at is stamped only on entry into completed.
+func (r *TransactionRepository) UpdateStatusTx(tx *sql.Tx, id uuid.UUID, from, to models.TransactionStatus) (*time.Time, error) {
+	now := time.Now()
+	var processedAt *time.Time
+	if to == models.TransactionStatusCompleted {
+		processedAt = &now
+	}
+
+	query := `
+		UPDATE transactions
+		SET status = $1, processed_at = COALESCE($2, processed_at), updated_at = $3
+		WHERE id = $4 AND status = $5
+		RETURNING processed_at`
+
+	err := tx.QueryRow(query, to, processedAt, now, id, from).Scan(&processedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransactionStatusChanged
+		}
+		return nil, fmt.Errorf("failed to update transaction status: %w", err)
+	}
+
+	return processedAt, nil
+}
+
+// CreateStatusHistoryTx records a transition. clock_timestamp() is used
+// because NOW() is constant within a database transaction and several
+// transitions are usually recorded in the same one.
+func (r *TransactionRepository) CreateStatusHistoryTx(tx *sql.Tx, entry *models.TransactionStatusHistory) error {
+	query := `
+		INSERT INTO transaction_status_history (id, transaction_id, from_status, to_status, actor_id, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, clock_timestamp())
+		RETURNING created_at`
+
+	err := tx.QueryRow(
+		query,
+		entry.ID,
+		entry.TransactionID,
+		entry.FromStatus,
+		entry.ToStatus,
+		entry.ActorID,
+		entry.Reason,
+	).Scan(&entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record status history: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TransactionRepository) GetStatusHistory(transactionID uuid.UUID) ([]models.TransactionStatusHistory, error) {
+	query := `
+		SELECT id, transaction_id, from_status, to_status, actor_id, reason, created_at
+		FROM transaction_status_history
+		WHERE transaction_id = $1
+		ORDER BY created_at ASC, id ASC`
+
+	rows, err := r.db.DB.Query(query, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.TransactionStatusHistory{}
+	for rows.Next() {
+		var entry models.TransactionStatusHistory
+		err := rows.Scan(
+			&entry.ID,
+			&entry.TransactionID,
+			&entry.FromStatus,
+			&entry.ToStatus,
+			&entry.ActorID,
+			&entry.Reason,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan status history: %w", err)
+		}
+		history = append(history, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get status history: %w", err)
+	}
+
+	return history, nil
+}
+
+// SumRefundsTx totals the completed refunds issued against a transaction
+func (r *TransactionRepository) SumRefundsTx(tx *sql.Tx, originalID uuid.UUID) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE original_transaction_id = $1
+		  AND transaction_type = 'refund'
+		  AND status = 'completed'`
+
+	var total decimal.Decimal
+	if err := tx.QueryRow(query, originalID).Scan(&total); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to sum refunds: %w", err)
+	}
+
+	return total, nil
+}
+
+// ActivitySinceTx counts and totals the transactions an account has initiated
+// since the given time, including those still pending or processing. It
+// implements fraud.Stats.
+func (r *TransactionRepository) ActivitySinceTx(tx *sql.Tx, accountID uuid.UUID, since time.Time) (int, decimal.Decimal, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE (from_account_id = $1 OR (from_account_id IS NULL AND to_account_id = $1))
+		  AND transaction_type IN ('transfer', 'withdrawal', 'deposit')
+		  AND status IN ('pending', 'processing', 'completed')
+		  AND created_at >= $2`
+
+	var count int
+	var total decimal.Decimal
+	if err := tx.QueryRow(query, accountID, since).Scan(&count, &total); err != nil {
+		return 0, decimal.Zero, fmt.Errorf("failed to get account activity: %w", err)
+	}
+
+	return count, total, nil
+}
+
+// SumOutgoingTx totals money leaving an account since the given time. It is
+// used to enforce the account's daily and monthly limits, so transactions
+// still held for fraud review or step-up authentication count as well as
+// completed ones. excludeID, when not uuid.Nil, leaves out a held
+// transaction that is being checked again before it is released.
+func (r *TransactionRepository) SumOutgoingTx(tx *sql.Tx, accountID uuid.UUID, since time.Time, excludeID uuid.UUID) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE from_account_id = $1
+		  AND transaction_type IN ('transfer', 'withdrawal')
+		  AND status IN ('pending', 'processing', 'completed')
+		  AND created_at >= $2
+		  AND id <> $3`
+
+	var total decimal.Decimal
+	if err := tx.QueryRow(query, accountID, since, excludeID).Scan(&total); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to sum outgoing transactions: %w", err)
+	}
+
+	return total, nil
+}
+
+// HasPaidAccountTx reports whether any of the user's accounts has completed a
+// transfer to the given account, i.e. whether it is a known payee
+func (r *TransactionRepository) HasPaidAccountTx(tx *sql.Tx, userID, toAccountID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM transactions t
+			JOIN accounts a ON a.id = t.from_account_id
+			WHERE a.user_id = $1
+			  AND t.to_account_id = $2
+			  AND t.transaction_type = 'transfer'
+			  AND t.status = 'completed'
+		)`
+
+	var paid bool
+	if err := tx.QueryRow(query, userID, toAccountID).Scan(&paid); err != nil {
+		return false, fmt.Errorf("failed to check payee history: %w", err)
+	}
+
+	return paid, nil
+}
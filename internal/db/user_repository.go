@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -12,6 +13,9 @@ import (
 	"github.com/lib/pq"
 )
 
+// ErrUserNotFound is returned when no active user has the given ID or email
+var ErrUserNotFound = errors.New("user not found")
+
 type UserRepository struct {
 	db *Database
 }
@@ -22,8 +26,8 @@ func NewUserRepository(db *Database) *UserRepository {
 
 func (r *UserRepository) Create(user *models.User) error {
 	query := `
-		INSERT INTO users (id, email, password_hash, first_name, last_name, phone, date_of_birth, address, is_active, is_verified)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO users (id, email, password_hash, first_name, last_name, phone, date_of_birth, address, role, is_active, is_verified)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING created_at, updated_at`
 
 	err := r.db.DB.QueryRow(
@@ -36,6 +40,7 @@ func (r *UserRepository) Create(user *models.User) error {
 		user.Phone,
 		user.DateOfBirth,
 		user.Address,
+		user.Role,
 		user.IsActive,
 		user.IsVerified,
 	).Scan(&user.CreatedAt, &user.UpdatedAt)
@@ -56,7 +61,7 @@ func (r *UserRepository) GetByID(id uuid.UUID) (*models.User, error) {
 	user := &models.User{}
 	query := `
 		SELECT id, email, password_hash, first_name, last_name, phone, date_of_birth, address, 
-		       is_active, is_verified, created_at, updated_at
+		       role, is_active, is_verified, created_at, updated_at
 		FROM users 
 		WHERE id = $1 AND is_active = true`
 
@@ -69,6 +74,7 @@ func (r *UserRepository) GetByID(id uuid.UUID) (*models.User, error) {
 		&user.Phone,
 		&user.DateOfBirth,
 		&user.Address,
+		&user.Role,
 		&user.IsActive,
 		&user.IsVerified,
 		&user.CreatedAt,
@@ -77,7 +83,7 @@ func (r *UserRepository) GetByID(id uuid.UUID) (*models.User, error) {
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -89,7 +95,7 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	user := &models.User{}
 	query := `
 		SELECT id, email, password_hash, first_name, last_name, phone, date_of_birth, address, 
-		       is_active, is_verified, created_at, updated_at
+		       role, is_active, is_verified, created_at, updated_at
 		FROM users 
 		WHERE email = $1 AND is_active = true`
 
@@ -102,6 +108,7 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 		&user.Phone,
 		&user.DateOfBirth,
 		&user.Address,
+		&user.Role,
 		&user.IsActive,
 		&user.IsVerified,
 		&user.CreatedAt,
@@ -110,7 +117,7 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -166,7 +173,7 @@ func (r *UserRepository) Update(id uuid.UUID, req *models.UpdateUserRequest) (*m
 		SET %s
 		WHERE id = $%d AND is_active = true
 		RETURNING id, email, password_hash, first_name, last_name, phone, date_of_birth, address, 
-		          is_active, is_verified, created_at, updated_at`,
+		          role, is_active, is_verified, created_at, updated_at`,
 		strings.Join(setParts, ", "),
 		argIndex,
 	)
@@ -183,6 +190,7 @@ func (r *UserRepository) Update(id uuid.UUID, req *models.UpdateUserRequest) (*m
 		&user.Phone,
 		&user.DateOfBirth,
 		&user.Address,
+		&user.Role,
 		&user.IsActive,
 		&user.IsVerified,
 		&user.CreatedAt,
@@ -191,7 +199,7 @@ func (r *UserRepository) Update(id uuid.UUID, req *models.UpdateUserRequest) (*m
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
@@ -213,7 +221,46 @@ func (r *UserRepository) UpdatePassword(id uuid.UUID, passwordHash string) error
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ReplacePasswordHash stores a new hash of the user's current password. It
+// reports false, changing nothing, if the stored hash is no longer
+// currentHash, so that a password changed meanwhile is never rolled back.
+func (r *UserRepository) ReplacePasswordHash(id uuid.UUID, currentHash, newHash string) (bool, error) {
+	query := `UPDATE users SET password_hash = $1 WHERE id = $2 AND password_hash = $3`
+
+	result, err := r.db.DB.Exec(query, newHash, id, currentHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to replace password hash: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *UserRepository) UpdatePasswordTx(tx *sql.Tx, id uuid.UUID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $1, updated_at = $2 WHERE id = $3 AND is_active = true`
+
+	result, err := tx.Exec(query, passwordHash, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
 	}
 
 	return nil
@@ -233,7 +280,7 @@ func (r *UserRepository) Deactivate(id uuid.UUID) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	return nil
@@ -253,8 +300,143 @@ func (r *UserRepository) SetVerified(id uuid.UUID, verified bool) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// MarkVerificationSent records that a verification email is being sent to an
+// unverified user. It reports false, leaving the row alone, if the previous
+// one went out less than interval ago or the user is verified or inactive.
+func (r *UserRepository) MarkVerificationSent(id uuid.UUID, interval time.Duration) (bool, error) {
+	now := time.Now()
+	query := `
+		UPDATE users SET verification_sent_at = $1
+		WHERE id = $2 AND is_active = true AND is_verified = false
+		  AND (verification_sent_at IS NULL OR verification_sent_at <= $3)`
+
+	result, err := r.db.DB.Exec(query, now, id, now.Add(-interval))
+	if err != nil {
+		return false, fmt.Errorf("failed to record verification email: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *UserRepository) UpdateRole(id uuid.UUID, role models.UserRole) error {
+	query := `UPDATE users SET role = $1, updated_at = $2 WHERE id = $3 AND is_active = true`
+
+	result, err := r.db.DB.Exec(query, role, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+const loginLockStateColumns = `id, failed_login_attempts, failure_window_started_at, last_failed_login_at, lockout_count, locked_until`
+
+func scanLoginLockState(row rowScanner) (*models.LoginLockState, error) {
+	state := &models.LoginLockState{}
+	err := row.Scan(
+		&state.UserID,
+		&state.FailedAttempts,
+		&state.FailureWindowStartedAt,
+		&state.LastFailedAt,
+		&state.LockoutCount,
+		&state.LockedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (r *UserRepository) GetLoginState(id uuid.UUID) (*models.LoginLockState, error) {
+	query := fmt.Sprintf(`SELECT %s FROM users WHERE id = $1`, loginLockStateColumns)
+
+	state, err := scanLoginLockState(r.db.DB.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get login state: %w", err)
+	}
+
+	return state, nil
+}
+
+// LockLoginStateTx loads a user's failed login record and locks the row until
+// the transaction ends, so that concurrent failures are counted one at a time
+func (r *UserRepository) LockLoginStateTx(tx *sql.Tx, id uuid.UUID) (*models.LoginLockState, error) {
+	query := fmt.Sprintf(`SELECT %s FROM users WHERE id = $1 FOR UPDATE`, loginLockStateColumns)
+
+	state, err := scanLoginLockState(tx.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to lock login state: %w", err)
+	}
+
+	return state, nil
+}
+
+func (r *UserRepository) UpdateLoginStateTx(tx *sql.Tx, state *models.LoginLockState) error {
+	query := `
+		UPDATE users
+		SET failed_login_attempts = $1, failure_window_started_at = $2, last_failed_login_at = $3,
+		    lockout_count = $4, locked_until = $5
+		WHERE id = $6`
+
+	_, err := tx.Exec(
+		query,
+		state.FailedAttempts,
+		state.FailureWindowStartedAt,
+		state.LastFailedAt,
+		state.LockoutCount,
+		state.LockedUntil,
+		state.UserID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update login state: %w", err)
 	}
 
 	return nil
 }
+
+// ClearLoginFailures forgets a user's failed logins and lockouts. It reports
+// whether there was anything to clear, and writes nothing otherwise.
+func (r *UserRepository) ClearLoginFailures(id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE users
+		SET failed_login_attempts = 0, failure_window_started_at = NULL, lockout_count = 0, locked_until = NULL
+		WHERE id = $1 AND (failed_login_attempts > 0 OR lockout_count > 0 OR locked_until IS NOT NULL)`
+
+	result, err := r.db.DB.Exec(query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear login failures: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
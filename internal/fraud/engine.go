@@ -0,0 +1,229 @@
+package fraud
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"financial-transaction-system/internal/config"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type Decision string
+
+const (
+	DecisionAllow  Decision = "allow"
+	DecisionReview Decision = "review"
+	DecisionBlock  Decision = "block"
+)
+
+const (
+	RuleTransactionAmount = "transaction_amount"
+	RuleDailyAmount       = "daily_amount"
+	RuleVelocity          = "velocity"
+)
+
+// Risk scores at or above these thresholds hold a transaction for review or block it
+const (
+	ReviewScore = 50
+	BlockScore  = 90
+)
+
+// Stats supplies the recent activity of an account. Implementations must
+// count transactions that are pending or processing as well as completed
+// ones, so that held transactions cannot be used to slip past the rules.
+type Stats interface {
+	ActivitySinceTx(tx *sql.Tx, accountID uuid.UUID, since time.Time) (count int, total decimal.Decimal, err error)
+}
+
+// Rules are the thresholds the engine applies. A zero value disables a rule.
+type Rules struct {
+	MaxTransactionAmount decimal.Decimal
+	MaxDailyAmount       decimal.Decimal
+	VelocityThreshold    int
+	VelocityWindow       time.Duration
+}
+
+func RulesFromConfig(cfg config.FraudConfig) Rules {
+	return Rules{
+		MaxTransactionAmount: decimal.NewFromFloat(cfg.MaxTransactionAmount),
+		MaxDailyAmount:       decimal.NewFromFloat(cfg.MaxDailyAmount),
+		VelocityThreshold:    cfg.VelocityThreshold,
+		VelocityWindow:       time.Duration(cfg.VelocityWindowMinutes) * time.Minute,
+	}
+}
+
+// Finding is one rule that a transaction tripped
+type Finding struct {
+	RuleName    string
+	Severity    models.FraudSeverity
+	RiskScore   int
+	Description string
+	Details     map[string]interface{}
+}
+
+// Evaluation is the outcome of running every rule against a transaction
+type Evaluation struct {
+	Decision  Decision
+	RiskScore int
+	Findings  []Finding
+}
+
+type Engine struct {
+	rules Rules
+	stats Stats
+}
+
+func NewEngine(rules Rules, stats Stats) *Engine {
+	return &Engine{
+		rules: rules,
+		stats: stats,
+	}
+}
+
+// EvaluateTx scores a transaction before it is stored. accountID is the
+// account whose activity the rules look at, normally the one being debited.
+// The account row should be locked so concurrent transactions are counted.
+func (e *Engine) EvaluateTx(tx *sql.Tx, txn *models.Transaction, accountID uuid.UUID) (*Evaluation, error) {
+	findings := []Finding{}
+
+	if finding := e.checkTransactionAmount(txn); finding != nil {
+		findings = append(findings, *finding)
+	}
+
+	now := time.Now().UTC()
+
+	if e.rules.MaxDailyAmount.IsPositive() {
+		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		_, total, err := e.stats.ActivitySinceTx(tx, accountID, startOfDay)
+		if err != nil {
+			return nil, err
+		}
+		if finding := e.checkDailyAmount(txn, total); finding != nil {
+			findings = append(findings, *finding)
+		}
+	}
+
+	if e.rules.VelocityThreshold > 0 && e.rules.VelocityWindow > 0 {
+		count, _, err := e.stats.ActivitySinceTx(tx, accountID, now.Add(-e.rules.VelocityWindow))
+		if err != nil {
+			return nil, err
+		}
+		if finding := e.checkVelocity(count); finding != nil {
+			findings = append(findings, *finding)
+		}
+	}
+
+	evaluation := &Evaluation{Decision: DecisionAllow, Findings: findings}
+	for _, finding := range findings {
+		if finding.RiskScore > evaluation.RiskScore {
+			evaluation.RiskScore = finding.RiskScore
+		}
+	}
+
+	switch {
+	case evaluation.RiskScore >= BlockScore:
+		evaluation.Decision = DecisionBlock
+	case evaluation.RiskScore >= ReviewScore:
+		evaluation.Decision = DecisionReview
+	}
+
+	return evaluation, nil
+}
+
+// checkTransactionAmount flags a single transaction above the limit, and
+// scores it as critical once it reaches twice the limit
+func (e *Engine) checkTransactionAmount(txn *models.Transaction) *Finding {
+	limit := e.rules.MaxTransactionAmount
+	if !limit.IsPositive() || !txn.Amount.GreaterThan(limit) {
+		return nil
+	}
+
+	score := 70
+	if txn.Amount.GreaterThanOrEqual(limit.Mul(decimal.NewFromInt(2))) {
+		score = 95
+	}
+
+	return &Finding{
+		RuleName:    RuleTransactionAmount,
+		Severity:    severityForScore(score),
+		RiskScore:   score,
+		Description: fmt.Sprintf("Transaction amount %s %s exceeds the limit of %s", txn.Amount.StringFixed(2), txn.Currency, limit.StringFixed(2)),
+		Details: map[string]interface{}{
+			"amount":   txn.Amount,
+			"currency": txn.Currency,
+			"limit":    limit,
+		},
+	}
+}
+
+// checkDailyAmount flags an account whose activity today, including this
+// transaction, goes over the daily limit
+func (e *Engine) checkDailyAmount(txn *models.Transaction, today decimal.Decimal) *Finding {
+	limit := e.rules.MaxDailyAmount
+	total := today.Add(txn.Amount)
+	if !total.GreaterThan(limit) {
+		return nil
+	}
+
+	score := 75
+	if total.GreaterThanOrEqual(limit.Mul(decimal.NewFromInt(2))) {
+		score = 90
+	}
+
+	return &Finding{
+		RuleName:    RuleDailyAmount,
+		Severity:    severityForScore(score),
+		RiskScore:   score,
+		Description: fmt.Sprintf("Daily total %s %s exceeds the limit of %s", total.StringFixed(2), txn.Currency, limit.StringFixed(2)),
+		Details: map[string]interface{}{
+			"amount":      txn.Amount,
+			"daily_total": total,
+			"currency":    txn.Currency,
+			"limit":       limit,
+		},
+	}
+}
+
+// checkVelocity flags an account making more transactions inside the window
+// than the threshold allows, counting this one
+func (e *Engine) checkVelocity(recent int) *Finding {
+	count := recent + 1
+	if count <= e.rules.VelocityThreshold {
+		return nil
+	}
+
+	score := 60
+	if count > 2*e.rules.VelocityThreshold {
+		score = 90
+	}
+
+	windowMinutes := int(e.rules.VelocityWindow / time.Minute)
+	return &Finding{
+		RuleName:    RuleVelocity,
+		Severity:    severityForScore(score),
+		RiskScore:   score,
+		Description: fmt.Sprintf("%d transactions within %d minutes exceeds the threshold of %d", count, windowMinutes, e.rules.VelocityThreshold),
+		Details: map[string]interface{}{
+			"count":          count,
+			"threshold":      e.rules.VelocityThreshold,
+			"window_minutes": windowMinutes,
+		},
+	}
+}
+
+func severityForScore(score int) models.FraudSeverity {
+	switch {
+	case score >= BlockScore:
+		return models.FraudSeverityCritical
+	case score >= 70:
+		return models.FraudSeverityHigh
+	case score >= 40:
+		return models.FraudSeverityMedium
+	default:
+		return models.FraudSeverityLow
+	}
+}
@@ -0,0 +1,134 @@
+package fraud
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// fakeStats reports the same recent activity for every window
+type fakeStats struct {
+	count int
+	total decimal.Decimal
+	err   error
+	calls int
+}
+
+func (f *fakeStats) ActivitySinceTx(tx *sql.Tx, accountID uuid.UUID, since time.Time) (int, decimal.Decimal, error) {
+	f.calls++
+	return f.count, f.total, f.err
+}
+
+var testRules = Rules{
+	MaxTransactionAmount: decimal.NewFromInt(1000),
+	MaxDailyAmount:       decimal.NewFromInt(5000),
+	VelocityThreshold:    5,
+	VelocityWindow:       10 * time.Minute,
+}
+
+func testTransaction(amount string) *models.Transaction {
+	return &models.Transaction{
+		ID:              uuid.New(),
+		TransactionType: models.TransactionTypeTransfer,
+		Amount:          decimal.RequireFromString(amount),
+		Currency:        "USD",
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		count    int
+		today    string
+		decision Decision
+		score    int
+		rules    []string
+	}{
+		{"quiet account", "100", 0, "0", DecisionAllow, 0, nil},
+		{"amount at the limit", "1000", 0, "0", DecisionAllow, 0, nil},
+		{"amount over the limit", "1000.01", 0, "0", DecisionReview, 70, []string{RuleTransactionAmount}},
+		{"amount twice the limit", "2000", 0, "0", DecisionBlock, 95, []string{RuleTransactionAmount}},
+		{"daily total at the limit", "100", 0, "4900", DecisionAllow, 0, nil},
+		{"daily total over the limit", "200", 0, "4900", DecisionReview, 75, []string{RuleDailyAmount}},
+		{"daily total twice the limit", "500", 0, "9500", DecisionBlock, 90, []string{RuleDailyAmount}},
+		{"velocity at the threshold", "100", 4, "0", DecisionAllow, 0, nil},
+		{"velocity over the threshold", "100", 5, "0", DecisionReview, 60, []string{RuleVelocity}},
+		{"velocity over twice the threshold", "100", 10, "0", DecisionBlock, 90, []string{RuleVelocity}},
+		{"highest score wins", "1500", 6, "0", DecisionReview, 70, []string{RuleTransactionAmount, RuleVelocity}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &fakeStats{count: tt.count, total: decimal.RequireFromString(tt.today)}
+			evaluation, err := NewEngine(testRules, stats).EvaluateTx(nil, testTransaction(tt.amount), uuid.New())
+			if err != nil {
+				t.Fatalf("EvaluateTx: %v", err)
+			}
+
+			if evaluation.Decision != tt.decision || evaluation.RiskScore != tt.score {
+				t.Errorf("got %s with score %d, want %s with score %d",
+					evaluation.Decision, evaluation.RiskScore, tt.decision, tt.score)
+			}
+
+			var rules []string
+			for _, finding := range evaluation.Findings {
+				rules = append(rules, finding.RuleName)
+				if finding.Severity != severityForScore(finding.RiskScore) {
+					t.Errorf("%s: severity %s for score %d", finding.RuleName, finding.Severity, finding.RiskScore)
+				}
+			}
+			if len(rules) != len(tt.rules) {
+				t.Fatalf("findings %v, want %v", rules, tt.rules)
+			}
+			for i := range rules {
+				if rules[i] != tt.rules[i] {
+					t.Errorf("findings %v, want %v", rules, tt.rules)
+				}
+			}
+		})
+	}
+}
+
+func TestDisabledRulesSkipStats(t *testing.T) {
+	stats := &fakeStats{err: errors.New("stats unavailable")}
+	evaluation, err := NewEngine(Rules{}, stats).EvaluateTx(nil, testTransaction("1000000"), uuid.New())
+	if err != nil {
+		t.Fatalf("EvaluateTx: %v", err)
+	}
+	if evaluation.Decision != DecisionAllow || stats.calls != 0 {
+		t.Errorf("got %s after %d stats lookups, want allow after none", evaluation.Decision, stats.calls)
+	}
+}
+
+func TestEvaluateReturnsStatsErrors(t *testing.T) {
+	failed := errors.New("stats unavailable")
+	_, err := NewEngine(testRules, &fakeStats{err: failed}).EvaluateTx(nil, testTransaction("100"), uuid.New())
+	if !errors.Is(err, failed) {
+		t.Errorf("got %v, want the stats error", err)
+	}
+}
+
+func TestSeverityForScore(t *testing.T) {
+	tests := map[int]models.FraudSeverity{
+		0:   models.FraudSeverityLow,
+		39:  models.FraudSeverityLow,
+		40:  models.FraudSeverityMedium,
+		69:  models.FraudSeverityMedium,
+		70:  models.FraudSeverityHigh,
+		89:  models.FraudSeverityHigh,
+		90:  models.FraudSeverityCritical,
+		100: models.FraudSeverityCritical,
+	}
+	for score, want := range tests {
+		if got := severityForScore(score); got != want {
+			t.Errorf("severityForScore(%d) = %s, want %s", score, got, want)
+		}
+	}
+}
@@ -57,6 +57,12 @@ type UpdateAccountRequest struct {
 	IsPrimary    *bool            `json:"is_primary,omitempty"`
 }
 
+// UpdateAccountStatusRequest is used by staff to suspend or reactivate an account
+type UpdateAccountStatusRequest struct {
+	Status AccountStatus `json:"status" validate:"required,oneof=active suspended"`
+	Reason string        `json:"reason" validate:"required"`
+}
+
 type AccountBalance struct {
 	AccountID        uuid.UUID       `json:"account_id"`
 	AccountNumber    string          `json:"account_number"`
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// What an API key may be used for
+const (
+	APIKeyScopeReadBalances      = "read_balances"
+	APIKeyScopeReadTransactions  = "read_transactions"
+	APIKeyScopeInitiateTransfers = "initiate_transfers"
+)
+
+// APIKey lets a business account holder call the API without a login. The
+// key itself is shown once on creation; only its SHA-256 hash is stored, and
+// Prefix, its public start, identifies it. An empty AllowedIPs allows any
+// address.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	KeyHash    string     `json:"-" db:"key_hash"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips" db:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	LastUsedIP *string    `json:"last_used_ip,omitempty" db:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=read_balances read_transactions initiate_transfers"`
+	AllowedIPs    []string `json:"allowed_ips,omitempty"`
+	ExpiresInDays *int     `json:"expires_in_days,omitempty" validate:"omitempty,min=1,max=365"`
+}
+
+// CreateAPIKeyResponse carries the new key, which cannot be retrieved again
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -24,6 +24,8 @@ const (
 	AuditActionTransactionProcessed AuditAction = "transaction_processed"
 	AuditActionTransactionFailed    AuditAction = "transaction_failed"
 	AuditActionTransactionCancelled AuditAction = "transaction_cancelled"
+	AuditActionTransactionReversed  AuditAction = "transaction_reversed"
+	AuditActionTransactionRefunded  AuditAction = "transaction_refunded"
 	AuditActionBalanceUpdated       AuditAction = "balance_updated"
 	AuditActionFraudDetected        AuditAction = "fraud_detected"
 	AuditActionFraudCleared         AuditAction = "fraud_cleared"
@@ -31,10 +33,26 @@ const (
 	AuditActionPasswordChanged      AuditAction = "password_changed"
 	AuditActionEmailChanged         AuditAction = "email_changed"
 	AuditActionProfileUpdated       AuditAction = "profile_updated"
+	AuditActionRefreshTokenReused   AuditAction = "refresh_token_reused"
+	AuditActionUserRoleChanged      AuditAction = "user_role_changed"
+	AuditActionMFAEnabled           AuditAction = "mfa_enabled"
+	AuditActionMFADisabled          AuditAction = "mfa_disabled"
+	AuditActionMFAChallengeFailed   AuditAction = "mfa_challenge_failed"
+	AuditActionStepUpRequired       AuditAction = "step_up_required"
+	AuditActionStepUpConfirmed      AuditAction = "step_up_confirmed"
+	AuditActionStepUpFailed         AuditAction = "step_up_failed"
+	AuditActionLoginFailed          AuditAction = "login_failed"
+	AuditActionUserLocked           AuditAction = "user_locked"
+	AuditActionUserUnlocked         AuditAction = "user_unlocked"
+	AuditActionAPIKeyCreated        AuditAction = "api_key_created"
+	AuditActionAPIKeyRevoked        AuditAction = "api_key_revoked"
+	AuditActionFraudAlertAssigned   AuditAction = "fraud_alert_assigned"
+	AuditActionFraudAlertUpdated    AuditAction = "fraud_alert_updated"
 )
 
 type AuditLog struct {
 	ID            uuid.UUID       `json:"id" db:"id"`
+	Seq           int64           `json:"seq" db:"seq"`
 	UserID        *uuid.UUID      `json:"user_id,omitempty" db:"user_id"`
 	AccountID     *uuid.UUID      `json:"account_id,omitempty" db:"account_id"`
 	TransactionID *uuid.UUID      `json:"transaction_id,omitempty" db:"transaction_id"`
@@ -49,6 +67,35 @@ type AuditLog struct {
 	Description   *string         `json:"description,omitempty" db:"description"`
 	Metadata      json.RawMessage `json:"metadata,omitempty" db:"metadata"`
 	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	PrevHash      *string         `json:"prev_hash,omitempty" db:"prev_hash"`
+	Hash          *string         `json:"hash,omitempty" db:"hash"`
+}
+
+type AuditLogList struct {
+	AuditLogs  []AuditLog         `json:"audit_logs"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+// AuditCheckpoint is a signed snapshot of the audit chain head
+type AuditCheckpoint struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Seq       int64     `json:"seq" db:"seq"`
+	HeadHash  string    `json:"head_hash" db:"head_hash"`
+	KeyID     string    `json:"key_id" db:"key_id"`
+	Signature string    `json:"signature" db:"signature"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// AuditChainReport is the outcome of walking the audit chain. Problem is empty
+// when the chain verified; BrokenAt is the first entry that failed, if any.
+type AuditChainReport struct {
+	Entries             int64   `json:"entries"`
+	UnchainedEntries    int64   `json:"unchained_entries"`
+	HeadSeq             int64   `json:"head_seq"`
+	HeadHash            *string `json:"head_hash,omitempty"`
+	CheckpointsVerified int     `json:"checkpoints_verified"`
+	BrokenAt            *int64  `json:"broken_at,omitempty"`
+	Problem             string  `json:"problem,omitempty"`
 }
 
 type CreateAuditLogRequest struct {
@@ -67,6 +114,13 @@ type CreateAuditLogRequest struct {
 	Metadata      interface{} `json:"metadata,omitempty"`
 }
 
+// RequestMeta identifies the client and session an audited action came from
+type RequestMeta struct {
+	IPAddress net.IP
+	UserAgent string
+	SessionID string
+}
+
 type AuditLogFilter struct {
 	UserID        *uuid.UUID   `json:"user_id,omitempty" query:"user_id"`
 	AccountID     *uuid.UUID   `json:"account_id,omitempty" query:"account_id"`
@@ -112,6 +166,8 @@ type FraudAlert struct {
 	ResolvedBy      *uuid.UUID      `json:"resolved_by,omitempty" db:"resolved_by"`
 	ResolvedAt      *time.Time      `json:"resolved_at,omitempty" db:"resolved_at"`
 	ResolutionNotes *string         `json:"resolution_notes,omitempty" db:"resolution_notes"`
+	AssignedTo      *uuid.UUID      `json:"assigned_to,omitempty" db:"assigned_to"`
+	AssignedAt      *time.Time      `json:"assigned_at,omitempty" db:"assigned_at"`
 	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
 }
@@ -133,6 +189,10 @@ type UpdateFraudAlertRequest struct {
 	ResolutionNotes *string      `json:"resolution_notes,omitempty"`
 }
 
+type AssignFraudAlertRequest struct {
+	AssigneeID *uuid.UUID `json:"assignee_id,omitempty"`
+}
+
 type FraudAlertFilter struct {
 	UserID        *uuid.UUID     `json:"user_id,omitempty" query:"user_id"`
 	AccountID     *uuid.UUID     `json:"account_id,omitempty" query:"account_id"`
@@ -142,6 +202,7 @@ type FraudAlertFilter struct {
 	Status        *FraudStatus   `json:"status,omitempty" query:"status"`
 	MinRiskScore  *int           `json:"min_risk_score,omitempty" query:"min_risk_score"`
 	MaxRiskScore  *int           `json:"max_risk_score,omitempty" query:"max_risk_score"`
+	AssignedTo    *uuid.UUID     `json:"assigned_to,omitempty" query:"assigned_to"`
 	StartDate     *time.Time     `json:"start_date,omitempty" query:"start_date"`
 	EndDate       *time.Time     `json:"end_date,omitempty" query:"end_date"`
 	PaginationRequest
@@ -161,9 +222,16 @@ type FraudAlertResponse struct {
 	ResolvedBy      *uuid.UUID           `json:"resolved_by,omitempty"`
 	ResolvedAt      *time.Time           `json:"resolved_at,omitempty"`
 	ResolutionNotes *string              `json:"resolution_notes,omitempty"`
+	AssignedTo      *uuid.UUID           `json:"assigned_to,omitempty"`
+	AssignedAt      *time.Time           `json:"assigned_at,omitempty"`
 	CreatedAt       time.Time            `json:"created_at"`
 	UpdatedAt       time.Time            `json:"updated_at"`
 	User            *UserProfile         `json:"user,omitempty"`
 	Account         *AccountSummary      `json:"account,omitempty"`
 	Transaction     *TransactionResponse `json:"transaction,omitempty"`
 }
+
+type FraudAlertList struct {
+	Alerts     []FraudAlertResponse `json:"alerts"`
+	Pagination PaginationResponse   `json:"pagination"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey records the first response to a request sent with an
+// Idempotency-Key header. ResponseStatus is nil while the request is in flight.
+type IdempotencyKey struct {
+	UserID             uuid.UUID `json:"user_id" db:"user_id"`
+	Key                string    `json:"idempotency_key" db:"idempotency_key"`
+	RequestMethod      string    `json:"request_method" db:"request_method"`
+	RequestPath        string    `json:"request_path" db:"request_path"`
+	RequestFingerprint string    `json:"request_fingerprint" db:"request_fingerprint"`
+	ResponseStatus     *int      `json:"response_status,omitempty" db:"response_status"`
+	ResponseBody       []byte    `json:"-" db:"response_body"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt          time.Time `json:"expires_at" db:"expires_at"`
+}
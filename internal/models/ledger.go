@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type PostingDirection string
+type JournalEntryType string
+type SystemAccountCode string
+
+const (
+	PostingDirectionDebit  PostingDirection = "debit"
+	PostingDirectionCredit PostingDirection = "credit"
+)
+
+const (
+	JournalEntryTypeTransaction    JournalEntryType = "transaction"
+	JournalEntryTypeOpeningBalance JournalEntryType = "opening_balance"
+	JournalEntryTypeReversal       JournalEntryType = "reversal"
+)
+
+// System accounts hold the bank's side of postings that have no customer counterparty
+const (
+	SystemAccountExternalCash    SystemAccountCode = "external_cash"
+	SystemAccountFeeIncome       SystemAccountCode = "fee_income"
+	SystemAccountInterestExpense SystemAccountCode = "interest_expense"
+)
+
+type JournalEntry struct {
+	ID            uuid.UUID        `json:"id" db:"id"`
+	TransactionID *uuid.UUID       `json:"transaction_id,omitempty" db:"transaction_id"`
+	EntryType     JournalEntryType `json:"entry_type" db:"entry_type"`
+	Description   *string          `json:"description,omitempty" db:"description"`
+	CreatedAt     time.Time        `json:"created_at" db:"created_at"`
+	Postings      []Posting        `json:"postings"`
+}
+
+// Posting is a single debit or credit leg. Exactly one of AccountID and
+// SystemAccount identifies the account it is posted to.
+type Posting struct {
+	ID             uuid.UUID         `json:"id" db:"id"`
+	JournalEntryID uuid.UUID         `json:"journal_entry_id" db:"journal_entry_id"`
+	AccountID      *uuid.UUID        `json:"account_id,omitempty" db:"account_id"`
+	SystemAccount  SystemAccountCode `json:"system_account,omitempty" db:"-"`
+	Direction      PostingDirection  `json:"direction" db:"direction"`
+	Amount         decimal.Decimal   `json:"amount" db:"amount"`
+	Currency       string            `json:"currency" db:"currency"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+}
+
+// BalanceMismatch reports an account whose cached balance disagrees with its postings
+type BalanceMismatch struct {
+	AccountID     uuid.UUID       `json:"account_id"`
+	AccountNumber string          `json:"account_number"`
+	Currency      string          `json:"currency"`
+	CachedBalance decimal.Decimal `json:"cached_balance"`
+	LedgerBalance decimal.Decimal `json:"ledger_balance"`
+}
+
+// AccountMovement is the effect of one journal entry on a customer account
+type AccountMovement struct {
+	JournalEntryID uuid.UUID        `json:"journal_entry_id"`
+	EntryType      JournalEntryType `json:"entry_type"`
+	TransactionID  *uuid.UUID       `json:"transaction_id,omitempty"`
+	Credits        decimal.Decimal  `json:"credits"`
+	Debits         decimal.Decimal  `json:"debits"`
+	PostedAt       time.Time        `json:"posted_at"`
+}
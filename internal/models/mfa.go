@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserMFA holds a user's TOTP enrollment. SecretCiphertext is the encrypted
+// shared secret; EnabledAt is nil until enrollment is confirmed with a code.
+// LastUsedStep is the time step of the last accepted code, which cannot be
+// used again.
+type UserMFA struct {
+	UserID           uuid.UUID  `json:"user_id" db:"user_id"`
+	SecretCiphertext []byte     `json:"-" db:"secret_ciphertext"`
+	EnabledAt        *time.Time `json:"enabled_at,omitempty" db:"enabled_at"`
+	LastUsedStep     *int64     `json:"-" db:"last_used_step"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// MFAEnrollmentResponse carries the secret to add to an authenticator app,
+// both raw and as an otpauth:// URI for a QR code
+type MFAEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type ConfirmMFARequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// MFARecoveryCodesResponse is the only time recovery codes are shown
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type DisableMFARequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// MFAChallengeResponse is returned by login instead of tokens when the user
+// has two-factor authentication enabled
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresAt   int64  `json:"expires_at"`
+}
+
+// VerifyMFARequest exchanges an MFA token for real tokens; Code is either a
+// TOTP code or an unused recovery code
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
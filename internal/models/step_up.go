@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type StepUpStatus string
+
+const (
+	StepUpStatusPending   StepUpStatus = "pending"
+	StepUpStatusConfirmed StepUpStatus = "confirmed"
+	StepUpStatusFailed    StepUpStatus = "failed"
+	StepUpStatusExpired   StepUpStatus = "expired"
+)
+
+// Reasons a transfer needs step-up authentication
+const (
+	StepUpReasonAmount      = "amount_threshold"
+	StepUpReasonNewPayee    = "new_payee"
+	StepUpReasonFraudReview = "fraud_review"
+)
+
+// StepUpChallenge holds a transfer as pending until the user proves their
+// password or a TOTP code again
+type StepUpChallenge struct {
+	ID            uuid.UUID    `json:"id" db:"id"`
+	UserID        uuid.UUID    `json:"user_id" db:"user_id"`
+	TransactionID uuid.UUID    `json:"transaction_id" db:"transaction_id"`
+	Reasons       []string     `json:"reasons" db:"reasons"`
+	Status        StepUpStatus `json:"status" db:"status"`
+	Attempts      int          `json:"attempts" db:"attempts"`
+	ExpiresAt     time.Time    `json:"expires_at" db:"expires_at"`
+	CompletedAt   *time.Time   `json:"completed_at,omitempty" db:"completed_at"`
+	CreatedAt     time.Time    `json:"created_at" db:"created_at"`
+}
+
+// ConfirmStepUpRequest carries the proof: a TOTP (or recovery) code for users
+// with two-factor authentication, otherwise the password
+type ConfirmStepUpRequest struct {
+	Password string `json:"password,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken tracks one issued refresh token by its jti. FamilyID is the
+// login session the token descends from; UsedAt is set once it is rotated.
+type RefreshToken struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	UserID        uuid.UUID  `json:"user_id" db:"user_id"`
+	FamilyID      uuid.UUID  `json:"family_id" db:"family_id"`
+	ReplacedBy    *uuid.UUID `json:"replaced_by,omitempty" db:"replaced_by"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt        *time.Time `json:"used_at,omitempty" db:"used_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedReason *string    `json:"revoked_reason,omitempty" db:"revoked_reason"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// PasswordResetToken is an emailed reset token, stored only as its SHA-256 hash
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
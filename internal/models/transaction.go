@@ -29,21 +29,34 @@ const (
 )
 
 type Transaction struct {
-	ID                uuid.UUID         `json:"id" db:"id"`
-	TransactionNumber string            `json:"transaction_number" db:"transaction_number"`
-	FromAccountID     *uuid.UUID        `json:"from_account_id,omitempty" db:"from_account_id"`
-	ToAccountID       *uuid.UUID        `json:"to_account_id,omitempty" db:"to_account_id"`
-	TransactionType   TransactionType   `json:"transaction_type" db:"transaction_type"`
-	Amount            decimal.Decimal   `json:"amount" db:"amount"`
-	Currency          string            `json:"currency" db:"currency"`
-	ExchangeRate      decimal.Decimal   `json:"exchange_rate" db:"exchange_rate"`
-	Fee               decimal.Decimal   `json:"fee" db:"fee"`
-	Description       *string           `json:"description,omitempty" db:"description"`
-	ReferenceNumber   *string           `json:"reference_number,omitempty" db:"reference_number"`
-	Status            TransactionStatus `json:"status" db:"status"`
-	ProcessedAt       *time.Time        `json:"processed_at,omitempty" db:"processed_at"`
-	CreatedAt         time.Time         `json:"created_at" db:"created_at"`
-	UpdatedAt         time.Time         `json:"updated_at" db:"updated_at"`
+	ID                    uuid.UUID         `json:"id" db:"id"`
+	TransactionNumber     string            `json:"transaction_number" db:"transaction_number"`
+	FromAccountID         *uuid.UUID        `json:"from_account_id,omitempty" db:"from_account_id"`
+	ToAccountID           *uuid.UUID        `json:"to_account_id,omitempty" db:"to_account_id"`
+	TransactionType       TransactionType   `json:"transaction_type" db:"transaction_type"`
+	Amount                decimal.Decimal   `json:"amount" db:"amount"`
+	Currency              string            `json:"currency" db:"currency"`
+	ExchangeRate          decimal.Decimal   `json:"exchange_rate" db:"exchange_rate"`
+	Fee                   decimal.Decimal   `json:"fee" db:"fee"`
+	Description           *string           `json:"description,omitempty" db:"description"`
+	ReferenceNumber       *string           `json:"reference_number,omitempty" db:"reference_number"`
+	Status                TransactionStatus `json:"status" db:"status"`
+	OriginalTransactionID *uuid.UUID        `json:"original_transaction_id,omitempty" db:"original_transaction_id"`
+	ProcessedAt           *time.Time        `json:"processed_at,omitempty" db:"processed_at"`
+	CreatedAt             time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// TransactionStatusHistory records one status transition. FromStatus is nil
+// for the entry created together with the transaction.
+type TransactionStatusHistory struct {
+	ID            uuid.UUID          `json:"id" db:"id"`
+	TransactionID uuid.UUID          `json:"transaction_id" db:"transaction_id"`
+	FromStatus    *TransactionStatus `json:"from_status,omitempty" db:"from_status"`
+	ToStatus      TransactionStatus  `json:"to_status" db:"to_status"`
+	ActorID       *uuid.UUID         `json:"actor_id,omitempty" db:"actor_id"`
+	Reason        *string            `json:"reason,omitempty" db:"reason"`
+	CreatedAt     time.Time          `json:"created_at" db:"created_at"`
 }
 
 type TransferRequest struct {
@@ -71,19 +84,32 @@ type WithdrawalRequest struct {
 	ReferenceNumber *string         `json:"reference_number,omitempty" validate:"omitempty,max=50"`
 }
 
+// ReverseTransactionRequest fully reverses a completed transaction
+type ReverseTransactionRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// RefundTransactionRequest refunds part or all of a completed transaction
+type RefundTransactionRequest struct {
+	Amount decimal.Decimal `json:"amount" validate:"required,gt=0"`
+	Reason string          `json:"reason" validate:"required,max=500"`
+}
+
 type TransactionResponse struct {
-	ID                uuid.UUID         `json:"id"`
-	TransactionNumber string            `json:"transaction_number"`
-	TransactionType   TransactionType   `json:"transaction_type"`
-	Amount            decimal.Decimal   `json:"amount"`
-	Currency          string            `json:"currency"`
-	Fee               decimal.Decimal   `json:"fee"`
-	Description       *string           `json:"description,omitempty"`
-	Status            TransactionStatus `json:"status"`
-	ProcessedAt       *time.Time        `json:"processed_at,omitempty"`
-	CreatedAt         time.Time         `json:"created_at"`
-	FromAccount       *AccountSummary   `json:"from_account,omitempty"`
-	ToAccount         *AccountSummary   `json:"to_account,omitempty"`
+	ID                    uuid.UUID         `json:"id"`
+	TransactionNumber     string            `json:"transaction_number"`
+	TransactionType       TransactionType   `json:"transaction_type"`
+	Amount                decimal.Decimal   `json:"amount"`
+	Currency              string            `json:"currency"`
+	Fee                   decimal.Decimal   `json:"fee"`
+	Description           *string           `json:"description,omitempty"`
+	Status                TransactionStatus `json:"status"`
+	OriginalTransactionID *uuid.UUID        `json:"original_transaction_id,omitempty"`
+	ProcessedAt           *time.Time        `json:"processed_at,omitempty"`
+	CreatedAt             time.Time         `json:"created_at"`
+	FromAccount           *AccountSummary   `json:"from_account,omitempty"`
+	ToAccount             *AccountSummary   `json:"to_account,omitempty"`
+	StepUp                *StepUpChallenge  `json:"step_up,omitempty"`
 }
 
 type TransactionHistory struct {
@@ -100,6 +126,7 @@ type TransactionStatement struct {
 	ClosingBalance decimal.Decimal       `json:"closing_balance"`
 	Transactions   []TransactionResponse `json:"transactions"`
 	Summary        TransactionSummary    `json:"summary"`
+	IssuedAt       *time.Time            `json:"issued_at,omitempty"`
 }
 
 type TransactionSummary struct {
@@ -115,12 +142,13 @@ type PaginationRequest struct {
 }
 
 type PaginationResponse struct {
-	Page         int   `json:"page"`
-	PageSize     int   `json:"page_size"`
-	TotalPages   int   `json:"total_pages"`
-	TotalRecords int64 `json:"total_records"`
-	HasNext      bool  `json:"has_next"`
-	HasPrevious  bool  `json:"has_previous"`
+	Page         int    `json:"page"`
+	PageSize     int    `json:"page_size"`
+	TotalPages   int    `json:"total_pages"`
+	TotalRecords int64  `json:"total_records"`
+	HasNext      bool   `json:"has_next"`
+	HasPrevious  bool   `json:"has_previous"`
+	NextCursor   string `json:"next_cursor,omitempty"`
 }
 
 // TransactionFilter for filtering transaction history
@@ -133,5 +161,13 @@ type TransactionFilter struct {
 	MinAmount       *decimal.Decimal   `json:"min_amount,omitempty" query:"min_amount"`
 	MaxAmount       *decimal.Decimal   `json:"max_amount,omitempty" query:"max_amount"`
 	Currency        *string            `json:"currency,omitempty" query:"currency"`
+	Cursor          *string            `json:"cursor,omitempty" query:"cursor"`
 	PaginationRequest
 }
+
+// TransactionCursor is the keyset position of a row in a transaction history,
+// which is ordered by created_at and id, newest first
+type TransactionCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+}
@@ -6,6 +6,17 @@ import (
 	"github.com/google/uuid"
 )
 
+// UserRole decides which staff endpoints a user may call
+type UserRole string
+
+const (
+	UserRoleCustomer          UserRole = "customer"
+	UserRoleTeller            UserRole = "teller"
+	UserRoleFraudAnalyst      UserRole = "fraud_analyst"
+	UserRoleComplianceAuditor UserRole = "compliance_auditor"
+	UserRoleAdmin             UserRole = "admin"
+)
+
 type User struct {
 	ID           uuid.UUID  `json:"id" db:"id"`
 	Email        string     `json:"email" db:"email" validate:"required,email"`
@@ -15,6 +26,7 @@ type User struct {
 	Phone        *string    `json:"phone,omitempty" db:"phone" validate:"omitempty,min=10,max=20"`
 	DateOfBirth  *time.Time `json:"date_of_birth,omitempty" db:"date_of_birth"`
 	Address      *string    `json:"address,omitempty" db:"address"`
+	Role         UserRole   `json:"role" db:"role"`
 	IsActive     bool       `json:"is_active" db:"is_active"`
 	IsVerified   bool       `json:"is_verified" db:"is_verified"`
 	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
@@ -45,23 +57,56 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	User         User   `json:"user"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresAt    int64  `json:"expires_at"`
+	User             User      `json:"user"`
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresAt        int64     `json:"expires_at"`
+	SessionID        string    `json:"-"`
+	RefreshTokenID   uuid.UUID `json:"-"`
+	RefreshExpiresAt time.Time `json:"-"`
 }
 
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+type UpdateUserRoleRequest struct {
+	Role UserRole `json:"role" validate:"required,oneof=customer teller fraud_analyst compliance_auditor admin"`
+}
+
 // UserProfile represents public user information
 type UserProfile struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Phone     *string   `json:"phone,omitempty"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         uuid.UUID `json:"id"`
+	Email      string    `json:"email"`
+	FirstName  string    `json:"first_name"`
+	LastName   string    `json:"last_name"`
+	Phone      *string   `json:"phone,omitempty"`
+	Role       UserRole  `json:"role"`
+	IsActive   bool      `json:"is_active"`
+	IsVerified bool      `json:"is_verified"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
+}
+
+// LoginLockState is a user's record of failed logins and lockouts
+type LoginLockState struct {
+	UserID                 uuid.UUID  `json:"user_id" db:"id"`
+	FailedAttempts         int        `json:"failed_attempts" db:"failed_login_attempts"`
+	FailureWindowStartedAt *time.Time `json:"failure_window_started_at,omitempty" db:"failure_window_started_at"`
+	LastFailedAt           *time.Time `json:"last_failed_at,omitempty" db:"last_failed_login_at"`
+	LockoutCount           int        `json:"lockout_count" db:"lockout_count"`
+	LockedUntil            *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+	Locked                 bool       `json:"locked"`
 }
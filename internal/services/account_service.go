@@ -0,0 +1,311 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAccountNotFound      = errors.New("account not found")
+	ErrAccountStatusChange  = errors.New("account status cannot be changed by the account holder")
+	ErrInvalidAccountLimits = errors.New("daily limit cannot exceed monthly limit")
+	ErrInvalidAccountStatus = errors.New("account status can only be set to active or suspended")
+	ErrAccountClosed        = errors.New("account is closed")
+)
+
+type AccountService struct {
+	accountRepo *db.AccountRepository
+	audit       *AuditService
+}
+
+func NewAccountService(accountRepo *db.AccountRepository, audit *AuditService) *AccountService {
+	return &AccountService{
+		accountRepo: accountRepo,
+		audit:       audit,
+	}
+}
+
+func (s *AccountService) CreateAccount(userID uuid.UUID, req *models.CreateAccountRequest, meta *models.RequestMeta) (*models.Account, error) {
+	if err := validateCreateAccountRequest(req); err != nil {
+		return nil, err
+	}
+
+	// The first account a user opens is always the primary one
+	count, err := s.accountRepo.CountByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &models.Account{
+		ID:           uuid.New(),
+		UserID:       userID,
+		AccountType:  req.AccountType,
+		AccountName:  strings.TrimSpace(req.AccountName),
+		Currency:     strings.ToUpper(req.Currency),
+		DailyLimit:   req.DailyLimit,
+		MonthlyLimit: req.MonthlyLimit,
+		IsPrimary:    req.IsPrimary || count == 0,
+	}
+
+	if err := s.accountRepo.Create(account); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(meta, &models.CreateAuditLogRequest{
+		UserID:     &userID,
+		AccountID:  &account.ID,
+		Action:     models.AuditActionAccountCreated,
+		EntityType: "account",
+		EntityID:   &account.ID,
+		NewValues:  accountAuditValues(account),
+	})
+
+	return account, nil
+}
+
+func (s *AccountService) ListAccounts(userID uuid.UUID) ([]models.AccountSummary, error) {
+	accounts, err := s.accountRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]models.AccountSummary, 0, len(accounts))
+	for _, account := range accounts {
+		summaries = append(summaries, toAccountSummary(account))
+	}
+
+	return summaries, nil
+}
+
+func (s *AccountService) GetAccount(userID, accountID uuid.UUID) (*models.Account, error) {
+	return s.getOwnedAccount(userID, accountID)
+}
+
+func (s *AccountService) GetBalance(userID, accountID uuid.UUID) (*models.AccountBalance, error) {
+	account, err := s.getOwnedAccount(userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AccountBalance{
+		AccountID:        account.ID,
+		AccountNumber:    account.AccountNumber,
+		Balance:          account.Balance,
+		AvailableBalance: account.AvailableBalance,
+		Currency:         account.Currency,
+		LastUpdated:      account.UpdatedAt,
+	}, nil
+}
+
+func (s *AccountService) UpdateAccount(userID, accountID uuid.UUID, req *models.UpdateAccountRequest, meta *models.RequestMeta) (*models.Account, error) {
+	account, err := s.getOwnedAccount(userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Suspending and closing accounts is an operations concern
+	if req.Status != nil {
+		return nil, ErrAccountStatusChange
+	}
+
+	if req.AccountName != nil {
+		name := strings.TrimSpace(*req.AccountName)
+		if err := validateAccountName(name); err != nil {
+			return nil, err
+		}
+		req.AccountName = &name
+	}
+
+	dailyLimit := account.DailyLimit
+	monthlyLimit := account.MonthlyLimit
+	if req.DailyLimit != nil {
+		if !req.DailyLimit.IsPositive() {
+			return nil, fmt.Errorf("daily limit must be greater than zero")
+		}
+		dailyLimit = *req.DailyLimit
+	}
+	if req.MonthlyLimit != nil {
+		if !req.MonthlyLimit.IsPositive() {
+			return nil, fmt.Errorf("monthly limit must be greater than zero")
+		}
+		monthlyLimit = *req.MonthlyLimit
+	}
+	if dailyLimit.GreaterThan(monthlyLimit) {
+		return nil, ErrInvalidAccountLimits
+	}
+
+	updated, err := s.accountRepo.Update(accountID, req)
+	if err != nil {
+		if errors.Is(err, db.ErrAccountNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	oldValues, newValues := changedValues(accountAuditValues(account), accountAuditValues(updated))
+	if len(newValues) > 0 {
+		s.audit.Record(meta, &models.CreateAuditLogRequest{
+			UserID:     &userID,
+			AccountID:  &updated.ID,
+			Action:     models.AuditActionAccountUpdated,
+			EntityType: "account",
+			EntityID:   &updated.ID,
+			OldValues:  oldValues,
+			NewValues:  newValues,
+		})
+	}
+
+	return updated, nil
+}
+
+// SetStatus suspends or reactivates any user's account on behalf of staff.
+// Closed accounts stay closed.
+func (s *AccountService) SetStatus(actorID, accountID uuid.UUID, req *models.UpdateAccountStatusRequest, meta *models.RequestMeta) (*models.Account, error) {
+	if req.Status != models.AccountStatusActive && req.Status != models.AccountStatusSuspended {
+		return nil, ErrInvalidAccountStatus
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("reason is required")
+	}
+
+	account, err := s.accountRepo.GetByID(accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrAccountNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	if account.Status == models.AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+	if account.Status == req.Status {
+		return account, nil
+	}
+
+	status := req.Status
+	updated, err := s.accountRepo.Update(accountID, &models.UpdateAccountRequest{Status: &status})
+	if err != nil {
+		if errors.Is(err, db.ErrAccountNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	action := models.AuditActionAccountUpdated
+	if status == models.AccountStatusSuspended {
+		action = models.AuditActionAccountSuspended
+	}
+	s.audit.Record(meta, &models.CreateAuditLogRequest{
+		UserID:      &actorID,
+		AccountID:   &updated.ID,
+		Action:      action,
+		EntityType:  "account",
+		EntityID:    &updated.ID,
+		OldValues:   map[string]interface{}{"status": account.Status},
+		NewValues:   map[string]interface{}{"status": updated.Status},
+		Description: &reason,
+		Metadata:    map[string]interface{}{"account_holder_id": account.UserID},
+	})
+
+	return updated, nil
+}
+
+// getOwnedAccount loads an account and hides it from anyone but its owner
+func (s *AccountService) getOwnedAccount(userID, accountID uuid.UUID) (*models.Account, error) {
+	account, err := s.accountRepo.GetByID(accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrAccountNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	if account.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+
+	return account, nil
+}
+
+func validateCreateAccountRequest(req *models.CreateAccountRequest) error {
+	switch req.AccountType {
+	case models.AccountTypeSavings, models.AccountTypeChecking, models.AccountTypeBusiness, models.AccountTypeInvestment:
+	default:
+		return fmt.Errorf("account type must be one of savings, checking, business, investment")
+	}
+
+	if err := validateAccountName(strings.TrimSpace(req.AccountName)); err != nil {
+		return err
+	}
+
+	if err := validateCurrency(req.Currency); err != nil {
+		return err
+	}
+
+	if !req.DailyLimit.IsPositive() {
+		return fmt.Errorf("daily limit must be greater than zero")
+	}
+	if !req.MonthlyLimit.IsPositive() {
+		return fmt.Errorf("monthly limit must be greater than zero")
+	}
+	if req.DailyLimit.GreaterThan(req.MonthlyLimit) {
+		return ErrInvalidAccountLimits
+	}
+
+	return nil
+}
+
+func validateAccountName(name string) error {
+	if len(name) < 3 || len(name) > 100 {
+		return fmt.Errorf("account name must be between 3 and 100 characters")
+	}
+	return nil
+}
+
+func validateCurrency(currency string) error {
+	if len(currency) != 3 {
+		return fmt.Errorf("currency must be a 3-letter ISO 4217 code")
+	}
+	for _, ch := range currency {
+		if (ch < 'A' || ch > 'Z') && (ch < 'a' || ch > 'z') {
+			return fmt.Errorf("currency must be a 3-letter ISO 4217 code")
+		}
+	}
+	return nil
+}
+
+// accountAuditValues lists the account settings recorded in the audit trail;
+// amounts are kept as strings so snapshots compare by value
+func accountAuditValues(account *models.Account) map[string]interface{} {
+	return map[string]interface{}{
+		"account_number": account.AccountNumber,
+		"account_type":   account.AccountType,
+		"account_name":   account.AccountName,
+		"currency":       account.Currency,
+		"status":         account.Status,
+		"daily_limit":    account.DailyLimit.String(),
+		"monthly_limit":  account.MonthlyLimit.String(),
+		"is_primary":     account.IsPrimary,
+	}
+}
+
+func toAccountSummary(account *models.Account) models.AccountSummary {
+	return models.AccountSummary{
+		ID:               account.ID,
+		AccountNumber:    account.AccountNumber,
+		AccountType:      account.AccountType,
+		AccountName:      account.AccountName,
+		Balance:          account.Balance,
+		AvailableBalance: account.AvailableBalance,
+		Currency:         account.Currency,
+		Status:           account.Status,
+		IsPrimary:        account.IsPrimary,
+	}
+}
@@ -0,0 +1,346 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// apiKeyMarker starts every key, so leaked keys are easy to recognise
+	apiKeyMarker      = "fts"
+	apiKeyIDBytes     = 6
+	apiKeySecretBytes = 32
+	maxActiveAPIKeys  = 10
+	maxAPIKeyIPs      = 20
+	// apiKeyTouchInterval is how stale last_used_at may get before a request
+	// updates it
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey       = errors.New("invalid or expired API key")
+	ErrAPIKeyIPNotAllowed  = errors.New("API key may not be used from this address")
+	ErrAPIKeyNotFound      = errors.New("API key not found")
+	ErrAPIKeyBusinessOnly  = errors.New("API keys are only available to holders of an active business account")
+	ErrInvalidAPIKeyScope  = errors.New("unknown API key scope")
+	ErrInvalidAPIKeyIP     = errors.New("allowed IPs must be IP addresses or CIDR ranges")
+	ErrInvalidAPIKeyName   = errors.New("API key name is required and must be at most 100 characters")
+	ErrInvalidAPIKeyExpiry = errors.New("expires_in_days must be between 1 and 365")
+	ErrAPIKeyLimitReached  = fmt.Errorf("a user may have at most %d active API keys", maxActiveAPIKeys)
+)
+
+var apiKeyScopes = map[string]bool{
+	models.APIKeyScopeReadBalances:      true,
+	models.APIKeyScopeReadTransactions:  true,
+	models.APIKeyScopeInitiateTransfers: true,
+}
+
+// APIKeyService issues and checks the API keys business account holders use
+// to call the API from their own systems. A key is fts_<id>_<secret>; the
+// fts_<id> prefix is stored in the clear to find the key, and the whole key
+// only as a SHA-256 hash. A key acts as its owner, limited to its scopes.
+type APIKeyService struct {
+	db          *db.Database
+	apiKeyRepo  *db.APIKeyRepository
+	accountRepo *db.AccountRepository
+	userRepo    *db.UserRepository
+	audit       *AuditService
+}
+
+func NewAPIKeyService(database *db.Database, apiKeyRepo *db.APIKeyRepository, accountRepo *db.AccountRepository, userRepo *db.UserRepository, audit *AuditService) *APIKeyService {
+	return &APIKeyService{
+		db:          database,
+		apiKeyRepo:  apiKeyRepo,
+		accountRepo: accountRepo,
+		userRepo:    userRepo,
+		audit:       audit,
+	}
+}
+
+// Create issues a key for a user with an active business account. The key
+// is returned once and cannot be retrieved again.
+func (s *APIKeyService) Create(userID uuid.UUID, req *models.CreateAPIKeyRequest, meta *models.RequestMeta) (*models.CreateAPIKeyResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, ErrInvalidAPIKeyName
+	}
+	scopes, err := normalizeAPIKeyScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	allowedIPs, err := normalizeAPIKeyIPs(req.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		if *req.ExpiresInDays < 1 || *req.ExpiresInDays > 365 {
+			return nil, ErrInvalidAPIKeyExpiry
+		}
+		t := time.Now().AddDate(0, 0, *req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	business, err := s.hasActiveBusinessAccount(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !business {
+		return nil, ErrAPIKeyBusinessOnly
+	}
+
+	rawKey, prefix, hash, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &models.APIKey{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Name:       name,
+		Prefix:     prefix,
+		KeyHash:    hash,
+		Scopes:     scopes,
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  expiresAt,
+	}
+
+	err = s.db.WithTransaction(func(tx *sql.Tx) error {
+		active, err := s.apiKeyRepo.CountActiveByUserTx(tx, userID)
+		if err != nil {
+			return err
+		}
+		if active >= maxActiveAPIKeys {
+			return ErrAPIKeyLimitReached
+		}
+
+		if err := s.apiKeyRepo.CreateTx(tx, key); err != nil {
+			return err
+		}
+
+		return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+			UserID:     &userID,
+			Action:     models.AuditActionAPIKeyCreated,
+			EntityType: "api_key",
+			EntityID:   &key.ID,
+			NewValues: map[string]interface{}{
+				"name":        key.Name,
+				"prefix":      key.Prefix,
+				"scopes":      key.Scopes,
+				"allowed_ips": key.AllowedIPs,
+				"expires_at":  key.ExpiresAt,
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreateAPIKeyResponse{APIKey: *key, Key: rawKey}, nil
+}
+
+// List returns a user's keys, without their secrets
+func (s *APIKeyService) List(userID uuid.UUID) ([]*models.APIKey, error) {
+	return s.apiKeyRepo.ListByUser(userID)
+}
+
+// Revoke stops one of a user's keys from working straight away
+func (s *APIKeyService) Revoke(userID, keyID uuid.UUID, meta *models.RequestMeta) error {
+	return s.db.WithTransaction(func(tx *sql.Tx) error {
+		key, err := s.apiKeyRepo.RevokeTx(tx, keyID, userID)
+		if err != nil {
+			if errors.Is(err, db.ErrAPIKeyNotFound) {
+				return ErrAPIKeyNotFound
+			}
+			return err
+		}
+
+		return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+			UserID:     &userID,
+			Action:     models.AuditActionAPIKeyRevoked,
+			EntityType: "api_key",
+			EntityID:   &key.ID,
+			NewValues: map[string]interface{}{
+				"prefix":     key.Prefix,
+				"revoked_at": key.RevokedAt,
+			},
+		})
+	})
+}
+
+// Authenticate checks a key presented by a client at ip and returns it with
+// its owner. Unknown, revoked and expired keys and keys of deactivated users
+// are all ErrInvalidAPIKey; a valid key used from outside its allowlist is
+// ErrAPIKeyIPNotAllowed, and one whose owner no longer has an active business
+// account is ErrAPIKeyBusinessOnly. Other errors mean the key could not be
+// checked.
+func (s *APIKeyService) Authenticate(rawKey, ip string) (*models.APIKey, *models.User, error) {
+	prefix, ok := apiKeyPrefix(rawKey)
+	if !ok {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.GetByPrefix(prefix)
+	if err != nil {
+		if errors.Is(err, db.ErrAPIKeyNotFound) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(rawKey)), []byte(key.KeyHash)) != 1 {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now())) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if !apiKeyIPAllowed(key.AllowedIPs, ip) {
+		return nil, nil, ErrAPIKeyIPNotAllowed
+	}
+
+	user, err := s.userRepo.GetByID(key.UserID)
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+
+	business, err := s.hasActiveBusinessAccount(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !business {
+		return nil, nil, ErrAPIKeyBusinessOnly
+	}
+
+	if err := s.apiKeyRepo.TouchLastUsed(key.ID, ip, apiKeyTouchInterval); err != nil {
+		logrus.WithError(err).WithField("api_key_id", key.ID).Warn("Failed to record API key use")
+	}
+
+	return key, user, nil
+}
+
+func (s *APIKeyService) hasActiveBusinessAccount(userID uuid.UUID) (bool, error) {
+	accounts, err := s.accountRepo.GetByUserID(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, account := range accounts {
+		if account.AccountType == models.AccountTypeBusiness && account.Status == models.AccountStatusActive {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// newAPIKey returns a random key, its prefix and the hash it is stored under
+func newAPIKey() (string, string, string, error) {
+	id := make([]byte, apiKeyIDBytes)
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	prefix := apiKeyMarker + "_" + hex.EncodeToString(id)
+	key := prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, hashAPIKey(key), nil
+}
+
+// apiKeyPrefix returns the fts_<id> start of a key. The secret may itself
+// contain underscores, so only the first two are separators.
+func apiKeyPrefix(key string) (string, bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyMarker || len(parts[1]) != hex.EncodedLen(apiKeyIDBytes) || parts[2] == "" {
+		return "", false
+	}
+	return parts[0] + "_" + parts[1], true
+}
+
+// hashAPIKey returns the form a key is stored in. Keys are random, so a fast
+// hash is enough.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeAPIKeyScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrInvalidAPIKeyScope
+	}
+	seen := make(map[string]bool, len(scopes))
+	normalized := []string{}
+	for _, scope := range scopes {
+		if !apiKeyScopes[scope] {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAPIKeyScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
+
+// normalizeAPIKeyIPs checks an allowlist and writes every entry in canonical
+// form, single addresses without a prefix length
+func normalizeAPIKeyIPs(entries []string) ([]string, error) {
+	if len(entries) > maxAPIKeyIPs {
+		return nil, fmt.Errorf("%w: at most %d entries", ErrInvalidAPIKeyIP, maxAPIKeyIPs)
+	}
+	normalized := []string{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if ip := net.ParseIP(entry); ip != nil {
+			normalized = append(normalized, ip.String())
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAPIKeyIP, entry)
+		}
+		normalized = append(normalized, network.String())
+	}
+	return normalized, nil
+}
+
+// apiKeyIPAllowed reports whether a key with the allowlist may be used from
+// ip. A key with an allowlist is refused when the client address is unknown.
+func apiKeyIPAllowed(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	client := net.ParseIP(ip)
+	if client == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(client) {
+				return true
+			}
+		} else if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(client) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		prefix string
+		ok     bool
+	}{
+		{"valid", "fts_0123456789ab_secret", "fts_0123456789ab", true},
+		{"underscores in secret", "fts_0123456789ab_sec_ret_", "fts_0123456789ab", true},
+		{"wrong marker", "abc_0123456789ab_secret", "", false},
+		{"short id", "fts_0123_secret", "", false},
+		{"long id", "fts_0123456789abcd_secret", "", false},
+		{"empty secret", "fts_0123456789ab_", "", false},
+		{"no secret", "fts_0123456789ab", "", false},
+		{"empty", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, ok := apiKeyPrefix(tt.key)
+			if prefix != tt.prefix || ok != tt.ok {
+				t.Errorf("apiKeyPrefix(%q) = %q, %v, want %q, %v", tt.key, prefix, ok, tt.prefix, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNewAPIKeyRoundTrip(t *testing.T) {
+	key, prefix, hash, err := newAPIKey()
+	if err != nil {
+		t.Fatalf("newAPIKey: %v", err)
+	}
+
+	parsed, ok := apiKeyPrefix(key)
+	if !ok || parsed != prefix {
+		t.Errorf("apiKeyPrefix(newAPIKey()) = %q, %v, want %q, true", parsed, ok, prefix)
+	}
+	if !strings.HasPrefix(key, prefix+"_") {
+		t.Errorf("key %q does not start with its prefix %q", key, prefix)
+	}
+	if hash != hashAPIKey(key) {
+		t.Errorf("returned hash does not match hashAPIKey(key)")
+	}
+}
+
+func TestAPIKeyIPAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		ip      string
+		want    bool
+	}{
+		{"no allowlist", nil, "203.0.113.7", true},
+		{"no allowlist, unknown client", nil, "", true},
+		{"exact address", []string{"203.0.113.7"}, "203.0.113.7", true},
+		{"other address", []string{"203.0.113.7"}, "203.0.113.8", false},
+		{"inside range", []string{"198.51.100.0/24"}, "198.51.100.42", true},
+		{"outside range", []string{"198.51.100.0/24"}, "198.51.101.1", false},
+		{"second entry matches", []string{"203.0.113.7", "198.51.100.0/24"}, "198.51.100.1", true},
+		{"ipv6 address", []string{"2001:db8::1"}, "2001:db8:0:0::1", true},
+		{"ipv6 range", []string{"2001:db8::/32"}, "2001:db8:ffff::1", true},
+		{"unknown client", []string{"203.0.113.7"}, "", false},
+		{"unparseable client", []string{"0.0.0.0/0"}, "not-an-ip", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := apiKeyIPAllowed(tt.allowed, tt.ip); got != tt.want {
+				t.Errorf("apiKeyIPAllowed(%v, %q) = %v, want %v", tt.allowed, tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeAPIKeyIPs(t *testing.T) {
+	got, err := normalizeAPIKeyIPs([]string{" 203.0.113.7 ", "198.51.100.9/24", "2001:0db8::0001"})
+	if err != nil {
+		t.Fatalf("normalizeAPIKeyIPs: %v", err)
+	}
+	want := []string{"203.0.113.7", "198.51.100.0/24", "2001:db8::1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeAPIKeyIPs = %v, want %v", got, want)
+	}
+
+	if _, err := normalizeAPIKeyIPs([]string{"example.com"}); !errors.Is(err, ErrInvalidAPIKeyIP) {
+		t.Errorf("hostname: got %v, want ErrInvalidAPIKeyIP", err)
+	}
+	if _, err := normalizeAPIKeyIPs(make([]string, maxAPIKeyIPs+1)); !errors.Is(err, ErrInvalidAPIKeyIP) {
+		t.Errorf("too many entries: got %v, want ErrInvalidAPIKeyIP", err)
+	}
+}
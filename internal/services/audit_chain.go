@@ -0,0 +1,103 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"time"
+
+	"financial-transaction-system/internal/audit"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// auditVerifyBatchSize is how many entries are loaded at a time while walking the chain
+const auditVerifyBatchSize = 1000
+
+var ErrCheckpointsDisabled = errors.New("audit checkpoint signing key is not configured")
+
+// PublishCheckpoint signs the current chain head, stores the checkpoint and
+// logs it so that a copy exists outside the database. It returns nil when
+// nothing has been chained since the last checkpoint.
+func (s *AuditService) PublishCheckpoint() (*models.AuditCheckpoint, error) {
+	if s.signer == nil {
+		return nil, ErrCheckpointsDisabled
+	}
+
+	seq, hash, err := s.auditRepo.GetChainHead()
+	if err != nil {
+		return nil, err
+	}
+	if hash == nil {
+		return nil, nil
+	}
+
+	latest, err := s.auditRepo.LatestCheckpointSeq()
+	if err != nil {
+		return nil, err
+	}
+	if latest >= seq {
+		return nil, nil
+	}
+
+	checkpoint := &models.AuditCheckpoint{
+		ID:        uuid.New(),
+		Seq:       seq,
+		HeadHash:  *hash,
+		CreatedAt: audit.StampTime(time.Now()),
+	}
+	s.signer.Sign(checkpoint)
+
+	if err := s.auditRepo.CreateCheckpoint(checkpoint); err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"seq":        checkpoint.Seq,
+		"head_hash":  checkpoint.HeadHash,
+		"key_id":     checkpoint.KeyID,
+		"signature":  checkpoint.Signature,
+		"created_at": checkpoint.CreatedAt,
+	}).Info("Published audit checkpoint")
+
+	return checkpoint, nil
+}
+
+// VerifyChain walks the audit chain from the first entry up to the current
+// head with an audit.ChainVerifier and reports the first broken link. With a
+// nil key, checkpoint signatures are not checked. Entries appended while the
+// walk runs are left for the next run.
+func (s *AuditService) VerifyChain(public ed25519.PublicKey) (*models.AuditChainReport, error) {
+	checkpoints, err := s.auditRepo.ListCheckpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	headSeq, headHash, err := s.auditRepo.GetChainHead()
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := audit.NewChainVerifier(public, checkpoints)
+	report := verifier.Report()
+
+	for report.BrokenAt == nil && report.HeadSeq < headSeq {
+		entries, err := s.auditRepo.ListAfterSeq(nil, report.HeadSeq, headSeq, auditVerifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if !verifier.Add(entry) {
+				return report, nil
+			}
+		}
+
+		if len(entries) < auditVerifyBatchSize {
+			break
+		}
+	}
+
+	return verifier.Finish(headSeq, headHash), nil
+}
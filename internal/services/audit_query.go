@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"financial-transaction-system/internal/models"
+)
+
+// auditExportBatchSize is how many entries an export loads at a time
+const auditExportBatchSize = 1000
+
+var ErrInvalidAuditLogFilter = errors.New("invalid audit log filter")
+
+var auditActions = map[models.AuditAction]bool{
+	models.AuditActionUserCreated:          true,
+	models.AuditActionUserUpdated:          true,
+	models.AuditActionUserDeleted:          true,
+	models.AuditActionUserLogin:            true,
+	models.AuditActionUserLogout:           true,
+	models.AuditActionAccountCreated:       true,
+	models.AuditActionAccountUpdated:       true,
+	models.AuditActionAccountSuspended:     true,
+	models.AuditActionAccountClosed:        true,
+	models.AuditActionTransactionCreated:   true,
+	models.AuditActionTransactionProcessed: true,
+	models.AuditActionTransactionFailed:    true,
+	models.AuditActionTransactionCancelled: true,
+	models.AuditActionTransactionReversed:  true,
+	models.AuditActionTransactionRefunded:  true,
+	models.AuditActionBalanceUpdated:       true,
+	models.AuditActionFraudDetected:        true,
+	models.AuditActionFraudCleared:         true,
+	models.AuditActionLimitExceeded:        true,
+	models.AuditActionPasswordChanged:      true,
+	models.AuditActionEmailChanged:         true,
+	models.AuditActionProfileUpdated:       true,
+	models.AuditActionRefreshTokenReused:   true,
+	models.AuditActionUserRoleChanged:      true,
+	models.AuditActionMFAEnabled:           true,
+	models.AuditActionMFADisabled:          true,
+	models.AuditActionMFAChallengeFailed:   true,
+	models.AuditActionStepUpRequired:       true,
+	models.AuditActionStepUpConfirmed:      true,
+	models.AuditActionStepUpFailed:         true,
+	models.AuditActionLoginFailed:          true,
+	models.AuditActionUserLocked:           true,
+	models.AuditActionUserUnlocked:         true,
+	models.AuditActionAPIKeyCreated:        true,
+	models.AuditActionAPIKeyRevoked:        true,
+	models.AuditActionFraudAlertAssigned:   true,
+	models.AuditActionFraudAlertUpdated:    true,
+}
+
+// ListLogs returns one page of audit entries matching the filter, newest first
+func (s *AuditService) ListLogs(filter *models.AuditLogFilter) (*models.AuditLogList, error) {
+	if err := normalizeAuditLogFilter(filter); err != nil {
+		return nil, err
+	}
+
+	total, err := s.auditRepo.Count(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	logs, err := s.auditRepo.List(filter, filter.PageSize+1, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	pagination := offsetPagination(filter.Page, filter.PageSize, total)
+	if len(logs) > filter.PageSize {
+		logs = logs[:filter.PageSize]
+		pagination.HasNext = true
+	}
+
+	list := &models.AuditLogList{
+		AuditLogs:  make([]models.AuditLog, 0, len(logs)),
+		Pagination: pagination,
+	}
+	for _, log := range logs {
+		list.AuditLogs = append(list.AuditLogs, *log)
+	}
+
+	return list, nil
+}
+
+// ExportLogs passes every entry matching the filter to write, oldest first,
+// one batch at a time so that the result never has to fit in memory. Only
+// entries that existed when the export started are included. Paging fields
+// of the filter are ignored.
+func (s *AuditService) ExportLogs(filter *models.AuditLogFilter, write func([]*models.AuditLog) error) error {
+	filter.Page, filter.PageSize = 0, 0
+	if err := normalizeAuditLogFilter(filter); err != nil {
+		return err
+	}
+
+	headSeq, _, err := s.auditRepo.GetChainHead()
+	if err != nil {
+		return err
+	}
+
+	var afterSeq int64
+	for {
+		logs, err := s.auditRepo.ListAfterSeq(filter, afterSeq, headSeq, auditExportBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+
+		if err := write(logs); err != nil {
+			return err
+		}
+
+		if len(logs) < auditExportBatchSize {
+			return nil
+		}
+		afterSeq = logs[len(logs)-1].Seq
+	}
+}
+
+// normalizeAuditLogFilter validates the filter and fills in paging defaults
+func normalizeAuditLogFilter(filter *models.AuditLogFilter) error {
+	if filter.Action != nil && !auditActions[*filter.Action] {
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidAuditLogFilter, *filter.Action)
+	}
+
+	if filter.StartDate != nil && filter.EndDate != nil && !filter.StartDate.Before(*filter.EndDate) {
+		return fmt.Errorf("%w: start_date must be before end_date", ErrInvalidAuditLogFilter)
+	}
+
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.Page < 1 {
+		return fmt.Errorf("%w: page must be at least 1", ErrInvalidAuditLogFilter)
+	}
+
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+	if filter.PageSize < 1 || filter.PageSize > maxPageSize {
+		return fmt.Errorf("%w: page_size must be between 1 and %d", ErrInvalidAuditLogFilter, maxPageSize)
+	}
+
+	return nil
+}
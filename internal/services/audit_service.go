@@ -0,0 +1,90 @@
+package services
+
+import (
+	"database/sql"
+
+	"financial-transaction-system/internal/audit"
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AuditService writes the audit trail. Changes made inside a database
+// transaction are audited with RecordTx so the entry commits with them.
+// signer may be nil, in which case no checkpoints are published.
+type AuditService struct {
+	db        *db.Database
+	auditRepo *db.AuditLogRepository
+	signer    *audit.Signer
+}
+
+func NewAuditService(database *db.Database, auditRepo *db.AuditLogRepository, signer *audit.Signer) *AuditService {
+	return &AuditService{
+		db:        database,
+		auditRepo: auditRepo,
+		signer:    signer,
+	}
+}
+
+// RecordTx writes an entry inside an existing database transaction, stamped
+// with the client and session from meta
+func (s *AuditService) RecordTx(tx *sql.Tx, meta *models.RequestMeta, req *models.CreateAuditLogRequest) error {
+	applyRequestMeta(req, meta)
+	_, err := s.auditRepo.CreateTx(tx, req)
+	return err
+}
+
+// Record writes an entry for a change that has already been committed on
+// its own. A failure cannot undo that change, so it is logged rather than
+// returned.
+func (s *AuditService) Record(meta *models.RequestMeta, req *models.CreateAuditLogRequest) {
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		return s.RecordTx(tx, meta, req)
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("action", req.Action).Error("Failed to write audit log")
+	}
+}
+
+// withSession returns a copy of meta for a session that started during the
+// request, such as the one a login creates
+func withSession(meta *models.RequestMeta, sessionID string) *models.RequestMeta {
+	session := models.RequestMeta{}
+	if meta != nil {
+		session = *meta
+	}
+	session.SessionID = sessionID
+	return &session
+}
+
+// changedValues keeps only the fields that differ between two snapshots
+func changedValues(before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	oldValues := map[string]interface{}{}
+	newValues := map[string]interface{}{}
+	for key, value := range after {
+		if before[key] != value {
+			oldValues[key] = before[key]
+			newValues[key] = value
+		}
+	}
+	return oldValues, newValues
+}
+
+func applyRequestMeta(req *models.CreateAuditLogRequest, meta *models.RequestMeta) {
+	if meta == nil {
+		return
+	}
+	if meta.IPAddress != nil {
+		ip := meta.IPAddress
+		req.IPAddress = &ip
+	}
+	if meta.UserAgent != "" {
+		userAgent := meta.UserAgent
+		req.UserAgent = &userAgent
+	}
+	if meta.SessionID != "" {
+		sessionID := meta.SessionID
+		req.SessionID = &sessionID
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"financial-transaction-system/internal/auth"
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/mail"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// mailSendTimeout bounds how long a request waits on the mail backend
+const mailSendTimeout = 15 * time.Second
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrEmailAlreadyVerified     = errors.New("email address is already verified")
+	ErrVerificationThrottled    = errors.New("a verification email was sent recently; try again later")
+	ErrEmailNotVerified         = errors.New("email address must be verified first")
+)
+
+// EmailVerificationService proves that users own the address they signed up
+// with. The emailed token is a signed JWT naming the user and address, so
+// nothing about it has to be stored.
+type EmailVerificationService struct {
+	userRepo       *db.UserRepository
+	jwtManager     *auth.JWTManager
+	mailer         mail.Mailer
+	audit          *AuditService
+	linkURL        string
+	resendInterval time.Duration
+}
+
+func NewEmailVerificationService(userRepo *db.UserRepository, jwtManager *auth.JWTManager, mailer mail.Mailer, audit *AuditService, linkURL string, resendInterval time.Duration) *EmailVerificationService {
+	return &EmailVerificationService{
+		userRepo:       userRepo,
+		jwtManager:     jwtManager,
+		mailer:         mailer,
+		audit:          audit,
+		linkURL:        linkURL,
+		resendInterval: resendInterval,
+	}
+}
+
+// IsVerified reports whether the user has verified their email address
+func (s *EmailVerificationService) IsVerified(userID uuid.UUID) (bool, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return false, ErrUserNotFound
+	}
+	return user.IsVerified, nil
+}
+
+// SendVerification emails a new verification token to the user, at most once
+// per resend interval
+func (s *EmailVerificationService) SendVerification(ctx context.Context, user *models.User) error {
+	if user.IsVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	claimed, err := s.userRepo.MarkVerificationSent(user.ID, s.resendInterval)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrVerificationThrottled
+	}
+
+	token, err := s.jwtManager.GenerateEmailVerificationToken(user)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mailSendTimeout)
+	defer cancel()
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body:    s.verificationBody(user, token),
+	})
+}
+
+// Resend sends the signed-in user a new verification email
+func (s *EmailVerificationService) Resend(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	return s.SendVerification(ctx, user)
+}
+
+// Verify marks the user named by a verification token as verified. Using a
+// token again is harmless and succeeds.
+func (s *EmailVerificationService) Verify(token string, meta *models.RequestMeta) error {
+	claims, err := s.jwtManager.ParseEmailVerificationToken(token)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil || user.Email != claims.Email {
+		return ErrInvalidVerificationToken
+	}
+	if user.IsVerified {
+		return nil
+	}
+
+	if err := s.userRepo.SetVerified(user.ID, true); err != nil {
+		return err
+	}
+
+	s.audit.Record(meta, &models.CreateAuditLogRequest{
+		UserID:     &user.ID,
+		Action:     models.AuditActionUserUpdated,
+		EntityType: "user",
+		EntityID:   &user.ID,
+		OldValues:  map[string]interface{}{"is_verified": false},
+		NewValues:  map[string]interface{}{"is_verified": true},
+	})
+
+	return nil
+}
+
+func (s *EmailVerificationService) verificationBody(user *models.User, token string) string {
+	instruction := "Use this token to verify your email address:"
+	proof := token
+	if s.linkURL != "" {
+		instruction = "Open this link to verify your email address:"
+		proof = s.linkURL + "?" + url.Values{"token": {token}}.Encode()
+	}
+
+	hours := int(s.jwtManager.EmailVerificationTokenExpiry().Hours())
+	return fmt.Sprintf("Hello %s,\n\n%s\n\n%s\n\nIt expires in %d hours. If you did not create an account, ignore this email.\n",
+		user.FirstName, instruction, proof, hours)
+}
@@ -0,0 +1,445 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrFraudAlertNotFound      = errors.New("fraud alert not found")
+	ErrFraudAlertClosed        = errors.New("fraud alert is already closed")
+	ErrInvalidAlertTransition  = errors.New("invalid fraud alert status transition")
+	ErrResolutionNotesRequired = errors.New("resolution notes are required to close an alert")
+	ErrAssigneeNotFound        = errors.New("assignee not found")
+	ErrAssigneeNotAnalyst      = errors.New("alerts can only be assigned to fraud analysts or admins")
+	ErrInvalidFraudAlertFilter = errors.New("invalid fraud alert filter")
+)
+
+// fraudAlertTransitions is the case workflow: an alert is picked up for
+// investigation and then closed as confirmed fraud or a false positive
+var fraudAlertTransitions = map[models.FraudStatus][]models.FraudStatus{
+	models.FraudStatusOpen:          {models.FraudStatusInvestigating},
+	models.FraudStatusInvestigating: {models.FraudStatusResolved, models.FraudStatusFalsePositive},
+}
+
+// FraudService is the case-management side of fraud detection: analysts work
+// the alerts raised by the engine and decide the fate of held transactions.
+type FraudService struct {
+	db           *db.Database
+	fraudRepo    *db.FraudAlertRepository
+	userRepo     *db.UserRepository
+	accountRepo  *db.AccountRepository
+	txnRepo      *db.TransactionRepository
+	audit        *AuditService
+	transactions *TransactionService
+}
+
+func NewFraudService(database *db.Database, fraudRepo *db.FraudAlertRepository, userRepo *db.UserRepository, accountRepo *db.AccountRepository, txnRepo *db.TransactionRepository, audit *AuditService, transactions *TransactionService) *FraudService {
+	return &FraudService{
+		db:           database,
+		fraudRepo:    fraudRepo,
+		userRepo:     userRepo,
+		accountRepo:  accountRepo,
+		txnRepo:      txnRepo,
+		audit:        audit,
+		transactions: transactions,
+	}
+}
+
+// ListAlerts returns one page of alerts matching the filter, newest first
+func (s *FraudService) ListAlerts(filter *models.FraudAlertFilter) (*models.FraudAlertList, error) {
+	if err := normalizeFraudAlertFilter(filter); err != nil {
+		return nil, err
+	}
+
+	total, err := s.fraudRepo.Count(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	alerts, err := s.fraudRepo.List(filter, filter.PageSize+1, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	pagination := offsetPagination(filter.Page, filter.PageSize, total)
+	if len(alerts) > filter.PageSize {
+		alerts = alerts[:filter.PageSize]
+		pagination.HasNext = true
+	}
+
+	list := &models.FraudAlertList{
+		Alerts:     make([]models.FraudAlertResponse, 0, len(alerts)),
+		Pagination: pagination,
+	}
+	for _, alert := range alerts {
+		list.Alerts = append(list.Alerts, *toFraudAlertResponse(alert))
+	}
+
+	return list, nil
+}
+
+// GetAlert returns an alert together with the user, account and transaction it concerns
+func (s *FraudService) GetAlert(alertID uuid.UUID) (*models.FraudAlertResponse, error) {
+	alert, err := s.fraudRepo.GetByID(alertID)
+	if err != nil {
+		if errors.Is(err, db.ErrFraudAlertNotFound) {
+			return nil, ErrFraudAlertNotFound
+		}
+		return nil, err
+	}
+
+	return s.withRelated(alert)
+}
+
+// AssignAlert hands an open alert to an analyst, or to the caller when no
+// assignee is given, and audits the assignment
+func (s *FraudService) AssignAlert(actorID, alertID uuid.UUID, req *models.AssignFraudAlertRequest, meta *models.RequestMeta) (*models.FraudAlertResponse, error) {
+	assigneeID := actorID
+	if req.AssigneeID != nil {
+		assigneeID = *req.AssigneeID
+	}
+	assignee, err := s.userRepo.GetByID(assigneeID)
+	if err != nil {
+		return nil, ErrAssigneeNotFound
+	}
+	if assignee.Role != models.UserRoleFraudAnalyst && assignee.Role != models.UserRoleAdmin {
+		return nil, ErrAssigneeNotAnalyst
+	}
+
+	var alert *models.FraudAlert
+	err = s.db.WithTransaction(func(tx *sql.Tx) error {
+		locked, err := s.lockAlert(tx, alertID)
+		if err != nil {
+			return err
+		}
+		alert = locked
+
+		if isClosedFraudStatus(alert.Status) {
+			return ErrFraudAlertClosed
+		}
+
+		previous := alert.AssignedTo
+		now := time.Now()
+		alert.AssignedTo = &assigneeID
+		alert.AssignedAt = &now
+		if err := s.fraudRepo.UpdateTx(tx, alert); err != nil {
+			return err
+		}
+
+		accountID := alert.AccountID
+		return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+			UserID:        &actorID,
+			AccountID:     &accountID,
+			TransactionID: alert.TransactionID,
+			Action:        models.AuditActionFraudAlertAssigned,
+			EntityType:    "fraud_alert",
+			EntityID:      &alert.ID,
+			OldValues:     map[string]interface{}{"assigned_to": previous},
+			NewValues:     map[string]interface{}{"assigned_to": assigneeID},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withRelated(alert)
+}
+
+// UpdateAlert moves an alert through the case workflow and records notes.
+// Confirming fraud cancels the held transaction the alert was raised for;
+// once every alert on that transaction is a false positive, it is posted.
+func (s *FraudService) UpdateAlert(actorID, alertID uuid.UUID, req *models.UpdateFraudAlertRequest, meta *models.RequestMeta) (*models.FraudAlertResponse, error) {
+	var notes *string
+	if req.ResolutionNotes != nil {
+		trimmed := strings.TrimSpace(*req.ResolutionNotes)
+		if trimmed != "" {
+			notes = &trimmed
+		}
+	}
+
+	var alert *models.FraudAlert
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		locked, err := s.lockAlert(tx, alertID)
+		if err != nil {
+			return err
+		}
+		alert = locked
+
+		if isClosedFraudStatus(alert.Status) {
+			return ErrFraudAlertClosed
+		}
+
+		if notes != nil {
+			alert.ResolutionNotes = notes
+		}
+
+		if req.Status == nil || *req.Status == alert.Status {
+			if err := s.fraudRepo.UpdateTx(tx, alert); err != nil {
+				return err
+			}
+			return s.auditUpdatedAlertTx(tx, alert, actorID, alert.Status, meta)
+		}
+
+		if !canTransitionAlert(alert.Status, *req.Status) {
+			return fmt.Errorf("%w: %s to %s", ErrInvalidAlertTransition, alert.Status, *req.Status)
+		}
+
+		previous := alert.Status
+		alert.Status = *req.Status
+		if !isClosedFraudStatus(alert.Status) {
+			if err := s.fraudRepo.UpdateTx(tx, alert); err != nil {
+				return err
+			}
+			return s.auditUpdatedAlertTx(tx, alert, actorID, previous, meta)
+		}
+
+		if alert.ResolutionNotes == nil {
+			return ErrResolutionNotesRequired
+		}
+
+		now := time.Now()
+		alert.ResolvedBy = &actorID
+		alert.ResolvedAt = &now
+		if err := s.fraudRepo.UpdateTx(tx, alert); err != nil {
+			return err
+		}
+
+		outcome, err := s.settleHeldTransactionTx(tx, alert, actorID)
+		if err != nil {
+			return err
+		}
+
+		return s.auditClosedAlertTx(tx, alert, actorID, outcome, meta)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withRelated(alert)
+}
+
+// settleHeldTransactionTx applies a closed alert to the transaction it was
+// raised for, if that transaction is still held. A cleared transaction that
+// still waits for step-up authentication is left for the user to confirm. It
+// returns the status the transaction ended in, or an empty string when it was
+// left alone.
+func (s *FraudService) settleHeldTransactionTx(tx *sql.Tx, alert *models.FraudAlert, actorID uuid.UUID) (models.TransactionStatus, error) {
+	if alert.TransactionID == nil {
+		return "", nil
+	}
+
+	txn, err := s.transactions.lockTransaction(tx, *alert.TransactionID)
+	if err != nil {
+		return "", err
+	}
+	if txn.Status != models.TransactionStatusPending {
+		return "", nil
+	}
+
+	reason := fmt.Sprintf("Fraud alert %s closed as %s: %s", alert.ID, alert.Status, *alert.ResolutionNotes)
+
+	if alert.Status == models.FraudStatusResolved {
+		if err := s.transactions.rejectHeldTx(tx, txn, actorID, reason); err != nil {
+			return "", err
+		}
+		return txn.Status, nil
+	}
+
+	total, cleared, err := s.fraudRepo.CountByTransactionTx(tx, txn.ID)
+	if err != nil {
+		return "", err
+	}
+	if cleared < total {
+		return "", nil
+	}
+
+	awaiting, err := s.transactions.awaitingStepUpTx(tx, txn.ID)
+	if err != nil {
+		return "", err
+	}
+	if awaiting {
+		return "", nil
+	}
+
+	if err := s.transactions.releaseHeldTx(tx, txn, actorID, reason); err != nil {
+		return "", err
+	}
+	return txn.Status, nil
+}
+
+// auditUpdatedAlertTx records a change to an alert that leaves it open: a
+// move to investigating or new notes. Closing an alert is audited by
+// auditClosedAlertTx.
+func (s *FraudService) auditUpdatedAlertTx(tx *sql.Tx, alert *models.FraudAlert, actorID uuid.UUID, previous models.FraudStatus, meta *models.RequestMeta) error {
+	accountID := alert.AccountID
+	return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+		UserID:        &actorID,
+		AccountID:     &accountID,
+		TransactionID: alert.TransactionID,
+		Action:        models.AuditActionFraudAlertUpdated,
+		EntityType:    "fraud_alert",
+		EntityID:      &alert.ID,
+		OldValues:     map[string]interface{}{"status": previous},
+		NewValues:     map[string]interface{}{"status": alert.Status},
+		Description:   alert.ResolutionNotes,
+	})
+}
+
+func (s *FraudService) auditClosedAlertTx(tx *sql.Tx, alert *models.FraudAlert, actorID uuid.UUID, outcome models.TransactionStatus, meta *models.RequestMeta) error {
+	action := models.AuditActionFraudDetected
+	if alert.Status == models.FraudStatusFalsePositive {
+		action = models.AuditActionFraudCleared
+	}
+
+	metadata := map[string]interface{}{
+		"rule_name":  alert.RuleName,
+		"risk_score": alert.RiskScore,
+	}
+	if outcome != "" {
+		metadata["transaction_status"] = outcome
+	}
+
+	accountID := alert.AccountID
+	return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+		UserID:        &actorID,
+		AccountID:     &accountID,
+		TransactionID: alert.TransactionID,
+		Action:        action,
+		EntityType:    "fraud_alert",
+		EntityID:      &alert.ID,
+		OldValues:     map[string]interface{}{"status": models.FraudStatusInvestigating},
+		NewValues:     map[string]interface{}{"status": alert.Status},
+		Description:   alert.ResolutionNotes,
+		Metadata:      metadata,
+	})
+}
+
+func (s *FraudService) lockAlert(tx *sql.Tx, alertID uuid.UUID) (*models.FraudAlert, error) {
+	alert, err := s.fraudRepo.LockForUpdateTx(tx, alertID)
+	if err != nil {
+		if errors.Is(err, db.ErrFraudAlertNotFound) {
+			return nil, ErrFraudAlertNotFound
+		}
+		return nil, err
+	}
+	return alert, nil
+}
+
+// withRelated builds the response for an alert; related records that can no
+// longer be loaded, such as a deactivated user, are left out
+func (s *FraudService) withRelated(alert *models.FraudAlert) (*models.FraudAlertResponse, error) {
+	response := toFraudAlertResponse(alert)
+
+	if user, err := s.userRepo.GetByID(alert.UserID); err == nil {
+		response.User = toUserProfile(user)
+	}
+
+	account, err := s.accountRepo.GetByID(alert.AccountID)
+	if err != nil && !errors.Is(err, db.ErrAccountNotFound) {
+		return nil, err
+	}
+	if account != nil {
+		summary := toAccountSummary(account)
+		response.Account = &summary
+	}
+
+	if alert.TransactionID != nil {
+		txn, err := s.txnRepo.GetByID(*alert.TransactionID)
+		if err != nil && !errors.Is(err, db.ErrTransactionNotFound) {
+			return nil, err
+		}
+		if txn != nil {
+			response.Transaction = toTransactionResponse(txn)
+		}
+	}
+
+	return response, nil
+}
+
+func canTransitionAlert(from, to models.FraudStatus) bool {
+	for _, allowed := range fraudAlertTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func isClosedFraudStatus(status models.FraudStatus) bool {
+	return status == models.FraudStatusResolved || status == models.FraudStatusFalsePositive
+}
+
+// normalizeFraudAlertFilter validates the filter and fills in paging defaults
+func normalizeFraudAlertFilter(filter *models.FraudAlertFilter) error {
+	if filter.Severity != nil {
+		switch *filter.Severity {
+		case models.FraudSeverityLow, models.FraudSeverityMedium, models.FraudSeverityHigh, models.FraudSeverityCritical:
+		default:
+			return fmt.Errorf("%w: unknown severity %q", ErrInvalidFraudAlertFilter, *filter.Severity)
+		}
+	}
+
+	if filter.Status != nil {
+		switch *filter.Status {
+		case models.FraudStatusOpen, models.FraudStatusInvestigating, models.FraudStatusResolved, models.FraudStatusFalsePositive:
+		default:
+			return fmt.Errorf("%w: unknown status %q", ErrInvalidFraudAlertFilter, *filter.Status)
+		}
+	}
+
+	if filter.MinRiskScore != nil && filter.MaxRiskScore != nil && *filter.MinRiskScore > *filter.MaxRiskScore {
+		return fmt.Errorf("%w: min_risk_score cannot exceed max_risk_score", ErrInvalidFraudAlertFilter)
+	}
+
+	if filter.StartDate != nil && filter.EndDate != nil && !filter.StartDate.Before(*filter.EndDate) {
+		return fmt.Errorf("%w: start_date must be before end_date", ErrInvalidFraudAlertFilter)
+	}
+
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.Page < 1 {
+		return fmt.Errorf("%w: page must be at least 1", ErrInvalidFraudAlertFilter)
+	}
+
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+	if filter.PageSize < 1 || filter.PageSize > maxPageSize {
+		return fmt.Errorf("%w: page_size must be between 1 and %d", ErrInvalidFraudAlertFilter, maxPageSize)
+	}
+
+	return nil
+}
+
+func toFraudAlertResponse(alert *models.FraudAlert) *models.FraudAlertResponse {
+	return &models.FraudAlertResponse{
+		ID:              alert.ID,
+		UserID:          alert.UserID,
+		AccountID:       alert.AccountID,
+		TransactionID:   alert.TransactionID,
+		RuleName:        alert.RuleName,
+		Severity:        alert.Severity,
+		Status:          alert.Status,
+		RiskScore:       alert.RiskScore,
+		Description:     alert.Description,
+		Details:         alert.Details,
+		ResolvedBy:      alert.ResolvedBy,
+		ResolvedAt:      alert.ResolvedAt,
+		ResolutionNotes: alert.ResolutionNotes,
+		AssignedTo:      alert.AssignedTo,
+		AssignedAt:      alert.AssignedAt,
+		CreatedAt:       alert.CreatedAt,
+		UpdatedAt:       alert.UpdatedAt,
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const MaxIdempotencyKeyLength = 255
+
+var (
+	ErrInvalidIdempotencyKey    = fmt.Errorf("idempotency key must be between 1 and %d characters", MaxIdempotencyKeyLength)
+	ErrIdempotencyKeyMismatch   = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+type IdempotencyService struct {
+	idempotencyRepo *db.IdempotencyRepository
+	ttl             time.Duration
+}
+
+func NewIdempotencyService(idempotencyRepo *db.IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		idempotencyRepo: idempotencyRepo,
+		ttl:             ttl,
+	}
+}
+
+// Begin claims a key for a request. It returns the stored record when the
+// request is a replay of a completed one, or nil when the caller should
+// process the request and then call Complete or Release.
+func (s *IdempotencyService) Begin(userID uuid.UUID, key, method, path string, body []byte) (*models.IdempotencyKey, error) {
+	if len(key) == 0 || len(key) > MaxIdempotencyKeyLength {
+		return nil, ErrInvalidIdempotencyKey
+	}
+
+	record := &models.IdempotencyKey{
+		UserID:             userID,
+		Key:                key,
+		RequestMethod:      method,
+		RequestPath:        path,
+		RequestFingerprint: fingerprintRequest(method, path, body),
+		ExpiresAt:          time.Now().Add(s.ttl),
+	}
+
+	reserved, err := s.idempotencyRepo.Reserve(record)
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	existing, err := s.idempotencyRepo.Get(userID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.RequestFingerprint != record.RequestFingerprint {
+		return nil, ErrIdempotencyKeyMismatch
+	}
+	if existing.ResponseStatus == nil {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	return existing, nil
+}
+
+// Complete stores the response that replays of the key will receive
+func (s *IdempotencyService) Complete(userID uuid.UUID, key string, status int, body []byte) error {
+	return s.idempotencyRepo.Complete(userID, key, status, body)
+}
+
+// Release forgets an in-flight key after a server-side failure so the client can retry
+func (s *IdempotencyService) Release(userID uuid.UUID, key string) error {
+	return s.idempotencyRepo.Delete(userID, key)
+}
+
+func (s *IdempotencyService) PurgeExpired() (int64, error) {
+	return s.idempotencyRepo.DeleteExpired()
+}
+
+// fingerprintRequest hashes everything that makes two requests "the same"
+func fingerprintRequest(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{'\n'})
+	hash.Write([]byte(path))
+	hash.Write([]byte{'\n'})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
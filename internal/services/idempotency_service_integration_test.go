@@ -0,0 +1,73 @@
+//go:build integration
+
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/dbtest"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestIdempotencyKeyLifecycle(t *testing.T) {
+	database := dbtest.Open(t)
+	s := NewIdempotencyService(db.NewIdempotencyRepository(database), time.Hour)
+	user := dbtest.CreateUser(t, database, models.UserRoleCustomer)
+	key := "transfer-" + uuid.NewString()
+	path := "/api/v1/transactions/transfer"
+	body := []byte(`{"amount":"10.00"}`)
+
+	record, err := s.Begin(user.ID, key, "POST", path, body)
+	if err != nil || record != nil {
+		t.Fatalf("first Begin: got %v, %v; want the key claimed", record, err)
+	}
+
+	if _, err := s.Begin(user.ID, key, "POST", path, body); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+		t.Errorf("retry while in flight: got %v, want ErrIdempotencyKeyInProgress", err)
+	}
+	if _, err := s.Begin(user.ID, key, "POST", path, []byte(`{"amount":"99.00"}`)); !errors.Is(err, ErrIdempotencyKeyMismatch) {
+		t.Errorf("same key, different body: got %v, want ErrIdempotencyKeyMismatch", err)
+	}
+
+	// Keys belong to a user; another user may use the same one
+	other := dbtest.CreateUser(t, database, models.UserRoleCustomer)
+	if record, err := s.Begin(other.ID, key, "POST", path, body); err != nil || record != nil {
+		t.Errorf("another user's Begin: got %v, %v; want the key claimed", record, err)
+	}
+
+	if err := s.Complete(user.ID, key, 201, []byte(`{"id":"1"}`)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	record, err = s.Begin(user.ID, key, "POST", path, body)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if record == nil || record.ResponseStatus == nil || *record.ResponseStatus != 201 || string(record.ResponseBody) != `{"id":"1"}` {
+		t.Errorf("replay returned %+v, want the stored 201 response", record)
+	}
+}
+
+func TestReleasedIdempotencyKeyCanBeRetried(t *testing.T) {
+	database := dbtest.Open(t)
+	s := NewIdempotencyService(db.NewIdempotencyRepository(database), time.Hour)
+	user := dbtest.CreateUser(t, database, models.UserRoleCustomer)
+	key := "deposit-" + uuid.NewString()
+
+	if _, err := s.Begin(user.ID, key, "POST", "/api/v1/transactions/deposit", nil); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if err := s.Release(user.ID, key); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	record, err := s.Begin(user.ID, key, "POST", "/api/v1/transactions/deposit", nil)
+	if err != nil || record != nil {
+		t.Errorf("Begin after Release: got %v, %v; want the key claimed again", record, err)
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFingerprintRequest(t *testing.T) {
+	base := fingerprintRequest("POST", "/api/v1/transactions/transfer", []byte(`{"amount":10}`))
+	if again := fingerprintRequest("POST", "/api/v1/transactions/transfer", []byte(`{"amount":10}`)); again != base {
+		t.Errorf("the same request fingerprinted differently: %s != %s", again, base)
+	}
+
+	tests := map[string][3]string{
+		"method": {"PUT", "/api/v1/transactions/transfer", `{"amount":10}`},
+		"path":   {"POST", "/api/v1/transactions/deposit", `{"amount":10}`},
+		"body":   {"POST", "/api/v1/transactions/transfer", `{"amount":100}`},
+		// Fields are separated, so moving bytes between them changes the hash
+		"boundary": {"POST", "/api/v1/transactions/transfer\n{", `"amount":10}`},
+	}
+	for name, req := range tests {
+		if got := fingerprintRequest(req[0], req[1], []byte(req[2])); got == base {
+			t.Errorf("changing the %s left the fingerprint unchanged", name)
+		}
+	}
+}
+
+func TestBeginRejectsInvalidKeys(t *testing.T) {
+	// Keys are checked before the repository is touched
+	s := NewIdempotencyService(nil, 0)
+
+	for _, key := range []string{"", strings.Repeat("k", MaxIdempotencyKeyLength+1)} {
+		if _, err := s.Begin(uuid.New(), key, "POST", "/", nil); !errors.Is(err, ErrInvalidIdempotencyKey) {
+			t.Errorf("key of %d characters: got %v, want ErrInvalidIdempotencyKey", len(key), err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+//go:build integration
+
+package services
+
+import (
+	"testing"
+	"time"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/dbtest"
+	"financial-transaction-system/internal/fraud"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// permissiveRules never flag the amounts the integration tests move
+var permissiveRules = fraud.Rules{
+	MaxTransactionAmount: decimal.NewFromInt(1000000),
+	MaxDailyAmount:       decimal.NewFromInt(100000000),
+	VelocityThreshold:    1000000,
+	VelocityWindow:       time.Hour,
+}
+
+// testEnv wires the money-movement services to the test database the way
+// cmd/server does
+type testEnv struct {
+	database     *db.Database
+	accountRepo  *db.AccountRepository
+	txnRepo      *db.TransactionRepository
+	fraudRepo    *db.FraudAlertRepository
+	stepUpRepo   *db.StepUpRepository
+	ledger       *LedgerService
+	audit        *AuditService
+	transactions *TransactionService
+	fraud        *FraudService
+}
+
+func newTestEnv(t *testing.T, rules fraud.Rules, stepUp StepUpPolicy) *testEnv {
+	t.Helper()
+
+	database := dbtest.Open(t)
+	env := &testEnv{
+		database:    database,
+		accountRepo: db.NewAccountRepository(database),
+		txnRepo:     db.NewTransactionRepository(database),
+		fraudRepo:   db.NewFraudAlertRepository(database),
+		stepUpRepo:  db.NewStepUpRepository(database),
+	}
+	env.ledger = NewLedgerService(db.NewLedgerRepository(database), env.accountRepo)
+	env.audit = NewAuditService(database, db.NewAuditLogRepository(database), nil)
+	env.transactions = NewTransactionService(database, env.accountRepo, env.txnRepo, env.ledger, env.audit,
+		fraud.NewEngine(rules, env.txnRepo), env.fraudRepo, env.stepUpRepo, stepUp)
+	env.fraud = NewFraudService(database, env.fraudRepo, db.NewUserRepository(database), env.accountRepo, env.txnRepo,
+		env.audit, env.transactions)
+	return env
+}
+
+// fund deposits amount into the account
+func (env *testEnv) fund(t *testing.T, userID, accountID uuid.UUID, amount string) {
+	t.Helper()
+
+	_, err := env.transactions.Deposit(userID, &models.DepositRequest{
+		ToAccountID: accountID,
+		Amount:      decimal.RequireFromString(amount),
+		Currency:    "USD",
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to fund account: %v", err)
+	}
+}
+
+// transfer moves amount between the user's accounts as a freshly signed-in user
+func (env *testEnv) transfer(t *testing.T, userID, fromID, toID uuid.UUID, amount string) *models.TransactionResponse {
+	t.Helper()
+
+	response, err := env.transactions.Transfer(userID, &models.TransferRequest{
+		FromAccountID: fromID,
+		ToAccountID:   toID,
+		Amount:        decimal.RequireFromString(amount),
+		Currency:      "USD",
+	}, time.Now(), nil)
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	return response
+}
+
+// assertBalance fails the test unless the account's cached balance is want
+func (env *testEnv) assertBalance(t *testing.T, accountID uuid.UUID, want string) {
+	t.Helper()
+
+	if balance := env.balance(t, accountID); !balance.Equal(decimal.RequireFromString(want)) {
+		t.Errorf("account %s: balance %s, want %s", accountID, balance, want)
+	}
+}
+
+// balance returns the account's cached balance
+func (env *testEnv) balance(t *testing.T, accountID uuid.UUID) decimal.Decimal {
+	t.Helper()
+
+	account, err := env.accountRepo.GetByID(accountID)
+	if err != nil {
+		t.Fatalf("failed to get account: %v", err)
+	}
+	return account.Balance
+}
+
+// alerts returns the fraud alerts raised for a transaction
+func (env *testEnv) alerts(t *testing.T, transactionID uuid.UUID) []*models.FraudAlert {
+	t.Helper()
+
+	alerts, err := env.fraudRepo.List(&models.FraudAlertFilter{TransactionID: &transactionID}, 100, 0)
+	if err != nil {
+		t.Fatalf("failed to list fraud alerts: %v", err)
+	}
+	return alerts
+}
+
+// closeAlert takes an alert through investigation to status as analyst
+func (env *testEnv) closeAlert(t *testing.T, analystID, alertID uuid.UUID, status models.FraudStatus) {
+	t.Helper()
+
+	investigating := models.FraudStatusInvestigating
+	if _, err := env.fraud.UpdateAlert(analystID, alertID, &models.UpdateFraudAlertRequest{Status: &investigating}, nil); err != nil {
+		t.Fatalf("failed to investigate alert: %v", err)
+	}
+	notes := "Checked with the customer"
+	if _, err := env.fraud.UpdateAlert(analystID, alertID, &models.UpdateFraudAlertRequest{Status: &status, ResolutionNotes: &notes}, nil); err != nil {
+		t.Fatalf("failed to close alert: %v", err)
+	}
+}
+
+// transactionStatus returns the stored status of a transaction
+func (env *testEnv) transactionStatus(t *testing.T, transactionID uuid.UUID) models.TransactionStatus {
+	t.Helper()
+
+	txn, err := env.txnRepo.GetByID(transactionID)
+	if err != nil {
+		t.Fatalf("failed to get transaction: %v", err)
+	}
+	return txn.Status
+}
+
+// assertReconciled fails the test if any account's balance differs from its postings
+func (env *testEnv) assertReconciled(t *testing.T) {
+	t.Helper()
+
+	mismatches, err := env.ledger.Reconcile()
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	for _, m := range mismatches {
+		t.Errorf("account %s: balance %s, ledger %s", m.AccountNumber, m.CachedBalance, m.LedgerBalance)
+	}
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var ErrUnbalancedEntry = errors.New("journal entry debits and credits do not balance")
+
+// LedgerService owns the double-entry journal. Postings are the source of
+// truth for balances; accounts.balance is a cached projection that is only
+// ever changed here, in the same database transaction as the postings.
+type LedgerService struct {
+	ledgerRepo  *db.LedgerRepository
+	accountRepo *db.AccountRepository
+}
+
+func NewLedgerService(ledgerRepo *db.LedgerRepository, accountRepo *db.AccountRepository) *LedgerService {
+	return &LedgerService{
+		ledgerRepo:  ledgerRepo,
+		accountRepo: accountRepo,
+	}
+}
+
+// PostTransactionTx records the balanced legs for a transaction and updates
+// the cached balances of the customer accounts involved.
+func (s *LedgerService) PostTransactionTx(tx *sql.Tx, txn *models.Transaction) (*models.JournalEntry, error) {
+	postings, err := buildPostings(txn)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionID := txn.ID
+	entry := &models.JournalEntry{
+		ID:            uuid.New(),
+		TransactionID: &transactionID,
+		EntryType:     models.JournalEntryTypeTransaction,
+		Description:   txn.Description,
+		Postings:      postings,
+	}
+
+	if err := s.PostEntryTx(tx, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// ReverseTransactionTx posts a compensating entry that mirrors every leg
+// originally posted for the transaction with debit and credit swapped.
+func (s *LedgerService) ReverseTransactionTx(tx *sql.Tx, txn *models.Transaction, reason string) (*models.JournalEntry, error) {
+	entries, err := s.ledgerRepo.GetEntriesByTransactionIDTx(tx, txn.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var postings []models.Posting
+	for _, entry := range entries {
+		if entry.EntryType != models.JournalEntryTypeTransaction {
+			continue
+		}
+		for _, original := range entry.Postings {
+			posting := original
+			posting.ID = uuid.New()
+			if original.Direction == models.PostingDirectionDebit {
+				posting.Direction = models.PostingDirectionCredit
+			} else {
+				posting.Direction = models.PostingDirectionDebit
+			}
+			postings = append(postings, posting)
+		}
+	}
+
+	if len(postings) == 0 {
+		return nil, fmt.Errorf("transaction %s has no postings to reverse", txn.TransactionNumber)
+	}
+
+	transactionID := txn.ID
+	entry := &models.JournalEntry{
+		ID:            uuid.New(),
+		TransactionID: &transactionID,
+		EntryType:     models.JournalEntryTypeReversal,
+		Description:   &reason,
+		Postings:      postings,
+	}
+
+	if err := s.PostEntryTx(tx, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// PostEntryTx validates and writes a journal entry, then applies each
+// customer leg to the cached balance. Account rows must already be locked.
+func (s *LedgerService) PostEntryTx(tx *sql.Tx, entry *models.JournalEntry) error {
+	if err := checkBalanced(entry.Postings); err != nil {
+		return err
+	}
+
+	if err := s.ledgerRepo.CreateEntryTx(tx, entry); err != nil {
+		return err
+	}
+
+	for _, posting := range entry.Postings {
+		if posting.AccountID == nil {
+			continue
+		}
+
+		delta := posting.Amount
+		if posting.Direction == models.PostingDirectionDebit {
+			delta = delta.Neg()
+		}
+
+		if err := s.accountRepo.AdjustBalanceTx(tx, *posting.AccountID, delta); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reconcile compares every cached balance with the balance derived from postings
+func (s *LedgerService) Reconcile() ([]models.BalanceMismatch, error) {
+	return s.ledgerRepo.FindBalanceMismatches()
+}
+
+// GetLedgerBalance returns an account's balance computed from its postings
+func (s *LedgerService) GetLedgerBalance(accountID uuid.UUID) (decimal.Decimal, error) {
+	balance, err := s.ledgerRepo.GetAccountBalance(accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrAccountNotFound) {
+			return decimal.Zero, ErrAccountNotFound
+		}
+		return decimal.Zero, err
+	}
+	return balance, nil
+}
+
+// buildPostings maps a transaction onto debit/credit legs. Customer accounts
+// are liabilities of the bank, so money arriving in one is a credit.
+func buildPostings(txn *models.Transaction) ([]models.Posting, error) {
+	var postings []models.Posting
+
+	switch txn.TransactionType {
+	case models.TransactionTypeTransfer:
+		if txn.FromAccountID == nil || txn.ToAccountID == nil {
+			return nil, fmt.Errorf("transfer requires source and destination accounts")
+		}
+		postings = append(postings,
+			accountPosting(*txn.FromAccountID, models.PostingDirectionDebit, txn.Amount, txn.Currency),
+			accountPosting(*txn.ToAccountID, models.PostingDirectionCredit, txn.Amount, txn.Currency),
+		)
+
+	case models.TransactionTypeDeposit:
+		if txn.ToAccountID == nil {
+			return nil, fmt.Errorf("deposit requires a destination account")
+		}
+		postings = append(postings,
+			systemPosting(models.SystemAccountExternalCash, models.PostingDirectionDebit, txn.Amount, txn.Currency),
+			accountPosting(*txn.ToAccountID, models.PostingDirectionCredit, txn.Amount, txn.Currency),
+		)
+
+	case models.TransactionTypeWithdrawal:
+		if txn.FromAccountID == nil {
+			return nil, fmt.Errorf("withdrawal requires a source account")
+		}
+		postings = append(postings,
+			accountPosting(*txn.FromAccountID, models.PostingDirectionDebit, txn.Amount, txn.Currency),
+			systemPosting(models.SystemAccountExternalCash, models.PostingDirectionCredit, txn.Amount, txn.Currency),
+		)
+
+	case models.TransactionTypeFee:
+		if txn.FromAccountID == nil {
+			return nil, fmt.Errorf("fee requires a source account")
+		}
+		postings = append(postings,
+			accountPosting(*txn.FromAccountID, models.PostingDirectionDebit, txn.Amount, txn.Currency),
+			systemPosting(models.SystemAccountFeeIncome, models.PostingDirectionCredit, txn.Amount, txn.Currency),
+		)
+
+	case models.TransactionTypeInterest:
+		if txn.ToAccountID == nil {
+			return nil, fmt.Errorf("interest requires a destination account")
+		}
+		postings = append(postings,
+			systemPosting(models.SystemAccountInterestExpense, models.PostingDirectionDebit, txn.Amount, txn.Currency),
+			accountPosting(*txn.ToAccountID, models.PostingDirectionCredit, txn.Amount, txn.Currency),
+		)
+
+	case models.TransactionTypeRefund:
+		// Refunds send money back the way it came; a missing side is external cash
+		switch {
+		case txn.FromAccountID != nil && txn.ToAccountID != nil:
+			postings = append(postings,
+				accountPosting(*txn.FromAccountID, models.PostingDirectionDebit, txn.Amount, txn.Currency),
+				accountPosting(*txn.ToAccountID, models.PostingDirectionCredit, txn.Amount, txn.Currency),
+			)
+		case txn.FromAccountID != nil:
+			postings = append(postings,
+				accountPosting(*txn.FromAccountID, models.PostingDirectionDebit, txn.Amount, txn.Currency),
+				systemPosting(models.SystemAccountExternalCash, models.PostingDirectionCredit, txn.Amount, txn.Currency),
+			)
+		case txn.ToAccountID != nil:
+			postings = append(postings,
+				systemPosting(models.SystemAccountExternalCash, models.PostingDirectionDebit, txn.Amount, txn.Currency),
+				accountPosting(*txn.ToAccountID, models.PostingDirectionCredit, txn.Amount, txn.Currency),
+			)
+		default:
+			return nil, fmt.Errorf("refund requires at least one account")
+		}
+
+	default:
+		return nil, fmt.Errorf("cannot post transactions of type %s", txn.TransactionType)
+	}
+
+	// A fee charged on top of a payment is collected from the paying account
+	if txn.Fee.IsPositive() && txn.FromAccountID != nil && txn.TransactionType != models.TransactionTypeFee {
+		postings = append(postings,
+			accountPosting(*txn.FromAccountID, models.PostingDirectionDebit, txn.Fee, txn.Currency),
+			systemPosting(models.SystemAccountFeeIncome, models.PostingDirectionCredit, txn.Fee, txn.Currency),
+		)
+	}
+
+	return postings, nil
+}
+
+func accountPosting(accountID uuid.UUID, direction models.PostingDirection, amount decimal.Decimal, currency string) models.Posting {
+	id := accountID
+	return models.Posting{
+		ID:        uuid.New(),
+		AccountID: &id,
+		Direction: direction,
+		Amount:    amount,
+		Currency:  currency,
+	}
+}
+
+func systemPosting(code models.SystemAccountCode, direction models.PostingDirection, amount decimal.Decimal, currency string) models.Posting {
+	return models.Posting{
+		ID:            uuid.New(),
+		SystemAccount: code,
+		Direction:     direction,
+		Amount:        amount,
+		Currency:      currency,
+	}
+}
+
+func checkBalanced(postings []models.Posting) error {
+	if len(postings) < 2 {
+		return ErrUnbalancedEntry
+	}
+
+	totals := map[string]decimal.Decimal{}
+	for _, posting := range postings {
+		if !posting.Amount.IsPositive() {
+			return fmt.Errorf("posting amounts must be positive")
+		}
+		if posting.Direction == models.PostingDirectionDebit {
+			totals[posting.Currency] = totals[posting.Currency].Add(posting.Amount)
+		} else {
+			totals[posting.Currency] = totals[posting.Currency].Sub(posting.Amount)
+		}
+	}
+
+	for _, total := range totals {
+		if !total.IsZero() {
+			return ErrUnbalancedEntry
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+//go:build integration
+
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"financial-transaction-system/internal/dbtest"
+	"financial-transaction-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestPostingsMatchBalances moves money in and out of two accounts and
+// checks that each cached balance equals the balance derived from postings
+func TestPostingsMatchBalances(t *testing.T) {
+	env := newTestEnv(t, permissiveRules, StepUpPolicy{})
+	user := dbtest.CreateUser(t, env.database, models.UserRoleCustomer)
+	a := dbtest.CreateAccount(t, env.database, user.ID, models.AccountTypeChecking, "USD")
+	b := dbtest.CreateAccount(t, env.database, user.ID, models.AccountTypeSavings, "USD")
+
+	env.fund(t, user.ID, a.ID, "100.00")
+	if _, err := env.transactions.Withdrawal(user.ID, &models.WithdrawalRequest{
+		FromAccountID: a.ID,
+		Amount:        decimal.RequireFromString("30.00"),
+		Currency:      "USD",
+	}, nil); err != nil {
+		t.Fatalf("Withdrawal: %v", err)
+	}
+	if _, err := env.transactions.Transfer(user.ID, &models.TransferRequest{
+		FromAccountID: a.ID,
+		ToAccountID:   b.ID,
+		Amount:        decimal.RequireFromString("20.00"),
+		Currency:      "USD",
+	}, time.Now(), nil); err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	// An overdraft is refused without posting anything
+	_, err := env.transactions.Withdrawal(user.ID, &models.WithdrawalRequest{
+		FromAccountID: b.ID,
+		Amount:        decimal.RequireFromString("20.01"),
+		Currency:      "USD",
+	}, nil)
+	if !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("overdraft: got %v, want ErrInsufficientFunds", err)
+	}
+
+	for _, want := range []struct {
+		account *models.Account
+		balance string
+	}{{a, "50.00"}, {b, "20.00"}} {
+		ledgerBalance, err := env.ledger.GetLedgerBalance(want.account.ID)
+		if err != nil {
+			t.Fatalf("GetLedgerBalance: %v", err)
+		}
+		if !ledgerBalance.Equal(decimal.RequireFromString(want.balance)) {
+			t.Errorf("account %s: ledger balance %s, want %s", want.account.AccountNumber, ledgerBalance, want.balance)
+		}
+		if balance := env.balance(t, want.account.ID); !balance.Equal(ledgerBalance) {
+			t.Errorf("account %s: balance %s, ledger %s", want.account.AccountNumber, balance, ledgerBalance)
+		}
+	}
+	env.assertReconciled(t)
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// legs describes postings as "<account>:<direction>:<amount>", naming
+// customer accounts "from" and "to" and system accounts by their code
+func legs(postings []models.Posting, from, to uuid.UUID) []string {
+	var described []string
+	for _, posting := range postings {
+		account := string(posting.SystemAccount)
+		if posting.AccountID != nil {
+			switch *posting.AccountID {
+			case from:
+				account = "from"
+			case to:
+				account = "to"
+			default:
+				account = posting.AccountID.String()
+			}
+		}
+		described = append(described, account+":"+string(posting.Direction)+":"+posting.Amount.String())
+	}
+	return described
+}
+
+func TestBuildPostings(t *testing.T) {
+	from, to := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name     string
+		txnType  models.TransactionType
+		from, to bool
+		fee      string
+		want     []string
+	}{
+		{"transfer", models.TransactionTypeTransfer, true, true, "0",
+			[]string{"from:debit:10", "to:credit:10"}},
+		{"transfer with fee", models.TransactionTypeTransfer, true, true, "1.5",
+			[]string{"from:debit:10", "to:credit:10", "from:debit:1.5", "fee_income:credit:1.5"}},
+		{"deposit", models.TransactionTypeDeposit, false, true, "0",
+			[]string{"external_cash:debit:10", "to:credit:10"}},
+		{"withdrawal", models.TransactionTypeWithdrawal, true, false, "0",
+			[]string{"from:debit:10", "external_cash:credit:10"}},
+		{"fee", models.TransactionTypeFee, true, false, "10",
+			[]string{"from:debit:10", "fee_income:credit:10"}},
+		{"interest", models.TransactionTypeInterest, false, true, "0",
+			[]string{"interest_expense:debit:10", "to:credit:10"}},
+		{"refund of a transfer", models.TransactionTypeRefund, true, true, "0",
+			[]string{"from:debit:10", "to:credit:10"}},
+		{"refund of a deposit", models.TransactionTypeRefund, true, false, "0",
+			[]string{"from:debit:10", "external_cash:credit:10"}},
+		{"refund of a withdrawal", models.TransactionTypeRefund, false, true, "0",
+			[]string{"external_cash:debit:10", "to:credit:10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn := newTransaction(tt.txnType, decimal.NewFromInt(10), "usd", nil, nil)
+			txn.Fee = decimal.RequireFromString(tt.fee)
+			if tt.from {
+				txn.FromAccountID = &from
+			}
+			if tt.to {
+				txn.ToAccountID = &to
+			}
+
+			postings, err := buildPostings(txn)
+			if err != nil {
+				t.Fatalf("buildPostings: %v", err)
+			}
+			if got := legs(postings, from, to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("postings %v, want %v", got, tt.want)
+			}
+			for _, posting := range postings {
+				if posting.Currency != "USD" {
+					t.Errorf("posting in %s, want USD", posting.Currency)
+				}
+			}
+			if err := checkBalanced(postings); err != nil {
+				t.Errorf("checkBalanced: %v", err)
+			}
+		})
+	}
+}
+
+func TestBuildPostingsRequiresAccounts(t *testing.T) {
+	for _, txnType := range []models.TransactionType{
+		models.TransactionTypeTransfer,
+		models.TransactionTypeDeposit,
+		models.TransactionTypeWithdrawal,
+		models.TransactionTypeFee,
+		models.TransactionTypeInterest,
+		models.TransactionTypeRefund,
+	} {
+		txn := newTransaction(txnType, decimal.NewFromInt(10), "USD", nil, nil)
+		if _, err := buildPostings(txn); err == nil {
+			t.Errorf("%s without accounts: postings built", txnType)
+		}
+	}
+}
+
+func TestCheckBalanced(t *testing.T) {
+	account := uuid.New()
+	posting := func(direction models.PostingDirection, amount, currency string) models.Posting {
+		return accountPosting(account, direction, decimal.RequireFromString(amount), currency)
+	}
+
+	tests := []struct {
+		name     string
+		postings []models.Posting
+		balanced bool
+	}{
+		{"balanced", []models.Posting{
+			posting(models.PostingDirectionDebit, "10", "USD"),
+			posting(models.PostingDirectionCredit, "7.5", "USD"),
+			posting(models.PostingDirectionCredit, "2.5", "USD"),
+		}, true},
+		{"balanced per currency", []models.Posting{
+			posting(models.PostingDirectionDebit, "10", "USD"),
+			posting(models.PostingDirectionCredit, "10", "USD"),
+			posting(models.PostingDirectionDebit, "5", "EUR"),
+			posting(models.PostingDirectionCredit, "5", "EUR"),
+		}, true},
+		{"single leg", []models.Posting{
+			posting(models.PostingDirectionDebit, "10", "USD"),
+		}, false},
+		{"debits exceed credits", []models.Posting{
+			posting(models.PostingDirectionDebit, "10", "USD"),
+			posting(models.PostingDirectionCredit, "9.99", "USD"),
+		}, false},
+		{"balanced only across currencies", []models.Posting{
+			posting(models.PostingDirectionDebit, "10", "USD"),
+			posting(models.PostingDirectionCredit, "10", "EUR"),
+		}, false},
+		{"zero amounts", []models.Posting{
+			posting(models.PostingDirectionDebit, "0", "USD"),
+			posting(models.PostingDirectionCredit, "0", "USD"),
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBalanced(tt.postings)
+			if tt.balanced && err != nil {
+				t.Errorf("checkBalanced: %v", err)
+			}
+			if !tt.balanced && err == nil {
+				t.Error("unbalanced postings accepted")
+			}
+		})
+	}
+
+	err := checkBalanced([]models.Posting{
+		posting(models.PostingDirectionDebit, "10", "USD"),
+		posting(models.PostingDirectionCredit, "1", "USD"),
+	})
+	if !errors.Is(err, ErrUnbalancedEntry) {
+		t.Errorf("got %v, want ErrUnbalancedEntry", err)
+	}
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"financial-transaction-system/internal/auth"
+	"financial-transaction-system/internal/config"
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/mail"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrAccountLocked      = errors.New("account is temporarily locked after too many failed logins")
+	ErrLoginThrottled     = errors.New("too many failed logins from this address; try again later")
+)
+
+// LoginBlockedError is returned when a login is refused before the password
+// is checked, either because the account is locked or because the client IP
+// failed too often, and when a client IP asks for too many password resets.
+// RetryAt is when the request may be tried again.
+type LoginBlockedError struct {
+	Reason  error
+	RetryAt time.Time
+}
+
+func (e *LoginBlockedError) Error() string {
+	return e.Reason.Error()
+}
+
+func (e *LoginBlockedError) Is(target error) bool {
+	return target == e.Reason
+}
+
+// LoginProtectionPolicy limits password guessing. A zero MaxFailedAttempts
+// disables account lockout and a zero IPMaxFailures the per-IP limit.
+type LoginProtectionPolicy struct {
+	MaxFailedAttempts int
+	FailureWindow     time.Duration
+	LockoutBase       time.Duration
+	LockoutMax        time.Duration
+	IPMaxFailures     int
+	IPWindow          time.Duration
+}
+
+func LoginProtectionPolicyFromConfig(cfg config.LoginProtectionConfig) LoginProtectionPolicy {
+	return LoginProtectionPolicy{
+		MaxFailedAttempts: cfg.MaxFailedAttempts,
+		FailureWindow:     time.Duration(cfg.FailureWindowMinutes) * time.Minute,
+		LockoutBase:       time.Duration(cfg.LockoutBaseMinutes) * time.Minute,
+		LockoutMax:        time.Duration(cfg.LockoutMaxMinutes) * time.Minute,
+		IPMaxFailures:     cfg.IPMaxFailures,
+		IPWindow:          time.Duration(cfg.IPWindowMinutes) * time.Minute,
+	}
+}
+
+// lockoutDuration returns how long the nth lock of an account lasts: the base
+// duration, doubled for each earlier lock, up to the maximum
+func (p LoginProtectionPolicy) lockoutDuration(n int) time.Duration {
+	d := p.LockoutBase
+	for i := 1; i < n && d < p.LockoutMax; i++ {
+		d *= 2
+	}
+	if p.LockoutMax > 0 && d > p.LockoutMax {
+		d = p.LockoutMax
+	}
+	return d
+}
+
+// LoginProtectionService counts failed logins per account and per client IP.
+// Accounts are locked for a while after too many failures and their owner is
+// emailed; addresses that fail too often are refused before any password is
+// hashed. Failed logins, lockouts and unlocks are all audited.
+type LoginProtectionService struct {
+	db       *db.Database
+	userRepo *db.UserRepository
+	counter  auth.FailureCounter
+	mailer   mail.Mailer
+	audit    *AuditService
+	policy   LoginProtectionPolicy
+}
+
+func NewLoginProtectionService(database *db.Database, userRepo *db.UserRepository, counter auth.FailureCounter, mailer mail.Mailer, audit *AuditService, policy LoginProtectionPolicy) *LoginProtectionService {
+	return &LoginProtectionService{
+		db:       database,
+		userRepo: userRepo,
+		counter:  counter,
+		mailer:   mailer,
+		audit:    audit,
+		policy:   policy,
+	}
+}
+
+// CheckIP refuses logins from a client IP that failed too often recently
+func (s *LoginProtectionService) CheckIP(ctx context.Context, meta *models.RequestMeta) error {
+	key := ipFailureKey(meta)
+	if s.policy.IPMaxFailures <= 0 {
+		return nil
+	}
+
+	count, resetAt, err := s.counter.Count(ctx, key)
+	if err != nil {
+		return err
+	}
+	if count >= int64(s.policy.IPMaxFailures) {
+		return &LoginBlockedError{Reason: ErrLoginThrottled, RetryAt: resetAt}
+	}
+	return nil
+}
+
+// CheckAccount refuses logins to a locked account. The attempt is audited and
+// counts against the client IP, but not towards locking the account again.
+func (s *LoginProtectionService) CheckAccount(ctx context.Context, user *models.User, meta *models.RequestMeta) error {
+	state, err := s.userRepo.GetLoginState(user.ID)
+	if err != nil {
+		return err
+	}
+	if state.LockedUntil == nil || !state.LockedUntil.After(time.Now()) {
+		return nil
+	}
+
+	ipFailures, err := s.countIPFailure(ctx, meta)
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(meta, &models.CreateAuditLogRequest{
+		UserID:     &user.ID,
+		Action:     models.AuditActionLoginFailed,
+		EntityType: "user",
+		EntityID:   &user.ID,
+		Metadata: map[string]interface{}{
+			"reason":       "account_locked",
+			"locked_until": *state.LockedUntil,
+			"ip_failures":  ipFailures,
+		},
+	})
+
+	return &LoginBlockedError{Reason: ErrAccountLocked, RetryAt: *state.LockedUntil}
+}
+
+// RecordFailure counts a failed login for the email address and client IP.
+// user is nil when no active user has the address. When the failure locks the
+// account the owner is emailed and the lock is returned as the error.
+func (s *LoginProtectionService) RecordFailure(ctx context.Context, email string, user *models.User, meta *models.RequestMeta) error {
+	ipFailures, err := s.countIPFailure(ctx, meta)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		s.audit.Record(meta, &models.CreateAuditLogRequest{
+			Action:     models.AuditActionLoginFailed,
+			EntityType: "user",
+			Metadata: map[string]interface{}{
+				"reason":      "unknown_email",
+				"email":       email,
+				"ip_failures": ipFailures,
+			},
+		})
+		return nil
+	}
+
+	return s.recordAccountFailure(user, ipFailures, models.AuditActionLoginFailed, "invalid_password", meta)
+}
+
+// RecordMFAFailure counts a wrong second factor like a wrong password, for
+// both the account and the client IP, so knowing the password does not allow
+// unlimited guesses at the code
+func (s *LoginProtectionService) RecordMFAFailure(ctx context.Context, user *models.User, meta *models.RequestMeta) error {
+	ipFailures, err := s.countIPFailure(ctx, meta)
+	if err != nil {
+		return err
+	}
+	return s.recordAccountFailure(user, ipFailures, models.AuditActionMFAChallengeFailed, "invalid_mfa_code", meta)
+}
+
+// recordAccountFailure counts a failed login against a known account, audits
+// it as action and locks the account once it failed too often
+func (s *LoginProtectionService) recordAccountFailure(user *models.User, ipFailures int64, action models.AuditAction, reason string, meta *models.RequestMeta) error {
+	var state *models.LoginLockState
+	locked := false
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		current, err := s.userRepo.LockLoginStateTx(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		state = current
+
+		now := time.Now()
+		if state.FailureWindowStartedAt == nil || now.Sub(*state.FailureWindowStartedAt) >= s.policy.FailureWindow {
+			state.FailedAttempts = 0
+			state.FailureWindowStartedAt = &now
+		}
+		state.FailedAttempts++
+		state.LastFailedAt = &now
+
+		failedAttempts := state.FailedAttempts
+		if s.policy.MaxFailedAttempts > 0 && state.FailedAttempts >= s.policy.MaxFailedAttempts {
+			locked = true
+			lockedUntil := now.Add(s.policy.lockoutDuration(state.LockoutCount + 1))
+			state.LockoutCount++
+			state.LockedUntil = &lockedUntil
+			state.FailedAttempts = 0
+			state.FailureWindowStartedAt = nil
+		}
+
+		if err := s.userRepo.UpdateLoginStateTx(tx, state); err != nil {
+			return err
+		}
+
+		err = s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+			UserID:     &user.ID,
+			Action:     action,
+			EntityType: "user",
+			EntityID:   &user.ID,
+			Metadata: map[string]interface{}{
+				"reason":          reason,
+				"failed_attempts": failedAttempts,
+				"ip_failures":     ipFailures,
+			},
+		})
+		if err != nil || !locked {
+			return err
+		}
+
+		return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+			UserID:     &user.ID,
+			Action:     models.AuditActionUserLocked,
+			EntityType: "user",
+			EntityID:   &user.ID,
+			Metadata: map[string]interface{}{
+				"failed_attempts": failedAttempts,
+				"lockout_count":   state.LockoutCount,
+				"locked_until":    *state.LockedUntil,
+			},
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+
+	go s.notifyLocked(user, *state.LockedUntil)
+
+	return &LoginBlockedError{Reason: ErrAccountLocked, RetryAt: *state.LockedUntil}
+}
+
+// RecordSuccess forgets the account's failed logins once every factor has
+// been proven. Failures already counted against the client IP stand.
+func (s *LoginProtectionService) RecordSuccess(userID uuid.UUID) error {
+	_, err := s.userRepo.ClearLoginFailures(userID)
+	return err
+}
+
+// LockState returns a user's failed logins and whether the account is locked
+func (s *LoginProtectionService) LockState(userID uuid.UUID) (*models.LoginLockState, error) {
+	state, err := s.userRepo.GetLoginState(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	state.Locked = state.LockedUntil != nil && state.LockedUntil.After(time.Now())
+	return state, nil
+}
+
+// Unlock lifts a lock on behalf of an administrator and forgets the account's
+// failed logins, so the next lock starts again at the base duration
+func (s *LoginProtectionService) Unlock(actorID, userID uuid.UUID, meta *models.RequestMeta) (*models.LoginLockState, error) {
+	var state *models.LoginLockState
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		locked, err := s.userRepo.LockLoginStateTx(tx, userID)
+		if err != nil {
+			return ErrUserNotFound
+		}
+		state = locked
+
+		oldValues := map[string]interface{}{
+			"failed_attempts": state.FailedAttempts,
+			"lockout_count":   state.LockoutCount,
+			"locked_until":    state.LockedUntil,
+		}
+
+		state.FailedAttempts = 0
+		state.FailureWindowStartedAt = nil
+		state.LockoutCount = 0
+		state.LockedUntil = nil
+		if err := s.userRepo.UpdateLoginStateTx(tx, state); err != nil {
+			return err
+		}
+
+		return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+			UserID:     &actorID,
+			Action:     models.AuditActionUserUnlocked,
+			EntityType: "user",
+			EntityID:   &userID,
+			OldValues:  oldValues,
+			NewValues: map[string]interface{}{
+				"failed_attempts": 0,
+				"lockout_count":   0,
+				"locked_until":    nil,
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// countIPFailure counts a failure against the client IP and returns the
+// failures in its current window
+func (s *LoginProtectionService) countIPFailure(ctx context.Context, meta *models.RequestMeta) (int64, error) {
+	key := ipFailureKey(meta)
+	if s.policy.IPMaxFailures <= 0 {
+		return 0, nil
+	}
+	return s.counter.Increment(ctx, key, s.policy.IPWindow)
+}
+
+// notifyLocked tells the owner of an account that it was locked
+func (s *LoginProtectionService) notifyLocked(user *models.User, lockedUntil time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+	defer cancel()
+
+	err := s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Your account has been temporarily locked",
+		Body: fmt.Sprintf("Hello %s,\n\nAfter several failed attempts to sign in, your account has been locked until %s.\n\nIf these attempts were not yours, someone may be trying to guess your password. Once the lock ends, sign in and change it, or reset it from the sign-in page.\n",
+			user.FirstName, lockedUntil.UTC().Format(time.RFC1123)),
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to send account locked email")
+	}
+}
+
+// ipFailureKey names the failure counter of the request's client IP
+func ipFailureKey(meta *models.RequestMeta) string {
+	return clientIPKey("login:ip:", meta)
+}
+
+// clientIPKey names a per-client-IP counter. The address is the connection's
+// peer unless it came through a trusted proxy, so clients cannot pick it.
+// IPv6 clients usually hold a whole /64, so they are counted per /64, and
+// requests whose address is unknown share one counter rather than escaping
+// the limit.
+func clientIPKey(prefix string, meta *models.RequestMeta) string {
+	if meta == nil || meta.IPAddress == nil {
+		return prefix + "unknown"
+	}
+	if meta.IPAddress.To4() == nil {
+		return prefix + meta.IPAddress.Mask(net.CIDRMask(64, 128)).String() + "/64"
+	}
+	return prefix + meta.IPAddress.String()
+}
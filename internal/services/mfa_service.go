@@ -0,0 +1,271 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"financial-transaction-system/internal/auth"
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/models"
+	"financial-transaction-system/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// mfaRecoveryCodeCount is how many recovery codes a confirmed enrollment gets
+const mfaRecoveryCodeCount = 10
+
+const (
+	mfaMethodTOTP         = "totp"
+	mfaMethodRecoveryCode = "recovery_code"
+)
+
+var (
+	ErrMFAUnavailable    = errors.New("two-factor authentication is not configured on this server")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication enrollment has not been started")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrInvalidMFACode    = errors.New("invalid two-factor authentication code")
+	ErrInvalidMFAToken   = errors.New("invalid or expired mfa token")
+	ErrIncorrectPassword = errors.New("password is incorrect")
+)
+
+// MFAService manages TOTP two-factor authentication. Secrets are encrypted
+// at rest with cipher; without one, enrollment and verification are refused.
+type MFAService struct {
+	db       *db.Database
+	mfaRepo  *db.MFARepository
+	userRepo *db.UserRepository
+	cipher   *auth.SecretCipher
+	issuer   string
+	audit    *AuditService
+}
+
+func NewMFAService(database *db.Database, mfaRepo *db.MFARepository, userRepo *db.UserRepository, cipher *auth.SecretCipher, issuer string, audit *AuditService) *MFAService {
+	return &MFAService{
+		db:       database,
+		mfaRepo:  mfaRepo,
+		userRepo: userRepo,
+		cipher:   cipher,
+		issuer:   issuer,
+		audit:    audit,
+	}
+}
+
+// Enabled reports whether the user has a confirmed enrollment
+func (s *MFAService) Enabled(userID uuid.UUID) (bool, error) {
+	mfa, err := s.mfaRepo.GetByUserID(userID)
+	if err != nil {
+		if errors.Is(err, db.ErrMFANotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return mfa.EnabledAt != nil, nil
+}
+
+// Enroll generates a new secret for the user. It takes effect once Confirm
+// is called with a code from it; until then enrollment can be restarted.
+func (s *MFAService) Enroll(userID uuid.UUID) (*models.MFAEnrollmentResponse, error) {
+	if s.cipher == nil {
+		return nil, ErrMFAUnavailable
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := s.cipher.Seal([]byte(secret), userID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.SaveEnrollment(userID, ciphertext); err != nil {
+		if errors.Is(err, db.ErrMFAAlreadyEnabled) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return &models.MFAEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables two-factor authentication once the user proves their
+// authenticator works, and hands out recovery codes
+func (s *MFAService) Confirm(userID uuid.UUID, req *models.ConfirmMFARequest, meta *models.RequestMeta) (*models.MFARecoveryCodesResponse, error) {
+	if s.cipher == nil {
+		return nil, ErrMFAUnavailable
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(mfaRecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+
+	err = s.db.WithTransaction(func(tx *sql.Tx) error {
+		mfa, err := s.mfaRepo.LockForUpdateTx(tx, userID)
+		if err != nil {
+			if errors.Is(err, db.ErrMFANotFound) {
+				return ErrMFANotEnrolled
+			}
+			return err
+		}
+		if mfa.EnabledAt != nil {
+			return ErrMFAAlreadyEnabled
+		}
+
+		step, err := s.checkTOTP(mfa, req.Code)
+		if err != nil {
+			return err
+		}
+
+		if err := s.mfaRepo.EnableTx(tx, userID, step); err != nil {
+			return err
+		}
+		if err := s.mfaRepo.ReplaceRecoveryCodesTx(tx, userID, hashes); err != nil {
+			return err
+		}
+
+		return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+			UserID:     &userID,
+			Action:     models.AuditActionMFAEnabled,
+			EntityType: "user",
+			EntityID:   &userID,
+			OldValues:  map[string]interface{}{"mfa_enabled": false},
+			NewValues:  map[string]interface{}{"mfa_enabled": true},
+			Metadata:   map[string]interface{}{"method": mfaMethodTOTP},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable turns two-factor authentication off. It asks for the password and
+// a current code, so a stolen session alone cannot remove it.
+func (s *MFAService) Disable(userID uuid.UUID, req *models.DisableMFARequest, meta *models.RequestMeta) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if err := utils.VerifyPassword(user.PasswordHash, req.Password); err != nil {
+		return ErrIncorrectPassword
+	}
+
+	return s.db.WithTransaction(func(tx *sql.Tx) error {
+		method, err := s.verifyCodeTx(tx, userID, req.Code)
+		if err != nil {
+			return err
+		}
+
+		if err := s.mfaRepo.DeleteTx(tx, userID); err != nil {
+			return err
+		}
+
+		return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+			UserID:     &userID,
+			Action:     models.AuditActionMFADisabled,
+			EntityType: "user",
+			EntityID:   &userID,
+			OldValues:  map[string]interface{}{"mfa_enabled": true},
+			NewValues:  map[string]interface{}{"mfa_enabled": false},
+			Metadata:   map[string]interface{}{"mfa_method": method},
+		})
+	})
+}
+
+// VerifyCode checks the second factor of a login. It accepts a TOTP code or
+// an unused recovery code, spends it, and returns which kind it was.
+func (s *MFAService) VerifyCode(userID uuid.UUID, code string) (string, error) {
+	var method string
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		var err error
+		method, err = s.verifyCodeTx(tx, userID, code)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return method, nil
+}
+
+func (s *MFAService) verifyCodeTx(tx *sql.Tx, userID uuid.UUID, code string) (string, error) {
+	if s.cipher == nil {
+		return "", ErrMFAUnavailable
+	}
+
+	mfa, err := s.mfaRepo.LockForUpdateTx(tx, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrMFANotFound) {
+			return "", ErrMFANotEnabled
+		}
+		return "", err
+	}
+	if mfa.EnabledAt == nil {
+		return "", ErrMFANotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if isTOTPCode(code) {
+		step, err := s.checkTOTP(mfa, code)
+		if err != nil {
+			return "", err
+		}
+		if err := s.mfaRepo.UpdateLastUsedStepTx(tx, userID, step); err != nil {
+			return "", err
+		}
+		return mfaMethodTOTP, nil
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCodeTx(tx, userID, auth.HashRecoveryCode(code))
+	if err != nil {
+		return "", err
+	}
+	if !used {
+		return "", ErrInvalidMFACode
+	}
+	return mfaMethodRecoveryCode, nil
+}
+
+// checkTOTP validates a code against a locked enrollment. A code from a time
+// step at or before the last accepted one is refused, so codes cannot be replayed.
+func (s *MFAService) checkTOTP(mfa *models.UserMFA, code string) (int64, error) {
+	secret, err := s.cipher.Open(mfa.SecretCiphertext, mfa.UserID[:])
+	if err != nil {
+		return 0, err
+	}
+
+	step, ok := auth.ValidateTOTP(string(secret), strings.TrimSpace(code), time.Now())
+	if !ok || (mfa.LastUsedStep != nil && step <= *mfa.LastUsedStep) {
+		return 0, ErrInvalidMFACode
+	}
+	return step, nil
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
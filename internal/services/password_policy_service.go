@@ -0,0 +1,127 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/models"
+	"financial-transaction-system/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+var ErrWeakPassword = errors.New("password does not meet the requirements")
+
+// PasswordPolicyError lists every rule a new password breaks
+type PasswordPolicyError struct {
+	Violations []utils.PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return fmt.Sprintf("%s: %s", ErrWeakPassword, strings.Join(messages, "; "))
+}
+
+func (e *PasswordPolicyError) Is(target error) bool {
+	return target == ErrWeakPassword
+}
+
+// PasswordPolicyService applies the password policy to new passwords and
+// keeps the history that stops users from going back to a recent one.
+// historyCount includes the current password, which is read from users; the
+// ones before it are kept in password_history.
+type PasswordPolicyService struct {
+	historyRepo  *db.PasswordHistoryRepository
+	policy       *utils.PasswordPolicy
+	historyCount int
+}
+
+func NewPasswordPolicyService(historyRepo *db.PasswordHistoryRepository, policy *utils.PasswordPolicy, historyCount int) *PasswordPolicyService {
+	return &PasswordPolicyService{
+		historyRepo:  historyRepo,
+		policy:       policy,
+		historyCount: historyCount,
+	}
+}
+
+// Check applies the policy to the password of someone signing up
+func (s *PasswordPolicyService) Check(password string, owner utils.PasswordOwner) error {
+	violations, err := s.policy.Check(password, owner)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// checkChangeTx applies the policy to a user's new password, which also must
+// not be one of their latest passwords
+func (s *PasswordPolicyService) checkChangeTx(tx *sql.Tx, user *models.User, password string) error {
+	violations, err := s.policy.Check(password, passwordOwner(user))
+	if err != nil {
+		return err
+	}
+
+	reused, err := s.recentlyUsedTx(tx, user, password)
+	if err != nil {
+		return err
+	}
+	if reused {
+		violations = append(violations, utils.PasswordViolation{
+			Code:    utils.PasswordRecentlyUsed,
+			Message: fmt.Sprintf("must not be one of your last %d passwords", s.historyCount),
+		})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// recordChangeTx keeps the hash of the password a user just replaced
+func (s *PasswordPolicyService) recordChangeTx(tx *sql.Tx, userID uuid.UUID, previousHash string) error {
+	if s.historyCount <= 1 {
+		return nil
+	}
+	return s.historyRepo.AddTx(tx, userID, previousHash, s.historyCount-1)
+}
+
+func (s *PasswordPolicyService) recentlyUsedTx(tx *sql.Tx, user *models.User, password string) (bool, error) {
+	if s.historyCount <= 0 {
+		return false, nil
+	}
+	if utils.VerifyPassword(user.PasswordHash, password) == nil {
+		return true, nil
+	}
+	if s.historyCount == 1 {
+		return false, nil
+	}
+
+	hashes, err := s.historyRepo.ListRecentTx(tx, user.ID, s.historyCount-1)
+	if err != nil {
+		return false, err
+	}
+	for _, hash := range hashes {
+		if utils.VerifyPassword(hash, password) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func passwordOwner(user *models.User) utils.PasswordOwner {
+	return utils.PasswordOwner{
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	}
+}
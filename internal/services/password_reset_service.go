@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"financial-transaction-system/internal/auth"
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/mail"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	resetTokenBytes = 32
+	// resetPollInterval is how often the worker looks for queued requests it
+	// was not woken for, such as those queued by another instance
+	resetPollInterval = 5 * time.Second
+)
+
+var (
+	ErrInvalidResetToken      = errors.New("invalid or expired password reset token")
+	ErrPasswordResetThrottled = errors.New("too many password reset requests from this address; try again later")
+)
+
+// PasswordResetService lets users who forgot their password set a new one
+// through a single-use token emailed to them. Reset requests are queued in the
+// database and handled in the background, so the response to one is the same,
+// and takes the same time, whether or not the email belongs to a user, and a
+// burst of requests is worked through rather than dropped. Each client IP may
+// make ipMaxRequests requests per ipWindow.
+type PasswordResetService struct {
+	db              *db.Database
+	userRepo        *db.UserRepository
+	resetRepo       *db.PasswordResetRepository
+	users           *UserService
+	passwords       *PasswordPolicyService
+	mailer          mail.Mailer
+	audit           *AuditService
+	linkURL         string
+	tokenTTL        time.Duration
+	requestInterval time.Duration
+	limiter         auth.FailureCounter
+	ipMaxRequests   int
+	ipWindow        time.Duration
+	wake            chan struct{}
+}
+
+func NewPasswordResetService(database *db.Database, userRepo *db.UserRepository, resetRepo *db.PasswordResetRepository, users *UserService, passwords *PasswordPolicyService, mailer mail.Mailer, audit *AuditService, linkURL string, tokenTTL, requestInterval time.Duration, limiter auth.FailureCounter, ipMaxRequests int, ipWindow time.Duration) *PasswordResetService {
+	return &PasswordResetService{
+		db:              database,
+		userRepo:        userRepo,
+		resetRepo:       resetRepo,
+		users:           users,
+		passwords:       passwords,
+		mailer:          mailer,
+		audit:           audit,
+		linkURL:         linkURL,
+		tokenTTL:        tokenTTL,
+		requestInterval: requestInterval,
+		limiter:         limiter,
+		ipMaxRequests:   ipMaxRequests,
+		ipWindow:        ipWindow,
+		wake:            make(chan struct{}, 1),
+	}
+}
+
+// RequestReset queues a reset email for the address. It never reports
+// whether the address is registered; a client IP that asked too often is
+// refused with a LoginBlockedError.
+func (s *PasswordResetService) RequestReset(ctx context.Context, email string, meta *models.RequestMeta) error {
+	if err := s.checkIP(ctx, meta); err != nil {
+		return err
+	}
+
+	if err := s.resetRepo.EnqueueRequest(strings.TrimSpace(email)); err != nil {
+		return err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// ProcessRequests sends the reset emails queued by RequestReset. It runs until
+// the process exits; any number of instances can run it side by side.
+func (s *PasswordResetService) ProcessRequests() {
+	for {
+		processed, err := s.processNextRequest()
+		if err != nil {
+			logrus.WithError(err).Error("Failed to process password reset request")
+		}
+		if processed && err == nil {
+			continue
+		}
+
+		select {
+		case <-s.wake:
+		case <-time.After(resetPollInterval):
+		}
+	}
+}
+
+// processNextRequest handles the oldest queued request. The request is taken
+// off the queue before the email is sent, so that no transaction stays open
+// while the mail server answers; one claimed by an instance that stops
+// mid-send is lost, and the user can ask again. It reports false when the
+// queue is empty.
+func (s *PasswordResetService) processNextRequest() (bool, error) {
+	var email string
+	processed := false
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		claimed, ok, err := s.resetRepo.ClaimRequestTx(tx)
+		if err != nil || !ok {
+			return err
+		}
+		email, processed = claimed, true
+		return nil
+	})
+	if err != nil || !processed {
+		return processed, err
+	}
+
+	// A failed email is not retried: the token may already be stored, and
+	// the user can ask again
+	if err := s.sendReset(email); err != nil {
+		return true, fmt.Errorf("failed to send password reset email: %w", err)
+	}
+	return true, nil
+}
+
+// checkIP counts a reset request against the client IP and refuses it once
+// the IP made too many within the window
+func (s *PasswordResetService) checkIP(ctx context.Context, meta *models.RequestMeta) error {
+	if s.ipMaxRequests <= 0 {
+		return nil
+	}
+
+	key := clientIPKey("reset:ip:", meta)
+	count, err := s.limiter.Increment(ctx, key, s.ipWindow)
+	if err != nil {
+		return err
+	}
+	if count <= int64(s.ipMaxRequests) {
+		return nil
+	}
+
+	_, resetAt, err := s.limiter.Count(ctx, key)
+	if err != nil {
+		return err
+	}
+	return &LoginBlockedError{Reason: ErrPasswordResetThrottled, RetryAt: resetAt}
+}
+
+// sendReset emails a new token to the user with the address, if there is one
+// and they were not sent one within the request interval
+func (s *PasswordResetService) sendReset(email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, tokenHash, err := newResetToken()
+	if err != nil {
+		return err
+	}
+
+	created, err := s.resetRepo.CreateUnlessRecent(&models.PasswordResetToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(s.tokenTTL),
+	}, s.requestInterval)
+	if err != nil || !created {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+	defer cancel()
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    s.resetBody(user, token),
+	})
+}
+
+// ResetPassword sets a new password with an emailed token. The token and any
+// others outstanding for the user are spent, and all of the user's sessions
+// are ended.
+func (s *PasswordResetService) ResetPassword(ctx context.Context, req *models.ResetPasswordRequest, meta *models.RequestMeta) error {
+	var userID uuid.UUID
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		token, err := s.resetRepo.LockByHashTx(tx, hashResetToken(req.Token))
+		if err != nil {
+			if errors.Is(err, db.ErrPasswordResetTokenNotFound) {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+		if token.UsedAt != nil || !token.ExpiresAt.After(time.Now()) {
+			return ErrInvalidResetToken
+		}
+
+		user, err := s.userRepo.GetByID(token.UserID)
+		if err != nil || !user.IsActive {
+			return ErrInvalidResetToken
+		}
+
+		// A refused password leaves the token unspent for another try
+		if err := s.passwords.checkChangeTx(tx, user, req.NewPassword); err != nil {
+			return err
+		}
+
+		hashedPassword, err := s.users.hasher.Hash(req.NewPassword)
+		if err != nil {
+			return fmt.Errorf("failed to hash new password: %w", err)
+		}
+
+		if err := s.userRepo.UpdatePasswordTx(tx, user.ID, hashedPassword); err != nil {
+			return err
+		}
+		if err := s.passwords.recordChangeTx(tx, user.ID, user.PasswordHash); err != nil {
+			return err
+		}
+		if err := s.resetRepo.SpendAllForUserTx(tx, user.ID); err != nil {
+			return err
+		}
+		revoked, err := s.users.refreshRepo.RevokeAllForUserTx(tx, user.ID, "password_reset")
+		if err != nil {
+			return err
+		}
+		userID = user.ID
+
+		return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+			UserID:     &user.ID,
+			Action:     models.AuditActionPasswordChanged,
+			EntityType: "user",
+			EntityID:   &user.ID,
+			Metadata: map[string]interface{}{
+				"method":                 "reset_token",
+				"refresh_tokens_revoked": revoked,
+			},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	// The denylist lives outside the database, so access tokens are denied
+	// once the new password is committed. A failure cannot undo the reset;
+	// the user's access tokens then last until they expire.
+	if err := s.users.denyAccessTokens(ctx, userID); err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Error("Failed to revoke access tokens after password reset")
+	}
+	return nil
+}
+
+// PurgeExpiredTokens deletes reset tokens past their expiry
+func (s *PasswordResetService) PurgeExpiredTokens() (int64, error) {
+	return s.resetRepo.DeleteExpired()
+}
+
+func (s *PasswordResetService) resetBody(user *models.User, token string) string {
+	instruction := "Use this token to reset your password:"
+	proof := token
+	if s.linkURL != "" {
+		instruction = "Open this link to reset your password:"
+		proof = s.linkURL + "?" + url.Values{"token": {token}}.Encode()
+	}
+
+	minutes := int(s.tokenTTL.Minutes())
+	return fmt.Sprintf("Hello %s,\n\n%s\n\n%s\n\nIt expires in %d minutes and can be used once. If you did not ask to reset your password, ignore this email.\n",
+		user.FirstName, instruction, proof, minutes)
+}
+
+// newResetToken returns a random token and the hash it is stored under
+func newResetToken() (string, string, error) {
+	raw := make([]byte, resetTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate password reset token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashResetToken(token), nil
+}
+
+// hashResetToken returns the form a reset token is stored in. Tokens are
+// random, so a fast hash is enough.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// statementSettlementDelay is how long after a month ends its statement is
+// issued, so that transactions still committing at midnight are included
+const statementSettlementDelay = time.Hour
+
+// maxStatementPeriod bounds ad-hoc statements to keep them cheap to build
+const maxStatementPeriod = 366 * 24 * time.Hour
+
+var (
+	ErrInvalidStatementPeriod = errors.New("start_date must be before end_date and the period cannot exceed one year")
+	ErrStatementOutOfBalance  = errors.New("statement movements do not reconcile with the closing balance")
+)
+
+type StatementService struct {
+	db            *db.Database
+	accountRepo   *db.AccountRepository
+	txnRepo       *db.TransactionRepository
+	ledgerRepo    *db.LedgerRepository
+	statementRepo *db.StatementRepository
+}
+
+func NewStatementService(database *db.Database, accountRepo *db.AccountRepository, txnRepo *db.TransactionRepository, ledgerRepo *db.LedgerRepository, statementRepo *db.StatementRepository) *StatementService {
+	return &StatementService{
+		db:            database,
+		accountRepo:   accountRepo,
+		txnRepo:       txnRepo,
+		ledgerRepo:    ledgerRepo,
+		statementRepo: statementRepo,
+	}
+}
+
+// GetStatement returns the statement of an account for [start, end). A
+// request for a whole calendar month (UTC) that has ended is served from the
+// issued snapshot, which is created on first request and never changes.
+func (s *StatementService) GetStatement(userID, accountID uuid.UUID, start, end time.Time) (*models.TransactionStatement, error) {
+	start, end = start.UTC(), end.UTC()
+	if !start.Before(end) || end.Sub(start) > maxStatementPeriod {
+		return nil, ErrInvalidStatementPeriod
+	}
+
+	account, err := s.accountRepo.GetByID(accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrAccountNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	if account.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+
+	monthly := isClosedMonth(start, end, time.Now())
+	if monthly {
+		statement, err := s.statementRepo.GetByPeriod(account.ID, start, end)
+		if err == nil {
+			return statement, nil
+		}
+		if !errors.Is(err, db.ErrStatementNotFound) {
+			return nil, err
+		}
+	}
+
+	statement, err := s.buildStatement(account, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if monthly {
+		return s.statementRepo.Create(statement)
+	}
+	return statement, nil
+}
+
+// buildStatement reads the opening balance, the movements and the closing
+// balance from one snapshot so that concurrent postings cannot skew them
+func (s *StatementService) buildStatement(account *models.Account, start, end time.Time) (*models.TransactionStatement, error) {
+	statement := &models.TransactionStatement{
+		AccountID:     account.ID,
+		AccountNumber: account.AccountNumber,
+		StartDate:     start,
+		EndDate:       end,
+		Transactions:  []models.TransactionResponse{},
+		Summary: models.TransactionSummary{
+			TotalCredits: decimal.Zero,
+			TotalDebits:  decimal.Zero,
+			TotalFees:    decimal.Zero,
+		},
+	}
+
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := s.db.WithTransactionOptions(opts, func(tx *sql.Tx) error {
+		opening, err := s.ledgerRepo.GetAccountBalanceAtTx(tx, account.ID, start)
+		if err != nil {
+			return err
+		}
+
+		movements, err := s.ledgerRepo.GetAccountMovementsTx(tx, account.ID, start, end)
+		if err != nil {
+			return err
+		}
+
+		closing, err := s.ledgerRepo.GetAccountBalanceAtTx(tx, account.ID, end)
+		if err != nil {
+			return err
+		}
+
+		ids := []uuid.UUID{}
+		for _, m := range movements {
+			if m.TransactionID != nil {
+				ids = append(ids, *m.TransactionID)
+			}
+		}
+		transactions, err := s.txnRepo.GetByIDsTx(tx, ids)
+		if err != nil {
+			return err
+		}
+
+		statement.OpeningBalance = opening
+		statement.ClosingBalance = closing
+
+		listed := map[uuid.UUID]bool{}
+		for _, m := range movements {
+			var txn *models.Transaction
+			if m.TransactionID != nil {
+				txn = transactions[*m.TransactionID]
+			}
+
+			fees := movementFees(account.ID, m, txn)
+			statement.Summary.TotalCredits = statement.Summary.TotalCredits.Add(m.Credits)
+			statement.Summary.TotalDebits = statement.Summary.TotalDebits.Add(m.Debits.Sub(fees))
+			statement.Summary.TotalFees = statement.Summary.TotalFees.Add(fees)
+
+			if txn != nil && !listed[txn.ID] {
+				listed[txn.ID] = true
+				statement.Transactions = append(statement.Transactions, *toTransactionResponse(txn))
+			}
+		}
+		statement.Summary.TransactionCount = len(statement.Transactions)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	expected := statement.OpeningBalance.
+		Add(statement.Summary.TotalCredits).
+		Sub(statement.Summary.TotalDebits).
+		Sub(statement.Summary.TotalFees)
+	if !expected.Equal(statement.ClosingBalance) {
+		return nil, fmt.Errorf("%w: account %s, expected %s, ledger %s",
+			ErrStatementOutOfBalance, account.AccountNumber, expected, statement.ClosingBalance)
+	}
+
+	return statement, nil
+}
+
+// movementFees is the part of a movement's debits that is a fee: all of a
+// fee transaction, or the fee charged on top of a payment by this account
+func movementFees(accountID uuid.UUID, m models.AccountMovement, txn *models.Transaction) decimal.Decimal {
+	if txn == nil || m.EntryType != models.JournalEntryTypeTransaction {
+		return decimal.Zero
+	}
+	if txn.TransactionType == models.TransactionTypeFee {
+		return m.Debits
+	}
+	if txn.FromAccountID != nil && *txn.FromAccountID == accountID && txn.Fee.IsPositive() {
+		return decimal.Min(txn.Fee, m.Debits)
+	}
+	return decimal.Zero
+}
+
+// isClosedMonth reports whether [start, end) is exactly one UTC calendar
+// month that ended long enough ago to be issued
+func isClosedMonth(start, end, now time.Time) bool {
+	monthStart := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if !start.Equal(monthStart) || !end.Equal(monthStart.AddDate(0, 1, 0)) {
+		return false
+	}
+	return !now.Before(end.Add(statementSettlementDelay))
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/models"
+	"financial-transaction-system/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// stepUpExpiryBatch caps how many expired challenges one sweep closes
+const stepUpExpiryBatch = 100
+
+var (
+	ErrStepUpChallengeNotFound = errors.New("step-up challenge not found")
+	ErrStepUpChallengeClosed   = errors.New("step-up challenge is no longer pending")
+	ErrStepUpChallengeExpired  = errors.New("step-up challenge has expired; the transaction was cancelled")
+	ErrStepUpAttemptsExceeded  = errors.New("too many failed step-up attempts; the transaction was cancelled")
+)
+
+// StepUpService answers the challenges that hold risky transfers. Users with
+// two-factor authentication prove a TOTP or recovery code, everyone else
+// their password. Too many wrong answers or an expired challenge cancel the
+// transfer and release its hold.
+type StepUpService struct {
+	db           *db.Database
+	stepUpRepo   *db.StepUpRepository
+	userRepo     *db.UserRepository
+	fraudRepo    *db.FraudAlertRepository
+	mfa          *MFAService
+	transactions *TransactionService
+	audit        *AuditService
+	maxAttempts  int
+}
+
+func NewStepUpService(database *db.Database, stepUpRepo *db.StepUpRepository, userRepo *db.UserRepository, fraudRepo *db.FraudAlertRepository, mfa *MFAService, transactions *TransactionService, audit *AuditService, maxAttempts int) *StepUpService {
+	return &StepUpService{
+		db:           database,
+		stepUpRepo:   stepUpRepo,
+		userRepo:     userRepo,
+		fraudRepo:    fraudRepo,
+		mfa:          mfa,
+		transactions: transactions,
+		audit:        audit,
+		maxAttempts:  maxAttempts,
+	}
+}
+
+// Confirm answers a challenge. On success the transfer is posted, unless
+// fraud alerts raised for it are still open, in which case it stays held
+// until an analyst clears them.
+func (s *StepUpService) Confirm(userID, challengeID uuid.UUID, req *models.ConfirmStepUpRequest, meta *models.RequestMeta) (*models.TransactionResponse, error) {
+	mfaEnabled, err := s.mfa.Enabled(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var challenge *models.StepUpChallenge
+	var txn *models.Transaction
+	var outcome error
+	err = s.db.WithTransaction(func(tx *sql.Tx) error {
+		locked, err := s.stepUpRepo.LockForUpdateTx(tx, challengeID)
+		if err != nil {
+			if errors.Is(err, db.ErrStepUpChallengeNotFound) {
+				return ErrStepUpChallengeNotFound
+			}
+			return err
+		}
+		challenge = locked
+
+		if challenge.UserID != userID {
+			return ErrStepUpChallengeNotFound
+		}
+		if challenge.Status != models.StepUpStatusPending {
+			return ErrStepUpChallengeClosed
+		}
+
+		txn, err = s.transactions.lockTransaction(tx, challenge.TransactionID)
+		if err != nil {
+			return err
+		}
+
+		// The transfer was settled some other way, e.g. rejected as fraud
+		if txn.Status != models.TransactionStatusPending {
+			outcome = ErrStepUpChallengeClosed
+			return s.stepUpRepo.CompleteTx(tx, challenge, models.StepUpStatusExpired)
+		}
+
+		if !challenge.ExpiresAt.After(time.Now()) {
+			outcome = ErrStepUpChallengeExpired
+			return s.expireTx(tx, challenge, txn, meta)
+		}
+
+		method, err := s.verifyProofTx(tx, userID, mfaEnabled, req)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidMFACode) && !errors.Is(err, ErrIncorrectPassword) {
+				return err
+			}
+			outcome = err
+			if err := s.recordFailedAttemptTx(tx, challenge, txn, meta); err != nil {
+				return err
+			}
+			if challenge.Status == models.StepUpStatusFailed {
+				outcome = ErrStepUpAttemptsExceeded
+			}
+			return nil
+		}
+
+		if err := s.stepUpRepo.CompleteTx(tx, challenge, models.StepUpStatusConfirmed); err != nil {
+			return err
+		}
+
+		total, cleared, err := s.fraudRepo.CountByTransactionTx(tx, txn.ID)
+		if err != nil {
+			return err
+		}
+		if cleared == total {
+			if err := s.transactions.releaseHeldTx(tx, txn, userID, "Step-up authentication confirmed"); err != nil {
+				return err
+			}
+		}
+
+		return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+			UserID:        &userID,
+			AccountID:     primaryAccountID(txn),
+			TransactionID: &txn.ID,
+			Action:        models.AuditActionStepUpConfirmed,
+			EntityType:    "step_up_challenge",
+			EntityID:      &challenge.ID,
+			Metadata: map[string]interface{}{
+				"method":             method,
+				"reasons":            challenge.Reasons,
+				"transaction_status": txn.Status,
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if outcome != nil {
+		return nil, outcome
+	}
+
+	response := toTransactionResponse(txn)
+	response.StepUp = challenge
+	return response, nil
+}
+
+// ExpireChallenges cancels the transfers of challenges that were not answered
+// in time and returns how many were closed
+func (s *StepUpService) ExpireChallenges() (int, error) {
+	ids, err := s.stepUpRepo.ListExpiredIDs(stepUpExpiryBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		closed := false
+		err := s.db.WithTransaction(func(tx *sql.Tx) error {
+			challenge, err := s.stepUpRepo.LockForUpdateTx(tx, id)
+			if err != nil {
+				return err
+			}
+			if challenge.Status != models.StepUpStatusPending || challenge.ExpiresAt.After(time.Now()) {
+				return nil
+			}
+
+			txn, err := s.transactions.lockTransaction(tx, challenge.TransactionID)
+			if err != nil {
+				return err
+			}
+
+			closed = true
+			return s.expireTx(tx, challenge, txn, nil)
+		})
+		if err != nil {
+			logrus.WithError(err).WithField("challenge_id", id).Error("Failed to expire step-up challenge")
+			continue
+		}
+		if closed {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// verifyProofTx checks the second factor a challenge is answered with and
+// returns which kind it was
+func (s *StepUpService) verifyProofTx(tx *sql.Tx, userID uuid.UUID, mfaEnabled bool, req *models.ConfirmStepUpRequest) (string, error) {
+	if mfaEnabled {
+		return s.mfa.verifyCodeTx(tx, userID, req.Code)
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return "", ErrUserNotFound
+	}
+	if err := utils.VerifyPassword(user.PasswordHash, req.Password); err != nil {
+		return "", ErrIncorrectPassword
+	}
+	return "password", nil
+}
+
+// recordFailedAttemptTx counts a wrong answer and cancels the transfer once
+// the attempts are used up
+func (s *StepUpService) recordFailedAttemptTx(tx *sql.Tx, challenge *models.StepUpChallenge, txn *models.Transaction, meta *models.RequestMeta) error {
+	if err := s.stepUpRepo.IncrementAttemptsTx(tx, challenge); err != nil {
+		return err
+	}
+
+	if challenge.Attempts >= s.maxAttempts {
+		if err := s.stepUpRepo.CompleteTx(tx, challenge, models.StepUpStatusFailed); err != nil {
+			return err
+		}
+		if err := s.transactions.cancelHeldTx(tx, txn, &challenge.UserID, "Step-up authentication failed"); err != nil {
+			return err
+		}
+	}
+
+	return s.auditFailureTx(tx, challenge, txn, meta)
+}
+
+// expireTx closes an unanswered challenge and cancels its transfer
+func (s *StepUpService) expireTx(tx *sql.Tx, challenge *models.StepUpChallenge, txn *models.Transaction, meta *models.RequestMeta) error {
+	if err := s.stepUpRepo.CompleteTx(tx, challenge, models.StepUpStatusExpired); err != nil {
+		return err
+	}
+	if txn.Status == models.TransactionStatusPending {
+		if err := s.transactions.cancelHeldTx(tx, txn, nil, "Step-up authentication expired"); err != nil {
+			return err
+		}
+	}
+
+	return s.auditFailureTx(tx, challenge, txn, meta)
+}
+
+func (s *StepUpService) auditFailureTx(tx *sql.Tx, challenge *models.StepUpChallenge, txn *models.Transaction, meta *models.RequestMeta) error {
+	return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+		UserID:        &challenge.UserID,
+		AccountID:     primaryAccountID(txn),
+		TransactionID: &txn.ID,
+		Action:        models.AuditActionStepUpFailed,
+		EntityType:    "step_up_challenge",
+		EntityID:      &challenge.ID,
+		Metadata: map[string]interface{}{
+			"challenge_status":   challenge.Status,
+			"attempts":           challenge.Attempts,
+			"transaction_status": txn.Status,
+		},
+	})
+}
@@ -0,0 +1,78 @@
+//go:build integration
+
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"financial-transaction-system/internal/dbtest"
+	"financial-transaction-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestConcurrentOppositeTransfers fires transfers in both directions between
+// two accounts at once. Accounts are locked in a fixed order, so none of them
+// may deadlock, and no money may be created, destroyed or overdrawn.
+func TestConcurrentOppositeTransfers(t *testing.T) {
+	const workers = 40
+	env := newTestEnv(t, permissiveRules, StepUpPolicy{})
+	user := dbtest.CreateUser(t, env.database, models.UserRoleCustomer)
+	a := dbtest.CreateAccount(t, env.database, user.ID, models.AccountTypeChecking, "USD")
+	b := dbtest.CreateAccount(t, env.database, user.ID, models.AccountTypeChecking, "USD")
+	env.fund(t, user.ID, a.ID, "1000.00")
+	env.fund(t, user.ID, b.ID, "1000.00")
+
+	amount := decimal.RequireFromString("75.00")
+	transfer := func(from, to *models.Account) error {
+		_, err := env.transactions.Transfer(user.ID, &models.TransferRequest{
+			FromAccountID: from.ID,
+			ToAccountID:   to.ID,
+			Amount:        amount,
+			Currency:      "USD",
+		}, time.Now(), nil)
+		return err
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	completed, refused := 0, 0
+	for i := 0; i < workers; i++ {
+		for _, pair := range [][2]*models.Account{{a, b}, {b, a}} {
+			wg.Add(1)
+			go func(from, to *models.Account) {
+				defer wg.Done()
+				err := transfer(from, to)
+
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					completed++
+				case errors.Is(err, ErrInsufficientFunds):
+					refused++
+				default:
+					t.Errorf("transfer failed: %v", err)
+				}
+			}(pair[0], pair[1])
+		}
+	}
+	wg.Wait()
+
+	if completed == 0 {
+		t.Error("no transfer completed")
+	}
+	t.Logf("%d transfers completed, %d refused for insufficient funds", completed, refused)
+
+	balanceA, balanceB := env.balance(t, a.ID), env.balance(t, b.ID)
+	if balanceA.IsNegative() || balanceB.IsNegative() {
+		t.Errorf("balances went negative: %s and %s", balanceA, balanceB)
+	}
+	if total := balanceA.Add(balanceB); !total.Equal(decimal.RequireFromString("2000.00")) {
+		t.Errorf("total balance is %s, want 2000.00", total)
+	}
+	env.assertReconciled(t)
+}
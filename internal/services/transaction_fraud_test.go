@@ -0,0 +1,135 @@
+//go:build integration
+
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"financial-transaction-system/internal/dbtest"
+	"financial-transaction-system/internal/fraud"
+	"financial-transaction-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// fraudFixture funds a with 600.00 under rules that review transactions over
+// 300.00 and block them from 600.00
+type fraudFixture struct {
+	env     *testEnv
+	user    *models.User
+	analyst *models.User
+	a, b    *models.Account
+}
+
+func newFraudFixture(t *testing.T) *fraudFixture {
+	t.Helper()
+
+	env := newTestEnv(t, fraud.Rules{MaxTransactionAmount: decimal.NewFromInt(300)}, StepUpPolicy{})
+	user := dbtest.CreateUser(t, env.database, models.UserRoleCustomer)
+	f := &fraudFixture{
+		env:     env,
+		user:    user,
+		analyst: dbtest.CreateUser(t, env.database, models.UserRoleFraudAnalyst),
+		a:       dbtest.CreateAccount(t, env.database, user.ID, models.AccountTypeChecking, "USD"),
+		b:       dbtest.CreateAccount(t, env.database, user.ID, models.AccountTypeChecking, "USD"),
+	}
+	env.fund(t, user.ID, f.a.ID, "300.00")
+	env.fund(t, user.ID, f.a.ID, "300.00")
+	return f
+}
+
+func (f *fraudFixture) transfer(amount string) (*models.TransactionResponse, error) {
+	return f.env.transactions.Transfer(f.user.ID, &models.TransferRequest{
+		FromAccountID: f.a.ID,
+		ToAccountID:   f.b.ID,
+		Amount:        decimal.RequireFromString(amount),
+		Currency:      "USD",
+	}, time.Now(), nil)
+}
+
+func (f *fraudFixture) assertAvailable(t *testing.T, want string) {
+	t.Helper()
+
+	account, err := f.env.accountRepo.GetByID(f.a.ID)
+	if err != nil {
+		t.Fatalf("failed to get account: %v", err)
+	}
+	if !account.AvailableBalance.Equal(decimal.RequireFromString(want)) {
+		t.Errorf("available balance %s, want %s", account.AvailableBalance, want)
+	}
+}
+
+func TestReviewedTransferIsHeld(t *testing.T) {
+	f := newFraudFixture(t)
+
+	response, err := f.transfer("400.00")
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	if response.Status != models.TransactionStatusPending {
+		t.Errorf("status %s, want %s", response.Status, models.TransactionStatusPending)
+	}
+	if response.StepUp == nil || !containsReason(response.StepUp.Reasons, models.StepUpReasonFraudReview) {
+		t.Errorf("step-up %+v, want a challenge for the fraud review", response.StepUp)
+	}
+
+	// The money is held, not moved
+	f.env.assertBalance(t, f.a.ID, "600.00")
+	f.assertAvailable(t, "200.00")
+	f.env.assertBalance(t, f.b.ID, "0.00")
+
+	alerts := f.env.alerts(t, response.ID)
+	if len(alerts) != 1 || alerts[0].RuleName != fraud.RuleTransactionAmount || alerts[0].Status != models.FraudStatusOpen {
+		t.Fatalf("alerts %+v, want one open %s alert", alerts, fraud.RuleTransactionAmount)
+	}
+
+	// A held amount cannot be spent twice
+	if _, err := f.transfer("250.00"); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("spending the held amount: got %v, want ErrInsufficientFunds", err)
+	}
+
+	// Confirmed fraud cancels the transfer and lifts the hold
+	f.env.closeAlert(t, f.analyst.ID, alerts[0].ID, models.FraudStatusResolved)
+	if status := f.env.transactionStatus(t, response.ID); status != models.TransactionStatusCancelled {
+		t.Errorf("status after confirmed fraud %s, want %s", status, models.TransactionStatusCancelled)
+	}
+	f.env.assertBalance(t, f.a.ID, "600.00")
+	f.assertAvailable(t, "600.00")
+	f.env.assertReconciled(t)
+}
+
+func TestBlockedTransferFails(t *testing.T) {
+	f := newFraudFixture(t)
+
+	_, err := f.transfer("600.00")
+	if !errors.Is(err, ErrTransactionBlocked) {
+		t.Fatalf("Transfer: got %v, want ErrTransactionBlocked", err)
+	}
+
+	// The blocked transfer is kept, failed, with its alert for investigation
+	alerts, err := f.env.fraudRepo.List(&models.FraudAlertFilter{AccountID: &f.a.ID}, 100, 0)
+	if err != nil {
+		t.Fatalf("failed to list fraud alerts: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].TransactionID == nil {
+		t.Fatalf("alerts %+v, want one for the blocked transfer", alerts)
+	}
+	if status := f.env.transactionStatus(t, *alerts[0].TransactionID); status != models.TransactionStatusFailed {
+		t.Errorf("status %s, want %s", status, models.TransactionStatusFailed)
+	}
+
+	f.env.assertBalance(t, f.a.ID, "600.00")
+	f.assertAvailable(t, "600.00")
+	f.env.assertBalance(t, f.b.ID, "0.00")
+}
+
+func containsReason(reasons []string, reason string) bool {
+	for _, r := range reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
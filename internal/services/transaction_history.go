@@ -0,0 +1,186 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var (
+	ErrInvalidTransactionFilter = errors.New("invalid transaction filter")
+	ErrInvalidCursor            = errors.New("invalid cursor")
+)
+
+// ListAccountTransactions returns one page of an account's transaction history.
+// A cursor from a previous page selects keyset paging, which stays stable
+// while new transactions arrive; otherwise page and page_size are used.
+func (s *TransactionService) ListAccountTransactions(userID, accountID uuid.UUID, filter *models.TransactionFilter) (*models.TransactionHistory, error) {
+	account, err := s.accountRepo.GetByID(accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrAccountNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	if account.UserID != userID {
+		return nil, ErrAccountNotFound
+	}
+
+	filter.AccountID = &account.ID
+	if err := normalizeTransactionFilter(filter); err != nil {
+		return nil, err
+	}
+
+	pagination := models.PaginationResponse{PageSize: filter.PageSize}
+	var transactions []*models.Transaction
+
+	if filter.Cursor != nil {
+		after, err := decodeTransactionCursor(*filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		transactions, err = s.txnRepo.List(filter, after, filter.PageSize+1, 0)
+		if err != nil {
+			return nil, err
+		}
+		pagination.HasPrevious = true
+	} else {
+		total, err := s.txnRepo.Count(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		offset := (filter.Page - 1) * filter.PageSize
+		transactions, err = s.txnRepo.List(filter, nil, filter.PageSize+1, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		pagination = offsetPagination(filter.Page, filter.PageSize, total)
+	}
+
+	if len(transactions) > filter.PageSize {
+		transactions = transactions[:filter.PageSize]
+		pagination.HasNext = true
+
+		last := transactions[len(transactions)-1]
+		pagination.NextCursor = encodeTransactionCursor(&models.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	history := &models.TransactionHistory{
+		Transactions: make([]models.TransactionResponse, 0, len(transactions)),
+		Pagination:   pagination,
+	}
+	for _, txn := range transactions {
+		history.Transactions = append(history.Transactions, *toTransactionResponse(txn))
+	}
+
+	return history, nil
+}
+
+// offsetPagination describes a page of an offset-paged listing; HasNext is
+// left for the caller, which knows whether another row was found
+func offsetPagination(page, pageSize int, total int64) models.PaginationResponse {
+	return models.PaginationResponse{
+		Page:         page,
+		PageSize:     pageSize,
+		TotalPages:   int((total + int64(pageSize) - 1) / int64(pageSize)),
+		TotalRecords: total,
+		HasPrevious:  page > 1,
+	}
+}
+
+// normalizeTransactionFilter validates the filter and fills in paging defaults
+func normalizeTransactionFilter(filter *models.TransactionFilter) error {
+	if filter.TransactionType != nil {
+		switch *filter.TransactionType {
+		case models.TransactionTypeTransfer, models.TransactionTypeDeposit, models.TransactionTypeWithdrawal,
+			models.TransactionTypeFee, models.TransactionTypeInterest, models.TransactionTypeRefund:
+		default:
+			return fmt.Errorf("%w: unknown transaction type %q", ErrInvalidTransactionFilter, *filter.TransactionType)
+		}
+	}
+
+	if filter.Status != nil {
+		switch *filter.Status {
+		case models.TransactionStatusPending, models.TransactionStatusProcessing, models.TransactionStatusCompleted,
+			models.TransactionStatusFailed, models.TransactionStatusCancelled, models.TransactionStatusReversed:
+		default:
+			return fmt.Errorf("%w: unknown status %q", ErrInvalidTransactionFilter, *filter.Status)
+		}
+	}
+
+	if filter.StartDate != nil && filter.EndDate != nil && !filter.StartDate.Before(*filter.EndDate) {
+		return fmt.Errorf("%w: start_date must be before end_date", ErrInvalidTransactionFilter)
+	}
+
+	if filter.MinAmount != nil && filter.MinAmount.IsNegative() {
+		return fmt.Errorf("%w: min_amount cannot be negative", ErrInvalidTransactionFilter)
+	}
+	if filter.MaxAmount != nil && filter.MaxAmount.IsNegative() {
+		return fmt.Errorf("%w: max_amount cannot be negative", ErrInvalidTransactionFilter)
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && filter.MinAmount.GreaterThan(*filter.MaxAmount) {
+		return fmt.Errorf("%w: min_amount cannot exceed max_amount", ErrInvalidTransactionFilter)
+	}
+
+	if filter.Currency != nil {
+		currency := strings.ToUpper(*filter.Currency)
+		if err := validateCurrency(currency); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTransactionFilter, err)
+		}
+		filter.Currency = &currency
+	}
+
+	if filter.Cursor != nil && filter.Page != 0 {
+		return fmt.Errorf("%w: page cannot be combined with cursor", ErrInvalidTransactionFilter)
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.Page < 1 {
+		return fmt.Errorf("%w: page must be at least 1", ErrInvalidTransactionFilter)
+	}
+
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+	if filter.PageSize < 1 || filter.PageSize > maxPageSize {
+		return fmt.Errorf("%w: page_size must be between 1 and %d", ErrInvalidTransactionFilter, maxPageSize)
+	}
+
+	return nil
+}
+
+// encodeTransactionCursor turns a keyset position into an opaque token
+func encodeTransactionCursor(cursor *models.TransactionCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTransactionCursor(token string) (*models.TransactionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor models.TransactionCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == uuid.Nil || cursor.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,159 @@
+//go:build integration
+
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"financial-transaction-system/internal/dbtest"
+	"financial-transaction-system/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// reversalFixture is a completed 100.00 transfer from a to b, with a left at
+// 400.00, and an admin to undo it
+type reversalFixture struct {
+	env      *testEnv
+	admin    *models.User
+	a, b     *models.Account
+	transfer *models.TransactionResponse
+}
+
+func newReversalFixture(t *testing.T) *reversalFixture {
+	t.Helper()
+
+	env := newTestEnv(t, permissiveRules, StepUpPolicy{})
+	user := dbtest.CreateUser(t, env.database, models.UserRoleCustomer)
+	f := &reversalFixture{
+		env:   env,
+		admin: dbtest.CreateUser(t, env.database, models.UserRoleAdmin),
+		a:     dbtest.CreateAccount(t, env.database, user.ID, models.AccountTypeChecking, "USD"),
+		b:     dbtest.CreateAccount(t, env.database, user.ID, models.AccountTypeChecking, "USD"),
+	}
+	env.fund(t, user.ID, f.a.ID, "500.00")
+	f.transfer = env.transfer(t, user.ID, f.a.ID, f.b.ID, "100.00")
+	return f
+}
+
+func (f *reversalFixture) refund(amount string) (*models.TransactionResponse, error) {
+	return f.env.transactions.RefundTransaction(f.admin.ID, f.transfer.ID, &models.RefundTransactionRequest{
+		Amount: decimal.RequireFromString(amount),
+		Reason: "Customer dispute",
+	}, nil)
+}
+
+func (f *reversalFixture) reverse() (*models.TransactionResponse, error) {
+	return f.env.transactions.ReverseTransaction(f.admin.ID, f.transfer.ID, &models.ReverseTransactionRequest{
+		Reason: "Sent in error",
+	}, nil)
+}
+
+func TestReverseTransaction(t *testing.T) {
+	f := newReversalFixture(t)
+
+	response, err := f.reverse()
+	if err != nil {
+		t.Fatalf("ReverseTransaction: %v", err)
+	}
+	if response.Status != models.TransactionStatusReversed {
+		t.Errorf("status %s, want %s", response.Status, models.TransactionStatusReversed)
+	}
+	f.env.assertBalance(t, f.a.ID, "500.00")
+	f.env.assertBalance(t, f.b.ID, "0.00")
+
+	if _, err := f.reverse(); !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Errorf("second reversal: got %v, want ErrInvalidStatusTransition", err)
+	}
+	if _, err := f.refund("10.00"); !errors.Is(err, ErrTransactionNotFinal) {
+		t.Errorf("refund of a reversed transaction: got %v, want ErrTransactionNotFinal", err)
+	}
+	f.env.assertReconciled(t)
+}
+
+func TestReverseTransactionNeedsTheFunds(t *testing.T) {
+	f := newReversalFixture(t)
+	f.env.transfer(t, f.b.UserID, f.b.ID, f.a.ID, "50.00")
+
+	if _, err := f.reverse(); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("reversal after the money was spent: got %v, want ErrInsufficientFunds", err)
+	}
+	f.env.assertBalance(t, f.a.ID, "450.00")
+	f.env.assertBalance(t, f.b.ID, "50.00")
+}
+
+func TestRefundTransaction(t *testing.T) {
+	f := newReversalFixture(t)
+
+	refund, err := f.refund("40.00")
+	if err != nil {
+		t.Fatalf("RefundTransaction: %v", err)
+	}
+	if refund.TransactionType != models.TransactionTypeRefund || refund.Status != models.TransactionStatusCompleted {
+		t.Errorf("refund is a %s %s, want a completed refund", refund.Status, refund.TransactionType)
+	}
+	f.env.assertBalance(t, f.a.ID, "440.00")
+	f.env.assertBalance(t, f.b.ID, "60.00")
+
+	if _, err := f.refund("60.01"); !errors.Is(err, ErrRefundExceedsAmount) {
+		t.Errorf("refund beyond the original: got %v, want ErrRefundExceedsAmount", err)
+	}
+	if _, err := f.reverse(); !errors.Is(err, ErrTransactionRefunded) {
+		t.Errorf("reversal of a refunded transaction: got %v, want ErrTransactionRefunded", err)
+	}
+
+	if _, err := f.refund("60.00"); err != nil {
+		t.Fatalf("refund of the remainder: %v", err)
+	}
+	f.env.assertBalance(t, f.a.ID, "500.00")
+	f.env.assertBalance(t, f.b.ID, "0.00")
+	f.env.assertReconciled(t)
+}
+
+func TestConcurrentRefundsStayWithinTheAmount(t *testing.T) {
+	f := newReversalFixture(t)
+
+	var wg sync.WaitGroup
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := f.refund("30.00")
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	refunded := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			refunded++
+		case !errors.Is(err, ErrRefundExceedsAmount):
+			t.Errorf("refund failed: %v", err)
+		}
+	}
+	if refunded != 3 {
+		t.Errorf("%d refunds of 30.00 went through against 100.00, want 3", refunded)
+	}
+	f.env.assertBalance(t, f.b.ID, "10.00")
+	f.env.assertReconciled(t)
+}
+
+func TestRefundNeedsActiveAccounts(t *testing.T) {
+	for _, status := range []models.AccountStatus{models.AccountStatusSuspended, models.AccountStatusClosed} {
+		t.Run(string(status), func(t *testing.T) {
+			f := newReversalFixture(t)
+			dbtest.SetAccountStatus(t, f.env.database, f.a.ID, status)
+
+			if _, err := f.refund("10.00"); !errors.Is(err, ErrAccountNotActive) {
+				t.Errorf("refund into a %s account: got %v, want ErrAccountNotActive", status, err)
+			}
+			f.env.assertBalance(t, f.b.ID, "100.00")
+		})
+	}
+}
@@ -0,0 +1,776 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/fraud"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrTransactionNotFound  = errors.New("transaction not found")
+	ErrInvalidAmount        = errors.New("amount must be greater than zero with at most 2 decimal places")
+	ErrSameAccount          = errors.New("source and destination accounts must be different")
+	ErrAccountNotActive     = errors.New("account is not active")
+	ErrCurrencyMismatch     = errors.New("transaction currency does not match account currency")
+	ErrInsufficientFunds    = errors.New("insufficient funds")
+	ErrDailyLimitExceeded   = errors.New("daily limit exceeded")
+	ErrMonthlyLimitExceeded = errors.New("monthly limit exceeded")
+	ErrTransactionNotFinal  = errors.New("only completed transactions can be refunded")
+	ErrTransactionRefunded  = errors.New("transaction has refunds; refund the remaining amount instead of reversing")
+	ErrRefundNotAllowed     = errors.New("transactions of this type cannot be refunded")
+	ErrRefundExceedsAmount  = errors.New("cumulative refunds cannot exceed the original amount")
+	ErrReasonRequired       = errors.New("a reason is required")
+	ErrTransactionBlocked   = errors.New("transaction blocked by fraud controls")
+)
+
+type TransactionService struct {
+	db          *db.Database
+	accountRepo *db.AccountRepository
+	txnRepo     *db.TransactionRepository
+	ledger      *LedgerService
+	audit       *AuditService
+	fraud       *fraud.Engine
+	fraudRepo   *db.FraudAlertRepository
+	stepUpRepo  *db.StepUpRepository
+	stepUp      StepUpPolicy
+}
+
+func NewTransactionService(database *db.Database, accountRepo *db.AccountRepository, txnRepo *db.TransactionRepository, ledger *LedgerService, audit *AuditService, fraudEngine *fraud.Engine, fraudRepo *db.FraudAlertRepository, stepUpRepo *db.StepUpRepository, stepUp StepUpPolicy) *TransactionService {
+	return &TransactionService{
+		db:          database,
+		accountRepo: accountRepo,
+		txnRepo:     txnRepo,
+		ledger:      ledger,
+		audit:       audit,
+		fraud:       fraudEngine,
+		fraudRepo:   fraudRepo,
+		stepUpRepo:  stepUpRepo,
+		stepUp:      stepUp,
+	}
+}
+
+// Transfer moves money between two accounts. The source account must belong to the user.
+// authTime is when the user last presented their credentials; transfers the
+// step-up policy considers risky are held until the user does so again, unless
+// that was recent.
+func (s *TransactionService) Transfer(userID uuid.UUID, req *models.TransferRequest, authTime time.Time, meta *models.RequestMeta) (*models.TransactionResponse, error) {
+	if err := validateAmount(req.Amount, req.Currency); err != nil {
+		return nil, err
+	}
+	if req.FromAccountID == req.ToAccountID {
+		return nil, ErrSameAccount
+	}
+
+	fromID, toID := req.FromAccountID, req.ToAccountID
+	txn := newTransaction(models.TransactionTypeTransfer, req.Amount, req.Currency, req.Description, req.ReferenceNumber)
+	txn.FromAccountID = &fromID
+	txn.ToAccountID = &toID
+
+	var from, to *models.Account
+	var challenge *models.StepUpChallenge
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		accounts, err := s.lockAccounts(tx, fromID, toID)
+		if err != nil {
+			return err
+		}
+		from, to = accounts[fromID], accounts[toID]
+
+		if from.UserID != userID {
+			return ErrAccountNotFound
+		}
+		if err := checkAccountUsable(from, txn.Currency); err != nil {
+			return err
+		}
+		if err := checkAccountUsable(to, txn.Currency); err != nil {
+			return err
+		}
+		if err := s.checkDebit(tx, from, txn.Amount); err != nil {
+			return err
+		}
+
+		check, err := s.transferStepUpCheckTx(tx, txn, userID, to, authTime)
+		if err != nil {
+			return err
+		}
+
+		challenge, err = s.screenAndPostTx(tx, txn, userID, from, check, meta)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if txn.Status == models.TransactionStatusFailed {
+		return nil, fmt.Errorf("%w: %s", ErrTransactionBlocked, txn.TransactionNumber)
+	}
+
+	applyDebit(from, txn)
+	response := toTransactionResponse(txn)
+	response.StepUp = challenge
+	fromSummary := toAccountSummary(from)
+	response.FromAccount = &fromSummary
+	if to.UserID == userID {
+		applyCredit(to, txn)
+		toSummary := toAccountSummary(to)
+		response.ToAccount = &toSummary
+	}
+
+	return response, nil
+}
+
+// Deposit credits cash into one of the user's accounts.
+func (s *TransactionService) Deposit(userID uuid.UUID, req *models.DepositRequest, meta *models.RequestMeta) (*models.TransactionResponse, error) {
+	if err := validateAmount(req.Amount, req.Currency); err != nil {
+		return nil, err
+	}
+
+	toID := req.ToAccountID
+	txn := newTransaction(models.TransactionTypeDeposit, req.Amount, req.Currency, req.Description, req.ReferenceNumber)
+	txn.ToAccountID = &toID
+
+	var to *models.Account
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		accounts, err := s.lockAccounts(tx, toID)
+		if err != nil {
+			return err
+		}
+		to = accounts[toID]
+
+		if to.UserID != userID {
+			return ErrAccountNotFound
+		}
+		if err := checkAccountUsable(to, txn.Currency); err != nil {
+			return err
+		}
+
+		_, err = s.screenAndPostTx(tx, txn, userID, to, stepUpCheck{}, meta)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if txn.Status == models.TransactionStatusFailed {
+		return nil, fmt.Errorf("%w: %s", ErrTransactionBlocked, txn.TransactionNumber)
+	}
+
+	applyCredit(to, txn)
+	response := toTransactionResponse(txn)
+	toSummary := toAccountSummary(to)
+	response.ToAccount = &toSummary
+
+	return response, nil
+}
+
+// Withdrawal debits cash from one of the user's accounts.
+func (s *TransactionService) Withdrawal(userID uuid.UUID, req *models.WithdrawalRequest, meta *models.RequestMeta) (*models.TransactionResponse, error) {
+	if err := validateAmount(req.Amount, req.Currency); err != nil {
+		return nil, err
+	}
+
+	fromID := req.FromAccountID
+	txn := newTransaction(models.TransactionTypeWithdrawal, req.Amount, req.Currency, req.Description, req.ReferenceNumber)
+	txn.FromAccountID = &fromID
+
+	var from *models.Account
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		accounts, err := s.lockAccounts(tx, fromID)
+		if err != nil {
+			return err
+		}
+		from = accounts[fromID]
+
+		if from.UserID != userID {
+			return ErrAccountNotFound
+		}
+		if err := checkAccountUsable(from, txn.Currency); err != nil {
+			return err
+		}
+		if err := s.checkDebit(tx, from, txn.Amount); err != nil {
+			return err
+		}
+
+		_, err = s.screenAndPostTx(tx, txn, userID, from, stepUpCheck{}, meta)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if txn.Status == models.TransactionStatusFailed {
+		return nil, fmt.Errorf("%w: %s", ErrTransactionBlocked, txn.TransactionNumber)
+	}
+
+	applyDebit(from, txn)
+	response := toTransactionResponse(txn)
+	fromSummary := toAccountSummary(from)
+	response.FromAccount = &fromSummary
+
+	return response, nil
+}
+
+// GetTransaction returns a transaction visible to the user through one of its accounts.
+func (s *TransactionService) GetTransaction(userID, transactionID uuid.UUID) (*models.TransactionResponse, error) {
+	txn, err := s.txnRepo.GetByID(transactionID)
+	if err != nil {
+		if errors.Is(err, db.ErrTransactionNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, err
+	}
+
+	response := toTransactionResponse(txn)
+	visible := false
+
+	if txn.FromAccountID != nil {
+		if from, err := s.accountRepo.GetByID(*txn.FromAccountID); err == nil && from.UserID == userID {
+			summary := toAccountSummary(from)
+			response.FromAccount = &summary
+			visible = true
+		}
+	}
+	if txn.ToAccountID != nil {
+		if to, err := s.accountRepo.GetByID(*txn.ToAccountID); err == nil && to.UserID == userID {
+			summary := toAccountSummary(to)
+			response.ToAccount = &summary
+			visible = true
+		}
+	}
+
+	if !visible {
+		return nil, ErrTransactionNotFound
+	}
+
+	return response, nil
+}
+
+// GetStatusHistory returns the status transitions of a transaction visible to the user, oldest first.
+func (s *TransactionService) GetStatusHistory(userID, transactionID uuid.UUID) ([]models.TransactionStatusHistory, error) {
+	if _, err := s.GetTransaction(userID, transactionID); err != nil {
+		return nil, err
+	}
+	return s.txnRepo.GetStatusHistory(transactionID)
+}
+
+// ReverseTransaction fully reverses a completed transaction by posting a
+// compensating journal entry and marking the original as reversed.
+func (s *TransactionService) ReverseTransaction(actorID, transactionID uuid.UUID, req *models.ReverseTransactionRequest, meta *models.RequestMeta) (*models.TransactionResponse, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+
+	var txn *models.Transaction
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		original, err := s.lockTransaction(tx, transactionID)
+		if err != nil {
+			return err
+		}
+		txn = original
+
+		if err := checkTransition(txn.Status, models.TransactionStatusReversed); err != nil {
+			return err
+		}
+
+		refunded, err := s.txnRepo.SumRefundsTx(tx, txn.ID)
+		if err != nil {
+			return err
+		}
+		if refunded.IsPositive() {
+			return ErrTransactionRefunded
+		}
+
+		if _, err := s.lockAccounts(tx, transactionAccountIDs(txn)...); err != nil {
+			return err
+		}
+
+		if _, err := s.ledger.ReverseTransactionTx(tx, txn, reason); err != nil {
+			return mapBalanceError(err)
+		}
+
+		previous := txn.Status
+		if err := s.transitionTx(tx, txn, models.TransactionStatusReversed, &actorID, reason); err != nil {
+			return err
+		}
+
+		return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+			UserID:        &actorID,
+			AccountID:     primaryAccountID(txn),
+			TransactionID: &txn.ID,
+			Action:        models.AuditActionTransactionReversed,
+			EntityType:    "transaction",
+			EntityID:      &txn.ID,
+			OldValues:     map[string]interface{}{"status": previous},
+			NewValues:     map[string]interface{}{"status": models.TransactionStatusReversed},
+			Description:   &reason,
+			Metadata:      map[string]interface{}{"amount": txn.Amount, "currency": txn.Currency},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toTransactionResponse(txn), nil
+}
+
+// RefundTransaction sends part or all of a completed transaction back to the
+// payer as a new refund transaction linked to the original.
+func (s *TransactionService) RefundTransaction(actorID, transactionID uuid.UUID, req *models.RefundTransactionRequest, meta *models.RequestMeta) (*models.TransactionResponse, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	if !req.Amount.IsPositive() || !req.Amount.Equal(req.Amount.Round(2)) {
+		return nil, ErrInvalidAmount
+	}
+
+	var refund *models.Transaction
+	err := s.db.WithTransaction(func(tx *sql.Tx) error {
+		original, err := s.lockTransaction(tx, transactionID)
+		if err != nil {
+			return err
+		}
+
+		if original.Status != models.TransactionStatusCompleted {
+			return ErrTransactionNotFinal
+		}
+
+		switch original.TransactionType {
+		case models.TransactionTypeTransfer, models.TransactionTypeDeposit, models.TransactionTypeWithdrawal:
+		default:
+			return ErrRefundNotAllowed
+		}
+
+		refunded, err := s.txnRepo.SumRefundsTx(tx, original.ID)
+		if err != nil {
+			return err
+		}
+		if refunded.Add(req.Amount).GreaterThan(original.Amount) {
+			return ErrRefundExceedsAmount
+		}
+
+		description := fmt.Sprintf("Refund of %s: %s", original.TransactionNumber, reason)
+		refund = newTransaction(models.TransactionTypeRefund, req.Amount, original.Currency, &description, nil)
+		refund.FromAccountID = original.ToAccountID
+		refund.ToAccountID = original.FromAccountID
+		refund.OriginalTransactionID = &original.ID
+
+		accounts, err := s.lockAccounts(tx, transactionAccountIDs(refund)...)
+		if err != nil {
+			return err
+		}
+		for _, id := range transactionAccountIDs(refund) {
+			if err := checkAccountUsable(accounts[id], refund.Currency); err != nil {
+				return err
+			}
+		}
+
+		if err := s.createAndPostTx(tx, refund, &actorID); err != nil {
+			return mapBalanceError(err)
+		}
+
+		totalRefunded := refunded.Add(req.Amount)
+		err = s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+			UserID:        &actorID,
+			AccountID:     primaryAccountID(original),
+			TransactionID: &original.ID,
+			Action:        models.AuditActionTransactionRefunded,
+			EntityType:    "transaction",
+			EntityID:      &original.ID,
+			OldValues:     map[string]interface{}{"refunded_amount": refunded},
+			NewValues:     map[string]interface{}{"refunded_amount": totalRefunded},
+			Description:   &reason,
+			Metadata: map[string]interface{}{
+				"refund_transaction_id": refund.ID,
+				"refund_amount":         req.Amount,
+				"currency":              original.Currency,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+			UserID:        &actorID,
+			AccountID:     primaryAccountID(refund),
+			TransactionID: &refund.ID,
+			Action:        models.AuditActionTransactionCreated,
+			EntityType:    "transaction",
+			EntityID:      &refund.ID,
+			NewValues:     refund,
+			Description:   &description,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toTransactionResponse(refund), nil
+}
+
+func (s *TransactionService) lockTransaction(tx *sql.Tx, transactionID uuid.UUID) (*models.Transaction, error) {
+	txn, err := s.txnRepo.LockForUpdateTx(tx, transactionID)
+	if err != nil {
+		if errors.Is(err, db.ErrTransactionNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, err
+	}
+	return txn, nil
+}
+
+// createAndPostTx stores the transaction as pending and posts it straight away
+func (s *TransactionService) createAndPostTx(tx *sql.Tx, txn *models.Transaction, actorID *uuid.UUID) error {
+	if err := s.recordCreatedTx(tx, txn, actorID, ""); err != nil {
+		return err
+	}
+	return s.postPendingTx(tx, txn, actorID, "")
+}
+
+// postPendingTx walks a pending transaction through processing to completed
+// around posting its journal entry, which also moves the cached balances of
+// the accounts involved
+func (s *TransactionService) postPendingTx(tx *sql.Tx, txn *models.Transaction, actorID *uuid.UUID, reason string) error {
+	if err := s.transitionTx(tx, txn, models.TransactionStatusProcessing, actorID, reason); err != nil {
+		return err
+	}
+	if _, err := s.ledger.PostTransactionTx(tx, txn); err != nil {
+		return err
+	}
+	return s.transitionTx(tx, txn, models.TransactionStatusCompleted, actorID, reason)
+}
+
+// screenAndPostTx runs the fraud rules against a customer transaction before
+// it is posted. A transaction held for review stays pending with the debit
+// held against the available balance; a blocked one is recorded as failed
+// and committed so the alert can be investigated. subject is the account
+// whose activity the rules look at. The transaction is audited in the same
+// database transaction whatever the outcome.
+//
+// A transaction that needs step-up authentication is held the same way and
+// the challenge the user has to answer is returned.
+func (s *TransactionService) screenAndPostTx(tx *sql.Tx, txn *models.Transaction, userID uuid.UUID, subject *models.Account, check stepUpCheck, meta *models.RequestMeta) (*models.StepUpChallenge, error) {
+	evaluation, err := s.fraud.EvaluateTx(tx, txn, subject.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	reasons := check.reasons
+	if check.onReview && evaluation.Decision == fraud.DecisionReview {
+		reasons = append(reasons, models.StepUpReasonFraudReview)
+	}
+	held := evaluation.Decision == fraud.DecisionReview ||
+		(evaluation.Decision == fraud.DecisionAllow && len(reasons) > 0)
+
+	switch {
+	case evaluation.Decision == fraud.DecisionBlock:
+		if err := s.recordCreatedTx(tx, txn, &userID, ""); err != nil {
+			return nil, err
+		}
+		if err := s.transitionTx(tx, txn, models.TransactionStatusFailed, nil, "Blocked by fraud rules"); err != nil {
+			return nil, err
+		}
+	case held:
+		if err := s.recordCreatedTx(tx, txn, &userID, ""); err != nil {
+			return nil, err
+		}
+		if txn.FromAccountID != nil {
+			if err := s.accountRepo.AdjustAvailableBalanceTx(tx, *txn.FromAccountID, txn.Amount.Neg()); err != nil {
+				return nil, mapBalanceError(err)
+			}
+		}
+	default:
+		if err := s.createAndPostTx(tx, txn, &userID); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+		UserID:        &userID,
+		AccountID:     &subject.ID,
+		TransactionID: &txn.ID,
+		Action:        models.AuditActionTransactionCreated,
+		EntityType:    "transaction",
+		EntityID:      &txn.ID,
+		NewValues:     txn,
+		Description:   txn.Description,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if evaluation.Decision != fraud.DecisionAllow {
+		if err := s.raiseFraudAlertsTx(tx, txn, userID, subject.ID, evaluation, meta); err != nil {
+			return nil, err
+		}
+	}
+
+	if evaluation.Decision == fraud.DecisionBlock || len(reasons) == 0 {
+		return nil, nil
+	}
+	return s.requireStepUpTx(tx, txn, userID, subject.ID, reasons, meta)
+}
+
+// releaseHeldTx lifts the hold on a transaction cleared by fraud review and
+// posts it. The accounts may have been suspended or closed, or their limits
+// used up, while it was held, so the checks it passed when it was made are run
+// again; a transaction that no longer passes them fails instead of posting.
+func (s *TransactionService) releaseHeldTx(tx *sql.Tx, txn *models.Transaction, actorID uuid.UUID, reason string) error {
+	accounts, err := s.lockAccounts(tx, transactionAccountIDs(txn)...)
+	if err != nil {
+		return err
+	}
+	if err := s.liftHoldTx(tx, txn); err != nil {
+		return err
+	}
+
+	if err := s.recheckHeldTx(tx, txn, accounts); err != nil {
+		if !isReleaseCheckError(err) {
+			return err
+		}
+		return s.transitionTx(tx, txn, models.TransactionStatusFailed, &actorID, fmt.Sprintf("%s; not posted: %s", reason, err))
+	}
+
+	return mapBalanceError(s.postPendingTx(tx, txn, &actorID, reason))
+}
+
+// recheckHeldTx runs the account checks a held transaction passed when it was
+// made against the accounts as they are now
+func (s *TransactionService) recheckHeldTx(tx *sql.Tx, txn *models.Transaction, accounts map[uuid.UUID]*models.Account) error {
+	for _, id := range transactionAccountIDs(txn) {
+		if err := checkAccountUsable(accounts[id], txn.Currency); err != nil {
+			return err
+		}
+	}
+	if txn.FromAccountID == nil {
+		return nil
+	}
+	return s.checkLimitsTx(tx, accounts[*txn.FromAccountID], txn.Amount, txn.ID)
+}
+
+// isReleaseCheckError reports whether recheckHeldTx refused a transaction, as
+// opposed to failing to check it
+func isReleaseCheckError(err error) bool {
+	return errors.Is(err, ErrAccountNotActive) ||
+		errors.Is(err, ErrCurrencyMismatch) ||
+		errors.Is(err, ErrDailyLimitExceeded) ||
+		errors.Is(err, ErrMonthlyLimitExceeded)
+}
+
+// rejectHeldTx lifts the hold on a transaction confirmed as fraud and cancels it
+func (s *TransactionService) rejectHeldTx(tx *sql.Tx, txn *models.Transaction, actorID uuid.UUID, reason string) error {
+	return s.cancelHeldTx(tx, txn, &actorID, reason)
+}
+
+// cancelHeldTx lifts the hold on a pending transaction and cancels it. actorID
+// is nil when the system cancels it, such as for an expired step-up challenge.
+func (s *TransactionService) cancelHeldTx(tx *sql.Tx, txn *models.Transaction, actorID *uuid.UUID, reason string) error {
+	if _, err := s.lockAccounts(tx, transactionAccountIDs(txn)...); err != nil {
+		return err
+	}
+	if err := s.liftHoldTx(tx, txn); err != nil {
+		return err
+	}
+	return s.transitionTx(tx, txn, models.TransactionStatusCancelled, actorID, reason)
+}
+
+// liftHoldTx returns a held debit to the payer's available balance
+func (s *TransactionService) liftHoldTx(tx *sql.Tx, txn *models.Transaction) error {
+	if txn.FromAccountID == nil {
+		return nil
+	}
+	return s.accountRepo.AdjustAvailableBalanceTx(tx, *txn.FromAccountID, txn.Amount)
+}
+
+// raiseFraudAlertsTx opens an alert for each rule the transaction tripped
+// and audits the detection
+func (s *TransactionService) raiseFraudAlertsTx(tx *sql.Tx, txn *models.Transaction, userID, accountID uuid.UUID, evaluation *fraud.Evaluation, meta *models.RequestMeta) error {
+	alertIDs := []uuid.UUID{}
+	rules := []string{}
+	for _, finding := range evaluation.Findings {
+		details := map[string]interface{}{"decision": evaluation.Decision}
+		for key, value := range finding.Details {
+			details[key] = value
+		}
+
+		alert, err := s.fraudRepo.CreateTx(tx, &models.CreateFraudAlertRequest{
+			UserID:        userID,
+			AccountID:     accountID,
+			TransactionID: &txn.ID,
+			RuleName:      finding.RuleName,
+			Severity:      finding.Severity,
+			RiskScore:     finding.RiskScore,
+			Description:   finding.Description,
+			Details:       details,
+		})
+		if err != nil {
+			return err
+		}
+		alertIDs = append(alertIDs, alert.ID)
+		rules = append(rules, finding.RuleName)
+	}
+
+	description := fmt.Sprintf("Fraud rules returned %s for %s", evaluation.Decision, txn.TransactionNumber)
+	return s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+		UserID:        &userID,
+		AccountID:     &accountID,
+		TransactionID: &txn.ID,
+		Action:        models.AuditActionFraudDetected,
+		EntityType:    "transaction",
+		EntityID:      &txn.ID,
+		NewValues:     map[string]interface{}{"status": txn.Status},
+		Description:   &description,
+		Metadata: map[string]interface{}{
+			"decision":   evaluation.Decision,
+			"risk_score": evaluation.RiskScore,
+			"rules":      rules,
+			"alert_ids":  alertIDs,
+		},
+	})
+}
+
+func (s *TransactionService) lockAccounts(tx *sql.Tx, ids ...uuid.UUID) (map[uuid.UUID]*models.Account, error) {
+	accounts, err := s.accountRepo.LockForUpdateTx(tx, ids...)
+	if err != nil {
+		if errors.Is(err, db.ErrAccountNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// checkDebit verifies that a locked account can fund the amount and stays within its limits
+func (s *TransactionService) checkDebit(tx *sql.Tx, account *models.Account, amount decimal.Decimal) error {
+	if account.AvailableBalance.LessThan(amount) {
+		return ErrInsufficientFunds
+	}
+	return s.checkLimitsTx(tx, account, amount, uuid.Nil)
+}
+
+// checkLimitsTx verifies that debiting the amount keeps a locked account within
+// its daily and monthly limits. excludeID is a held transaction being
+// released, which is already part of the account's outgoing total.
+func (s *TransactionService) checkLimitsTx(tx *sql.Tx, account *models.Account, amount decimal.Decimal, excludeID uuid.UUID) error {
+	now := time.Now().UTC()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	daily, err := s.txnRepo.SumOutgoingTx(tx, account.ID, startOfDay, excludeID)
+	if err != nil {
+		return err
+	}
+	if daily.Add(amount).GreaterThan(account.DailyLimit) {
+		return ErrDailyLimitExceeded
+	}
+
+	monthly, err := s.txnRepo.SumOutgoingTx(tx, account.ID, startOfMonth, excludeID)
+	if err != nil {
+		return err
+	}
+	if monthly.Add(amount).GreaterThan(account.MonthlyLimit) {
+		return ErrMonthlyLimitExceeded
+	}
+
+	return nil
+}
+
+// applyDebit mirrors on a loaded account what a transaction did to it: a
+// completed debit moves both balances, a held one only the available balance
+func applyDebit(account *models.Account, txn *models.Transaction) {
+	switch txn.Status {
+	case models.TransactionStatusCompleted:
+		account.Balance = account.Balance.Sub(txn.Amount)
+		account.AvailableBalance = account.AvailableBalance.Sub(txn.Amount)
+	case models.TransactionStatusPending:
+		account.AvailableBalance = account.AvailableBalance.Sub(txn.Amount)
+	}
+}
+
+// applyCredit mirrors a credit on a loaded account; pending credits are not yet available
+func applyCredit(account *models.Account, txn *models.Transaction) {
+	if txn.Status == models.TransactionStatusCompleted {
+		account.Balance = account.Balance.Add(txn.Amount)
+		account.AvailableBalance = account.AvailableBalance.Add(txn.Amount)
+	}
+}
+
+// mapBalanceError turns a violated non-negative balance constraint into ErrInsufficientFunds
+func mapBalanceError(err error) error {
+	if errors.Is(err, db.ErrNegativeBalance) {
+		return ErrInsufficientFunds
+	}
+	return err
+}
+
+func transactionAccountIDs(txn *models.Transaction) []uuid.UUID {
+	ids := []uuid.UUID{}
+	if txn.FromAccountID != nil {
+		ids = append(ids, *txn.FromAccountID)
+	}
+	if txn.ToAccountID != nil {
+		ids = append(ids, *txn.ToAccountID)
+	}
+	return ids
+}
+
+// primaryAccountID picks the account an audit entry is filed under: the payer if there is one
+func primaryAccountID(txn *models.Transaction) *uuid.UUID {
+	if txn.FromAccountID != nil {
+		return txn.FromAccountID
+	}
+	return txn.ToAccountID
+}
+
+func checkAccountUsable(account *models.Account, currency string) error {
+	if account.Status != models.AccountStatusActive {
+		return fmt.Errorf("%w: %s", ErrAccountNotActive, account.AccountNumber)
+	}
+	if account.Currency != currency {
+		return ErrCurrencyMismatch
+	}
+	return nil
+}
+
+func validateAmount(amount decimal.Decimal, currency string) error {
+	if !amount.IsPositive() || !amount.Equal(amount.Round(2)) {
+		return ErrInvalidAmount
+	}
+	return validateCurrency(currency)
+}
+
+func newTransaction(txnType models.TransactionType, amount decimal.Decimal, currency string, description, reference *string) *models.Transaction {
+	return &models.Transaction{
+		ID:              uuid.New(),
+		TransactionType: txnType,
+		Amount:          amount.Round(2),
+		Currency:        strings.ToUpper(currency),
+		ExchangeRate:    decimal.NewFromInt(1),
+		Fee:             decimal.Zero,
+		Description:     description,
+		ReferenceNumber: reference,
+		Status:          models.TransactionStatusPending,
+	}
+}
+
+func toTransactionResponse(txn *models.Transaction) *models.TransactionResponse {
+	return &models.TransactionResponse{
+		ID:                    txn.ID,
+		TransactionNumber:     txn.TransactionNumber,
+		TransactionType:       txn.TransactionType,
+		Amount:                txn.Amount,
+		Currency:              txn.Currency,
+		Fee:                   txn.Fee,
+		Description:           txn.Description,
+		Status:                txn.Status,
+		OriginalTransactionID: txn.OriginalTransactionID,
+		ProcessedAt:           txn.ProcessedAt,
+		CreatedAt:             txn.CreatedAt,
+	}
+}
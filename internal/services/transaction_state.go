@@ -0,0 +1,122 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidStatusTransition = errors.New("invalid transaction status transition")
+
+// InvalidStatusTransitionError is returned when a transaction is asked to
+// move between two statuses that the state machine does not connect.
+type InvalidStatusTransitionError struct {
+	From models.TransactionStatus
+	To   models.TransactionStatus
+}
+
+func (e *InvalidStatusTransitionError) Error() string {
+	return fmt.Sprintf("transaction cannot move from %s to %s", e.From, e.To)
+}
+
+func (e *InvalidStatusTransitionError) Is(target error) bool {
+	return target == ErrInvalidStatusTransition
+}
+
+// transactionTransitions lists the statuses each status may move to.
+// Failed, cancelled and reversed are terminal.
+var transactionTransitions = map[models.TransactionStatus][]models.TransactionStatus{
+	models.TransactionStatusPending: {
+		models.TransactionStatusProcessing,
+		models.TransactionStatusFailed,
+		models.TransactionStatusCancelled,
+	},
+	models.TransactionStatusProcessing: {
+		models.TransactionStatusCompleted,
+		models.TransactionStatusFailed,
+	},
+	models.TransactionStatusCompleted: {
+		models.TransactionStatusReversed,
+	},
+}
+
+// CanTransition reports whether the state machine allows from -> to
+func CanTransition(from, to models.TransactionStatus) bool {
+	for _, allowed := range transactionTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func checkTransition(from, to models.TransactionStatus) error {
+	if !CanTransition(from, to) {
+		return &InvalidStatusTransitionError{From: from, To: to}
+	}
+	return nil
+}
+
+// recordCreatedTx stores a new transaction and the history entry for its initial status
+func (s *TransactionService) recordCreatedTx(tx *sql.Tx, txn *models.Transaction, actorID *uuid.UUID, reason string) error {
+	if txn.Status != models.TransactionStatusPending {
+		return fmt.Errorf("transactions must be created as %s, not %s", models.TransactionStatusPending, txn.Status)
+	}
+
+	if err := s.txnRepo.CreateTx(tx, txn); err != nil {
+		return err
+	}
+
+	return s.txnRepo.CreateStatusHistoryTx(tx, &models.TransactionStatusHistory{
+		ID:            uuid.New(),
+		TransactionID: txn.ID,
+		ToStatus:      txn.Status,
+		ActorID:       actorID,
+		Reason:        optionalString(reason),
+	})
+}
+
+// transitionTx moves a transaction to a new status, enforcing the state
+// machine and recording who made the change and why.
+func (s *TransactionService) transitionTx(tx *sql.Tx, txn *models.Transaction, to models.TransactionStatus, actorID *uuid.UUID, reason string) error {
+	from := txn.Status
+	if err := checkTransition(from, to); err != nil {
+		return err
+	}
+
+	processedAt, err := s.txnRepo.UpdateStatusTx(tx, txn.ID, from, to)
+	if err != nil {
+		if errors.Is(err, db.ErrTransactionStatusChanged) {
+			return &InvalidStatusTransitionError{From: from, To: to}
+		}
+		return err
+	}
+
+	err = s.txnRepo.CreateStatusHistoryTx(tx, &models.TransactionStatusHistory{
+		ID:            uuid.New(),
+		TransactionID: txn.ID,
+		FromStatus:    &from,
+		ToStatus:      to,
+		ActorID:       actorID,
+		Reason:        optionalString(reason),
+	})
+	if err != nil {
+		return err
+	}
+
+	txn.Status = to
+	txn.ProcessedAt = processedAt
+	return nil
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"financial-transaction-system/internal/models"
+)
+
+var allTransactionStatuses = []models.TransactionStatus{
+	models.TransactionStatusPending,
+	models.TransactionStatusProcessing,
+	models.TransactionStatusCompleted,
+	models.TransactionStatusFailed,
+	models.TransactionStatusCancelled,
+	models.TransactionStatusReversed,
+}
+
+func TestCanTransition(t *testing.T) {
+	allowed := map[[2]models.TransactionStatus]bool{
+		{models.TransactionStatusPending, models.TransactionStatusProcessing}:   true,
+		{models.TransactionStatusPending, models.TransactionStatusFailed}:       true,
+		{models.TransactionStatusPending, models.TransactionStatusCancelled}:    true,
+		{models.TransactionStatusProcessing, models.TransactionStatusCompleted}: true,
+		{models.TransactionStatusProcessing, models.TransactionStatusFailed}:    true,
+		{models.TransactionStatusCompleted, models.TransactionStatusReversed}:   true,
+	}
+
+	for _, from := range allTransactionStatuses {
+		for _, to := range allTransactionStatuses {
+			want := allowed[[2]models.TransactionStatus{from, to}]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestTerminalStatusesHaveNoTransitions(t *testing.T) {
+	for _, status := range []models.TransactionStatus{
+		models.TransactionStatusFailed,
+		models.TransactionStatusCancelled,
+		models.TransactionStatusReversed,
+	} {
+		if next := transactionTransitions[status]; len(next) != 0 {
+			t.Errorf("%s is terminal but may move to %v", status, next)
+		}
+	}
+}
+
+func TestCheckTransition(t *testing.T) {
+	if err := checkTransition(models.TransactionStatusPending, models.TransactionStatusProcessing); err != nil {
+		t.Fatalf("pending -> processing: unexpected error %v", err)
+	}
+
+	err := checkTransition(models.TransactionStatusCompleted, models.TransactionStatusPending)
+	if !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Fatalf("completed -> pending: got %v, want ErrInvalidStatusTransition", err)
+	}
+
+	var transitionErr *InvalidStatusTransitionError
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("completed -> pending: got %T, want *InvalidStatusTransitionError", err)
+	}
+	if transitionErr.From != models.TransactionStatusCompleted || transitionErr.To != models.TransactionStatusPending {
+		t.Errorf("error records %s -> %s, want completed -> pending", transitionErr.From, transitionErr.To)
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"financial-transaction-system/internal/config"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// StepUpPolicy decides which transfers need step-up authentication. A zero
+// TransferThreshold disables the amount check and a zero FreshAuthWindow
+// disables the exemption for users who have just signed in.
+type StepUpPolicy struct {
+	TransferThreshold decimal.Decimal
+	FreshAuthWindow   time.Duration
+	ChallengeTTL      time.Duration
+	MaxAttempts       int
+}
+
+func StepUpPolicyFromConfig(cfg config.StepUpConfig) StepUpPolicy {
+	return StepUpPolicy{
+		TransferThreshold: decimal.NewFromFloat(cfg.TransferThreshold),
+		FreshAuthWindow:   time.Duration(cfg.FreshAuthMinutes) * time.Minute,
+		ChallengeTTL:      time.Duration(cfg.ChallengeTTLMinutes) * time.Minute,
+		MaxAttempts:       cfg.MaxAttempts,
+	}
+}
+
+// stepUpCheck tells screenAndPostTx why a transaction needs step-up
+// authentication before the fraud rules run, and whether a review decision
+// should require it too
+type stepUpCheck struct {
+	reasons  []string
+	onReview bool
+}
+
+// transferStepUpCheckTx applies the step-up policy to a transfer. The amount
+// and new payee checks are waived when the user signed in within the fresh
+// authentication window; a fraud review always needs a new proof.
+func (s *TransactionService) transferStepUpCheckTx(tx *sql.Tx, txn *models.Transaction, userID uuid.UUID, to *models.Account, authTime time.Time) (stepUpCheck, error) {
+	check := stepUpCheck{onReview: true}
+	if s.stepUp.FreshAuthWindow > 0 && time.Since(authTime) < s.stepUp.FreshAuthWindow {
+		return check, nil
+	}
+
+	if s.stepUp.TransferThreshold.IsPositive() && txn.Amount.GreaterThanOrEqual(s.stepUp.TransferThreshold) {
+		check.reasons = append(check.reasons, models.StepUpReasonAmount)
+	}
+
+	if to.UserID != userID {
+		paid, err := s.txnRepo.HasPaidAccountTx(tx, userID, to.ID)
+		if err != nil {
+			return check, err
+		}
+		if !paid {
+			check.reasons = append(check.reasons, models.StepUpReasonNewPayee)
+		}
+	}
+
+	return check, nil
+}
+
+// requireStepUpTx opens the challenge for a held transaction and audits it
+func (s *TransactionService) requireStepUpTx(tx *sql.Tx, txn *models.Transaction, userID, accountID uuid.UUID, reasons []string, meta *models.RequestMeta) (*models.StepUpChallenge, error) {
+	challenge := &models.StepUpChallenge{
+		ID:            uuid.New(),
+		UserID:        userID,
+		TransactionID: txn.ID,
+		Reasons:       reasons,
+		Status:        models.StepUpStatusPending,
+		ExpiresAt:     time.Now().Add(s.stepUp.ChallengeTTL),
+	}
+	if err := s.stepUpRepo.CreateTx(tx, challenge); err != nil {
+		return nil, err
+	}
+
+	err := s.audit.RecordTx(tx, meta, &models.CreateAuditLogRequest{
+		UserID:        &userID,
+		AccountID:     &accountID,
+		TransactionID: &txn.ID,
+		Action:        models.AuditActionStepUpRequired,
+		EntityType:    "step_up_challenge",
+		EntityID:      &challenge.ID,
+		Metadata: map[string]interface{}{
+			"reasons":    reasons,
+			"expires_at": challenge.ExpiresAt,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// awaitingStepUpTx reports whether a held transaction still waits for the
+// user to answer a step-up challenge
+func (s *TransactionService) awaitingStepUpTx(tx *sql.Tx, transactionID uuid.UUID) (bool, error) {
+	return s.stepUpRepo.HasPendingTx(tx, transactionID)
+}
@@ -0,0 +1,225 @@
+//go:build integration
+
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"financial-transaction-system/internal/db"
+	"financial-transaction-system/internal/dbtest"
+	"financial-transaction-system/internal/fraud"
+	"financial-transaction-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// testStepUpPolicy asks for step-up from 500.00 unless the user signed in
+// within the last quarter of an hour
+var testStepUpPolicy = StepUpPolicy{
+	TransferThreshold: decimal.NewFromInt(500),
+	FreshAuthWindow:   15 * time.Minute,
+	ChallengeTTL:      10 * time.Minute,
+	MaxAttempts:       3,
+}
+
+// staleAuth is a sign-in too long ago to waive step-up
+var staleAuth = time.Now().Add(-time.Hour)
+
+type stepUpFixture struct {
+	env     *testEnv
+	stepUp  *StepUpService
+	user    *models.User
+	a, b    *models.Account
+	analyst *models.User
+}
+
+func newStepUpFixture(t *testing.T, rules fraud.Rules, policy StepUpPolicy) *stepUpFixture {
+	t.Helper()
+
+	env := newTestEnv(t, rules, policy)
+	userRepo := db.NewUserRepository(env.database)
+	mfa := NewMFAService(env.database, db.NewMFARepository(env.database), userRepo, nil, "test", env.audit)
+	user := dbtest.CreateUser(t, env.database, models.UserRoleCustomer)
+	f := &stepUpFixture{
+		env:     env,
+		stepUp:  NewStepUpService(env.database, env.stepUpRepo, userRepo, env.fraudRepo, mfa, env.transactions, env.audit, policy.MaxAttempts),
+		user:    user,
+		a:       dbtest.CreateAccount(t, env.database, user.ID, models.AccountTypeChecking, "USD"),
+		b:       dbtest.CreateAccount(t, env.database, user.ID, models.AccountTypeChecking, "USD"),
+		analyst: dbtest.CreateUser(t, env.database, models.UserRoleFraudAnalyst),
+	}
+	env.fund(t, user.ID, f.a.ID, "250.00")
+	env.fund(t, user.ID, f.a.ID, "250.00")
+	env.fund(t, user.ID, f.a.ID, "250.00")
+	env.fund(t, user.ID, f.a.ID, "250.00")
+	return f
+}
+
+func (f *stepUpFixture) transfer(t *testing.T, toID uuid.UUID, amount string, authTime time.Time) *models.TransactionResponse {
+	t.Helper()
+
+	response, err := f.env.transactions.Transfer(f.user.ID, &models.TransferRequest{
+		FromAccountID: f.a.ID,
+		ToAccountID:   toID,
+		Amount:        decimal.RequireFromString(amount),
+		Currency:      "USD",
+	}, authTime, nil)
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	return response
+}
+
+// held checks that a transfer is waiting for a challenge with the reasons
+func held(t *testing.T, response *models.TransactionResponse, reasons ...string) *models.StepUpChallenge {
+	t.Helper()
+
+	if response.Status != models.TransactionStatusPending || response.StepUp == nil {
+		t.Fatalf("transfer is %s with challenge %+v, want it held for step-up", response.Status, response.StepUp)
+	}
+	if !reflect.DeepEqual(response.StepUp.Reasons, reasons) {
+		t.Errorf("step-up reasons %v, want %v", response.StepUp.Reasons, reasons)
+	}
+	return response.StepUp
+}
+
+func (f *stepUpFixture) confirm(challengeID uuid.UUID, password string) (*models.TransactionResponse, error) {
+	return f.stepUp.Confirm(f.user.ID, challengeID, &models.ConfirmStepUpRequest{Password: password}, nil)
+}
+
+func TestRecentSignInWaivesStepUp(t *testing.T) {
+	f := newStepUpFixture(t, permissiveRules, testStepUpPolicy)
+
+	response := f.transfer(t, f.b.ID, "600.00", time.Now())
+	if response.Status != models.TransactionStatusCompleted || response.StepUp != nil {
+		t.Errorf("transfer is %s with challenge %+v, want it completed", response.Status, response.StepUp)
+	}
+}
+
+func TestLargeTransferNeedsStepUp(t *testing.T) {
+	f := newStepUpFixture(t, permissiveRules, testStepUpPolicy)
+
+	if response := f.transfer(t, f.b.ID, "499.99", staleAuth); response.Status != models.TransactionStatusCompleted {
+		t.Errorf("transfer below the threshold is %s, want it completed", response.Status)
+	}
+
+	response := f.transfer(t, f.b.ID, "500.00", staleAuth)
+	challenge := held(t, response, models.StepUpReasonAmount)
+	f.env.assertBalance(t, f.b.ID, "499.99")
+
+	confirmed, err := f.confirm(challenge.ID, dbtest.Password)
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if confirmed.Status != models.TransactionStatusCompleted {
+		t.Errorf("confirmed transfer is %s, want it completed", confirmed.Status)
+	}
+	f.env.assertBalance(t, f.a.ID, "0.01")
+	f.env.assertBalance(t, f.b.ID, "999.99")
+
+	if _, err := f.confirm(challenge.ID, dbtest.Password); !errors.Is(err, ErrStepUpChallengeClosed) {
+		t.Errorf("second Confirm: got %v, want ErrStepUpChallengeClosed", err)
+	}
+	f.env.assertReconciled(t)
+}
+
+func TestNewPayeeNeedsStepUpOnce(t *testing.T) {
+	f := newStepUpFixture(t, permissiveRules, testStepUpPolicy)
+	payee := dbtest.CreateUser(t, f.env.database, models.UserRoleCustomer)
+	payeeAccount := dbtest.CreateAccount(t, f.env.database, payee.ID, models.AccountTypeChecking, "USD")
+
+	challenge := held(t, f.transfer(t, payeeAccount.ID, "10.00", staleAuth), models.StepUpReasonNewPayee)
+	if _, err := f.confirm(challenge.ID, dbtest.Password); err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+
+	if response := f.transfer(t, payeeAccount.ID, "10.00", staleAuth); response.Status != models.TransactionStatusCompleted {
+		t.Errorf("second transfer to the payee is %s, want it completed", response.Status)
+	}
+	f.env.assertBalance(t, payeeAccount.ID, "20.00")
+}
+
+func TestWrongAnswersCancelTheTransfer(t *testing.T) {
+	f := newStepUpFixture(t, permissiveRules, testStepUpPolicy)
+	response := f.transfer(t, f.b.ID, "600.00", staleAuth)
+	challenge := held(t, response, models.StepUpReasonAmount)
+
+	// Another user cannot answer the challenge
+	other := dbtest.CreateUser(t, f.env.database, models.UserRoleCustomer)
+	if _, err := f.stepUp.Confirm(other.ID, challenge.ID, &models.ConfirmStepUpRequest{Password: dbtest.Password}, nil); !errors.Is(err, ErrStepUpChallengeNotFound) {
+		t.Errorf("another user's Confirm: got %v, want ErrStepUpChallengeNotFound", err)
+	}
+
+	for attempt := 1; attempt <= testStepUpPolicy.MaxAttempts; attempt++ {
+		want := ErrIncorrectPassword
+		if attempt == testStepUpPolicy.MaxAttempts {
+			want = ErrStepUpAttemptsExceeded
+		}
+		if _, err := f.confirm(challenge.ID, "Wrong-Password-1"); !errors.Is(err, want) {
+			t.Errorf("attempt %d: got %v, want %v", attempt, err, want)
+		}
+	}
+
+	if status := f.env.transactionStatus(t, response.ID); status != models.TransactionStatusCancelled {
+		t.Errorf("transfer is %s, want it cancelled", status)
+	}
+	if _, err := f.confirm(challenge.ID, dbtest.Password); !errors.Is(err, ErrStepUpChallengeClosed) {
+		t.Errorf("Confirm after failing: got %v, want ErrStepUpChallengeClosed", err)
+	}
+	account, err := f.env.accountRepo.GetByID(f.a.ID)
+	if err != nil {
+		t.Fatalf("failed to get account: %v", err)
+	}
+	if !account.AvailableBalance.Equal(decimal.RequireFromString("1000.00")) {
+		t.Errorf("available balance %s, want the hold released", account.AvailableBalance)
+	}
+}
+
+func TestExpiredChallengeCancelsTheTransfer(t *testing.T) {
+	policy := testStepUpPolicy
+	policy.ChallengeTTL = 0
+	f := newStepUpFixture(t, permissiveRules, policy)
+
+	response := f.transfer(t, f.b.ID, "600.00", staleAuth)
+	challenge := held(t, response, models.StepUpReasonAmount)
+
+	if _, err := f.confirm(challenge.ID, dbtest.Password); !errors.Is(err, ErrStepUpChallengeExpired) {
+		t.Errorf("Confirm: got %v, want ErrStepUpChallengeExpired", err)
+	}
+	if status := f.env.transactionStatus(t, response.ID); status != models.TransactionStatusCancelled {
+		t.Errorf("transfer is %s, want it cancelled", status)
+	}
+	f.env.assertBalance(t, f.b.ID, "0.00")
+}
+
+func TestStepUpWaitsForFraudReview(t *testing.T) {
+	f := newStepUpFixture(t, fraud.Rules{MaxTransactionAmount: decimal.NewFromInt(300)}, testStepUpPolicy)
+
+	// A fraud review needs a new proof even right after signing in
+	response := f.transfer(t, f.b.ID, "400.00", time.Now())
+	challenge := held(t, response, models.StepUpReasonFraudReview)
+
+	confirmed, err := f.confirm(challenge.ID, dbtest.Password)
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if confirmed.Status != models.TransactionStatusPending {
+		t.Errorf("transfer with an open alert is %s, want it still held", confirmed.Status)
+	}
+
+	alerts := f.env.alerts(t, response.ID)
+	if len(alerts) != 1 {
+		t.Fatalf("%d alerts, want 1", len(alerts))
+	}
+	f.env.closeAlert(t, f.analyst.ID, alerts[0].ID, models.FraudStatusFalsePositive)
+
+	if status := f.env.transactionStatus(t, response.ID); status != models.TransactionStatusCompleted {
+		t.Errorf("cleared transfer is %s, want it completed", status)
+	}
+	f.env.assertBalance(t, f.b.ID, "400.00")
+	f.env.assertReconciled(t)
+}
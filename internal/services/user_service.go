@@ -1,7 +1,11 @@
 package services
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"financial-transaction-system/internal/auth"
 	"financial-transaction-system/internal/db"
@@ -9,28 +13,61 @@ import (
 	"financial-transaction-system/internal/utils"
 
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used; the session has been revoked")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrInvalidRole         = errors.New("invalid role")
+	ErrOwnRoleChange       = errors.New("users cannot change their own role")
+	ErrAccountDeactivated  = errors.New("account is deactivated")
 )
 
 type UserService struct {
-	userRepo   *db.UserRepository
-	jwtManager *auth.JWTManager
+	db           *db.Database
+	userRepo     *db.UserRepository
+	refreshRepo  *db.RefreshTokenRepository
+	jwtManager   *auth.JWTManager
+	denylist     auth.TokenDenylist
+	mfa          *MFAService
+	verification *EmailVerificationService
+	passwords    *PasswordPolicyService
+	hasher       utils.PasswordHasher
+	protection   *LoginProtectionService
+	audit        *AuditService
 }
 
-func NewUserService(userRepo *db.UserRepository, jwtManager *auth.JWTManager) *UserService {
+func NewUserService(database *db.Database, userRepo *db.UserRepository, refreshRepo *db.RefreshTokenRepository, jwtManager *auth.JWTManager, denylist auth.TokenDenylist, mfa *MFAService, verification *EmailVerificationService, passwords *PasswordPolicyService, hasher utils.PasswordHasher, protection *LoginProtectionService, audit *AuditService) *UserService {
 	return &UserService{
-		userRepo:   userRepo,
-		jwtManager: jwtManager,
+		db:           database,
+		userRepo:     userRepo,
+		refreshRepo:  refreshRepo,
+		jwtManager:   jwtManager,
+		denylist:     denylist,
+		mfa:          mfa,
+		verification: verification,
+		passwords:    passwords,
+		hasher:       hasher,
+		protection:   protection,
+		audit:        audit,
 	}
 }
 
-func (s *UserService) Register(req *models.CreateUserRequest) (*models.LoginResponse, error) {
+func (s *UserService) Register(req *models.CreateUserRequest, meta *models.RequestMeta) (*models.LoginResponse, error) {
 	// Validate password
-	if !utils.IsValidPassword(req.Password) {
-		return nil, fmt.Errorf("password must be at least %d characters long", utils.MinPasswordLength)
+	err := s.passwords.Check(req.Password, utils.PasswordOwner{
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Hash password
-	hashedPassword, err := utils.HashPassword(req.Password)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -45,6 +82,7 @@ func (s *UserService) Register(req *models.CreateUserRequest) (*models.LoginResp
 		Phone:        req.Phone,
 		DateOfBirth:  req.DateOfBirth,
 		Address:      req.Address,
+		Role:         models.UserRoleCustomer,
 		IsActive:     true,
 		IsVerified:   false,
 	}
@@ -55,32 +93,336 @@ func (s *UserService) Register(req *models.CreateUserRequest) (*models.LoginResp
 	}
 
 	// Generate JWT tokens
-	return s.jwtManager.GenerateTokens(user)
+	response, err := s.startSession(user)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(withSession(meta, response.SessionID), &models.CreateAuditLogRequest{
+		UserID:     &user.ID,
+		Action:     models.AuditActionUserCreated,
+		EntityType: "user",
+		EntityID:   &user.ID,
+		NewValues:  userAuditValues(user),
+	})
+
+	// The account exists either way; the user can ask for another email
+	if err := s.verification.SendVerification(context.Background(), user); err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to send verification email")
+	}
+
+	return response, nil
 }
 
-func (s *UserService) Login(req *models.LoginRequest) (*models.LoginResponse, error) {
+// Login checks the user's password. Users with two-factor authentication get
+// an MFA challenge instead of tokens; exactly one of the results is non-nil.
+func (s *UserService) Login(req *models.LoginRequest, meta *models.RequestMeta) (*models.LoginResponse, *models.MFAChallengeResponse, error) {
+	ctx := context.Background()
+
+	// Refuse clients that keep failing before spending a password hash on them
+	if err := s.protection.CheckIP(ctx, meta); err != nil {
+		return nil, nil, err
+	}
+
 	// Get user by email
 	user, err := s.userRepo.GetByEmail(req.Email)
 	if err != nil {
-		return nil, fmt.Errorf("invalid email or password")
+		if err := s.protection.RecordFailure(ctx, req.Email, nil, meta); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	if err := s.protection.CheckAccount(ctx, user, meta); err != nil {
+		return nil, nil, err
 	}
 
 	// Verify password
 	if err := utils.VerifyPassword(user.PasswordHash, req.Password); err != nil {
-		return nil, fmt.Errorf("invalid email or password")
+		if err := s.protection.RecordFailure(ctx, req.Email, user, meta); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	if s.hasher.NeedsRehash(user.PasswordHash) {
+		s.upgradePasswordHash(user, req.Password)
 	}
 
 	// Check if user is active
 	if !user.IsActive {
-		return nil, fmt.Errorf("account is deactivated")
+		return nil, nil, ErrAccountDeactivated
 	}
 
-	// Generate JWT tokens
-	return s.jwtManager.GenerateTokens(user)
+	mfaEnabled, err := s.mfa.Enabled(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mfaEnabled {
+		token, expiresAt, err := s.jwtManager.GenerateMFAToken(user)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Failures are only forgotten once the second factor is proven too
+		return nil, &models.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    token,
+			ExpiresAt:   expiresAt.Unix(),
+		}, nil
+	}
+
+	if err := s.protection.RecordSuccess(user.ID); err != nil {
+		return nil, nil, err
+	}
+
+	response, err := s.completeLogin(user, nil, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	return response, nil, nil
+}
+
+// upgradePasswordHash rehashes a password just proven at login with the
+// current algorithm and parameters. Failing only delays the upgrade to a
+// later login, so errors are logged rather than returned.
+func (s *UserService) upgradePasswordHash(user *models.User, password string) {
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to rehash password")
+		return
+	}
+
+	replaced, err := s.userRepo.ReplacePasswordHash(user.ID, user.PasswordHash, hashedPassword)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", user.ID).Error("Failed to store rehashed password")
+		return
+	}
+	if replaced {
+		user.PasswordHash = hashedPassword
+	}
+}
+
+// VerifyMFA finishes a two-factor login. Each MFA token allows a single
+// attempt, so a wrong code means starting again from the password. Wrong
+// codes count towards locking the account and throttling the client IP just
+// as wrong passwords do.
+func (s *UserService) VerifyMFA(ctx context.Context, req *models.VerifyMFARequest, meta *models.RequestMeta) (*models.LoginResponse, error) {
+	if err := s.protection.CheckIP(ctx, meta); err != nil {
+		return nil, err
+	}
+
+	claims, err := s.jwtManager.ParseMFAToken(req.MFAToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	revoked, err := s.denylist.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidMFAToken
+	}
+	if err := s.denylist.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if err := s.protection.CheckAccount(ctx, user, meta); err != nil {
+		return nil, err
+	}
+
+	method, err := s.mfa.VerifyCode(user.ID, req.Code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := s.protection.RecordMFAFailure(ctx, user, meta); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.protection.RecordSuccess(user.ID); err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(user, map[string]interface{}{"mfa_method": method}, meta)
 }
 
-func (s *UserService) RefreshToken(req *models.RefreshTokenRequest) (*models.LoginResponse, error) {
-	return s.jwtManager.RefreshToken(req.RefreshToken)
+// completeLogin starts a session for a user who passed every login check
+func (s *UserService) completeLogin(user *models.User, metadata map[string]interface{}, meta *models.RequestMeta) (*models.LoginResponse, error) {
+	response, err := s.startSession(user)
+	if err != nil {
+		return nil, err
+	}
+
+	login := &models.CreateAuditLogRequest{
+		UserID:     &user.ID,
+		Action:     models.AuditActionUserLogin,
+		EntityType: "user",
+		EntityID:   &user.ID,
+	}
+	if len(metadata) > 0 {
+		login.Metadata = metadata
+	}
+	s.audit.Record(withSession(meta, response.SessionID), login)
+
+	return response, nil
+}
+
+// RefreshToken rotates a refresh token: the presented token is used up and a
+// new pair is issued in the same session. Presenting a used token again means
+// it was stolen or replayed, so the whole session is revoked.
+func (s *UserService) RefreshToken(req *models.RefreshTokenRequest, meta *models.RequestMeta) (*models.LoginResponse, error) {
+	claims, err := s.jwtManager.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	tokenID := uuid.MustParse(claims.ID)
+
+	var response *models.LoginResponse
+	reused := false
+	err = s.db.WithTransaction(func(tx *sql.Tx) error {
+		stored, err := s.refreshRepo.LockForUpdateTx(tx, tokenID)
+		if err != nil {
+			if errors.Is(err, db.ErrRefreshTokenNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		if stored.UserID != claims.UserID || stored.RevokedAt != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		if stored.UsedAt != nil {
+			reused = true
+			if _, err := s.refreshRepo.RevokeFamilyTx(tx, stored.FamilyID, "reuse_detected"); err != nil {
+				return err
+			}
+			return s.audit.RecordTx(tx, withSession(meta, stored.FamilyID.String()), &models.CreateAuditLogRequest{
+				UserID:     &stored.UserID,
+				Action:     models.AuditActionRefreshTokenReused,
+				EntityType: "refresh_token",
+				EntityID:   &stored.ID,
+				Metadata: map[string]interface{}{
+					"used_at":     stored.UsedAt,
+					"replaced_by": stored.ReplacedBy,
+				},
+			})
+		}
+
+		if !stored.ExpiresAt.After(time.Now()) {
+			return ErrInvalidRefreshToken
+		}
+
+		// Deactivated users are not returned, so they cannot keep refreshing
+		user, err := s.userRepo.GetByID(stored.UserID)
+		if err != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		// Tokens from before auth_time was introduced are treated as stale
+		var authTime time.Time
+		if claims.AuthTime != nil {
+			authTime = claims.AuthTime.Time
+		}
+		response, err = s.jwtManager.GenerateSessionTokens(user, stored.FamilyID.String(), authTime)
+		if err != nil {
+			return err
+		}
+
+		if err := s.refreshRepo.CreateTx(tx, refreshTokenRecord(user.ID, response)); err != nil {
+			return err
+		}
+		return s.refreshRepo.MarkUsedTx(tx, stored.ID, response.RefreshTokenID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
+
+	return response, nil
+}
+
+// Logout ends the session the access token belongs to: the access token is
+// denylisted and the session's refresh tokens are revoked
+func (s *UserService) Logout(ctx context.Context, claims *auth.Claims, meta *models.RequestMeta) error {
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := s.denylist.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+	}
+
+	var revoked int64
+	if familyID, err := uuid.Parse(claims.SessionID); err == nil {
+		err = s.db.WithTransaction(func(tx *sql.Tx) error {
+			revoked, err = s.refreshRepo.RevokeFamilyTx(tx, familyID, "logout")
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	s.audit.Record(meta, &models.CreateAuditLogRequest{
+		UserID:     &claims.UserID,
+		Action:     models.AuditActionUserLogout,
+		EntityType: "user",
+		EntityID:   &claims.UserID,
+		Metadata: map[string]interface{}{
+			"all_sessions":           false,
+			"refresh_tokens_revoked": revoked,
+		},
+	})
+
+	return nil
+}
+
+// LogoutAll ends every session of the user, including the current one
+func (s *UserService) LogoutAll(ctx context.Context, userID uuid.UUID, meta *models.RequestMeta) error {
+	revoked, err := s.revokeAllSessions(ctx, userID, "logout_all")
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(meta, &models.CreateAuditLogRequest{
+		UserID:     &userID,
+		Action:     models.AuditActionUserLogout,
+		EntityType: "user",
+		EntityID:   &userID,
+		Metadata: map[string]interface{}{
+			"all_sessions":           true,
+			"refresh_tokens_revoked": revoked,
+		},
+	})
+
+	return nil
+}
+
+// revokeAllSessions denies every access token issued to the user so far and
+// revokes their refresh tokens
+func (s *UserService) revokeAllSessions(ctx context.Context, userID uuid.UUID, reason string) (int64, error) {
+	if err := s.denyAccessTokens(ctx, userID); err != nil {
+		return 0, err
+	}
+	return s.refreshRepo.RevokeAllForUser(userID, reason)
+}
+
+// denyAccessTokens denies every access token issued to the user so far
+func (s *UserService) denyAccessTokens(ctx context.Context, userID uuid.UUID) error {
+	return s.denylist.RevokeIssuedBefore(ctx, userID, time.Now(), s.jwtManager.AccessTokenExpiry())
+}
+
+// PurgeExpiredRefreshTokens deletes refresh tokens that can no longer be presented
+func (s *UserService) PurgeExpiredRefreshTokens() (int64, error) {
+	return s.refreshRepo.DeleteExpired()
 }
 
 func (s *UserService) GetProfile(userID uuid.UUID) (*models.UserProfile, error) {
@@ -89,35 +431,36 @@ func (s *UserService) GetProfile(userID uuid.UUID) (*models.UserProfile, error)
 		return nil, err
 	}
 
-	return &models.UserProfile{
-		ID:        user.ID,
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Phone:     user.Phone,
-		IsActive:  user.IsActive,
-		CreatedAt: user.CreatedAt,
-	}, nil
+	return toUserProfile(user), nil
 }
 
-func (s *UserService) UpdateProfile(userID uuid.UUID, req *models.UpdateUserRequest) (*models.UserProfile, error) {
+func (s *UserService) UpdateProfile(userID uuid.UUID, req *models.UpdateUserRequest, meta *models.RequestMeta) (*models.UserProfile, error) {
+	before, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.Update(userID, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.UserProfile{
-		ID:        user.ID,
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Phone:     user.Phone,
-		IsActive:  user.IsActive,
-		CreatedAt: user.CreatedAt,
-	}, nil
+	oldValues, newValues := changedValues(userAuditValues(before), userAuditValues(user))
+	if len(newValues) > 0 {
+		s.audit.Record(meta, &models.CreateAuditLogRequest{
+			UserID:     &user.ID,
+			Action:     models.AuditActionProfileUpdated,
+			EntityType: "user",
+			EntityID:   &user.ID,
+			OldValues:  oldValues,
+			NewValues:  newValues,
+		})
+	}
+
+	return toUserProfile(user), nil
 }
 
-func (s *UserService) ChangePassword(userID uuid.UUID, currentPassword, newPassword string) error {
+func (s *UserService) ChangePassword(userID uuid.UUID, currentPassword, newPassword string, meta *models.RequestMeta) error {
 	// Get user
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
@@ -129,25 +472,170 @@ func (s *UserService) ChangePassword(userID uuid.UUID, currentPassword, newPassw
 		return fmt.Errorf("current password is incorrect")
 	}
 
-	// Validate new password
-	if !utils.IsValidPassword(newPassword) {
-		return fmt.Errorf("new password must be at least %d characters long", utils.MinPasswordLength)
+	err = s.db.WithTransaction(func(tx *sql.Tx) error {
+		// Validate new password
+		if err := s.passwords.checkChangeTx(tx, user, newPassword); err != nil {
+			return err
+		}
+
+		// Hash new password
+		hashedPassword, err := s.hasher.Hash(newPassword)
+		if err != nil {
+			return fmt.Errorf("failed to hash new password: %w", err)
+		}
+
+		// Update password
+		if err := s.userRepo.UpdatePasswordTx(tx, userID, hashedPassword); err != nil {
+			return err
+		}
+		return s.passwords.recordChangeTx(tx, userID, user.PasswordHash)
+	})
+	if err != nil {
+		return err
 	}
 
-	// Hash new password
-	hashedPassword, err := utils.HashPassword(newPassword)
+	// The hashes themselves never go into the audit trail
+	s.audit.Record(meta, &models.CreateAuditLogRequest{
+		UserID:     &userID,
+		Action:     models.AuditActionPasswordChanged,
+		EntityType: "user",
+		EntityID:   &userID,
+	})
+
+	return nil
+}
+
+func (s *UserService) DeactivateAccount(userID uuid.UUID, meta *models.RequestMeta) error {
+	if err := s.userRepo.Deactivate(userID); err != nil {
+		return err
+	}
+
+	// Refresh already refuses inactive users; this also cuts off access tokens
+	if _, err := s.revokeAllSessions(context.Background(), userID, "user_deactivated"); err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Error("Failed to revoke sessions")
+	}
+
+	s.audit.Record(meta, &models.CreateAuditLogRequest{
+		UserID:     &userID,
+		Action:     models.AuditActionUserDeleted,
+		EntityType: "user",
+		EntityID:   &userID,
+		OldValues:  map[string]interface{}{"is_active": true},
+		NewValues:  map[string]interface{}{"is_active": false},
+	})
+
+	return nil
+}
+
+// ChangeRole grants a user a different role. The user's sessions are ended so
+// that tokens carrying the old role stop working.
+func (s *UserService) ChangeRole(actorID, userID uuid.UUID, role models.UserRole, meta *models.RequestMeta) (*models.UserProfile, error) {
+	if !validRoles[role] {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRole, role)
+	}
+	// An admin demoting themselves could leave nobody able to grant roles
+	if actorID == userID {
+		return nil, ErrOwnRoleChange
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Role == role {
+		return toUserProfile(user), nil
+	}
+
+	// Revoke first: a demoted user must not keep a token with the old role
+	if _, err := s.revokeAllSessions(context.Background(), userID, "role_changed"); err != nil {
+		return nil, err
+	}
+
+	previous := user.Role
+	if err := s.userRepo.UpdateRole(userID, role); err != nil {
+		return nil, err
+	}
+	user.Role = role
+
+	s.audit.Record(meta, &models.CreateAuditLogRequest{
+		UserID:     &actorID,
+		Action:     models.AuditActionUserRoleChanged,
+		EntityType: "user",
+		EntityID:   &userID,
+		OldValues:  map[string]interface{}{"role": previous},
+		NewValues:  map[string]interface{}{"role": role},
+	})
+
+	return toUserProfile(user), nil
+}
+
+// startSession issues the first token pair of a new login session
+func (s *UserService) startSession(user *models.User) (*models.LoginResponse, error) {
+	response, err := s.jwtManager.GenerateTokens(user)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithTransaction(func(tx *sql.Tx) error {
+		return s.refreshRepo.CreateTx(tx, refreshTokenRecord(user.ID, response))
+	})
 	if err != nil {
-		return fmt.Errorf("failed to hash new password: %w", err)
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func refreshTokenRecord(userID uuid.UUID, response *models.LoginResponse) *models.RefreshToken {
+	return &models.RefreshToken{
+		ID:        response.RefreshTokenID,
+		UserID:    userID,
+		FamilyID:  uuid.MustParse(response.SessionID),
+		ExpiresAt: response.RefreshExpiresAt,
 	}
+}
 
-	// Update password
-	return s.userRepo.UpdatePassword(userID, hashedPassword)
+var validRoles = map[models.UserRole]bool{
+	models.UserRoleCustomer:          true,
+	models.UserRoleTeller:            true,
+	models.UserRoleFraudAnalyst:      true,
+	models.UserRoleComplianceAuditor: true,
+	models.UserRoleAdmin:             true,
 }
 
-func (s *UserService) DeactivateAccount(userID uuid.UUID) error {
-	return s.userRepo.Deactivate(userID)
+func toUserProfile(user *models.User) *models.UserProfile {
+	return &models.UserProfile{
+		ID:         user.ID,
+		Email:      user.Email,
+		FirstName:  user.FirstName,
+		LastName:   user.LastName,
+		Phone:      user.Phone,
+		Role:       user.Role,
+		IsActive:   user.IsActive,
+		IsVerified: user.IsVerified,
+		CreatedAt:  user.CreatedAt,
+	}
 }
 
-func (s *UserService) VerifyAccount(userID uuid.UUID) error {
-	return s.userRepo.SetVerified(userID, true)
+// userAuditValues lists the user fields recorded in the audit trail
+func userAuditValues(user *models.User) map[string]interface{} {
+	values := map[string]interface{}{
+		"email":         user.Email,
+		"first_name":    user.FirstName,
+		"last_name":     user.LastName,
+		"phone":         nil,
+		"date_of_birth": nil,
+		"address":       nil,
+		"role":          user.Role,
+	}
+	if user.Phone != nil {
+		values["phone"] = *user.Phone
+	}
+	if user.DateOfBirth != nil {
+		values["date_of_birth"] = user.DateOfBirth.Format("2006-01-02")
+	}
+	if user.Address != nil {
+		values["address"] = *user.Address
+	}
+	return values
 }
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2idPrefix     = "$argon2id$"
+	argon2SaltLength   = 16
+	argon2KeyLength    = 32
+	argon2MaxMemoryKiB = 4 * 1024 * 1024
+)
+
+// Argon2idHasher hashes passwords with argon2id. Hashes use the PHC string
+// format, $argon2id$v=19$m=<KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>,
+// with unpadded base64 salt and key.
+type Argon2idHasher struct {
+	MemoryKiB   uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// argon2idParams are the parameters read back from an encoded hash
+type argon2idParams struct {
+	memoryKiB   uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.MemoryKiB, h.Parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, h.MemoryKiB, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h Argon2idHasher) NeedsRehash(hash string) bool {
+	params, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return params.memoryKiB < h.MemoryKiB ||
+		params.iterations < h.Iterations ||
+		params.parallelism < h.Parallelism ||
+		len(params.key) < argon2KeyLength
+}
+
+func verifyArgon2id(hash, password string) error {
+	params, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memoryKiB, params.parallelism, uint32(len(params.key)))
+	if subtle.ConstantTimeCompare(key, params.key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+func decodeArgon2id(hash string) (*argon2idParams, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	params := &argon2idParams{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memoryKiB, &params.iterations, &params.parallelism); err != nil {
+		return nil, ErrUnknownPasswordHash
+	}
+	// Stored hashes are trusted, but a corrupt one must not exhaust memory
+	if params.memoryKiB == 0 || params.memoryKiB > argon2MaxMemoryKiB || params.iterations == 0 || params.parallelism == 0 {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnknownPasswordHash
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	return params, nil
+}
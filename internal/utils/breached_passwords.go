@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// breachedPrefixLength is how many hex characters of a SHA-1 hash a range is
+// named by, as in the Pwned Passwords range API
+const breachedPrefixLength = 5
+
+// BreachedPasswords looks passwords up in a local copy of the Pwned Passwords
+// ranges. The copy is a directory with one file per five-character SHA-1
+// prefix, named <PREFIX>.txt in uppercase hex (00000.txt to FFFFF.txt), each
+// holding what the range API returns for that prefix: one SUFFIX:COUNT line
+// per breached hash, where SUFFIX is the remaining 35 hex characters. This is
+// the layout the official downloader writes when not asked for a single file.
+// A lookup reads the one range its prefix names, so nothing is kept in memory;
+// a missing range file means no breached hash has that prefix.
+type BreachedPasswords struct {
+	dir string
+}
+
+// OpenBreachedPasswords checks that dir is a directory of range files
+func OpenBreachedPasswords(dir string) (*BreachedPasswords, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password ranges: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breached password ranges: %s is not a directory", dir)
+	}
+	return &BreachedPasswords{dir: dir}, nil
+}
+
+// Contains reports whether the password appears in its range with a count
+// above zero; entries with a zero count are padding
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+
+	path := filepath.Join(b.dir, prefix+".txt")
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to open breached password range: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		entry, countText, ok := strings.Cut(text, ":")
+		if !ok || len(entry) != 2*sha1.Size-breachedPrefixLength {
+			return false, fmt.Errorf("invalid entry on line %d of %s", line, path)
+		}
+		if !strings.EqualFold(entry, suffix) {
+			continue
+		}
+
+		count, err := strconv.ParseInt(countText, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid count on line %d of %s", line, path)
+		}
+		return count > 0, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read breached password range: %w", err)
+	}
+
+	return false, nil
+}
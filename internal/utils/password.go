@@ -1,29 +1,70 @@
 package utils
 
 import (
+	"errors"
+	"strings"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
-const (
-	MinPasswordLength = 8  // Minimum password length
-	DefaultCost       = 12 // bcrypt cost factor
+var (
+	ErrUnknownPasswordHash = errors.New("unrecognised password hash format")
+	ErrPasswordMismatch    = errors.New("password does not match")
 )
 
-// HashPassword hashes a password using bcrypt
-func HashPassword(password string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), DefaultCost)
+// PasswordHasher hashes new passwords with one algorithm and set of
+// parameters. Every hash names its algorithm and parameters, so
+// VerifyPassword can check it whichever hasher made it.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of a password
+	Hash(password string) (string, error)
+	// NeedsRehash reports whether a hash was made with another algorithm or
+	// weaker parameters, and should be replaced when the password is known
+	NeedsRehash(hash string) bool
+}
+
+// BcryptHasher hashes passwords with bcrypt. Hashes start with $2a$, $2b$ or
+// $2y$ and carry their cost.
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash hashes a password using bcrypt
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
 	if err != nil {
 		return "", err
 	}
 	return string(hashedBytes), nil
 }
 
-// VerifyPassword compares hashed and plain passwords
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	if !isBcryptHash(hash) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < h.Cost
+}
+
+// VerifyPassword compares hashed and plain passwords, telling the algorithm
+// from the hash's prefix
 func VerifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	switch {
+	case strings.HasPrefix(hashedPassword, argon2idPrefix):
+		return verifyArgon2id(hashedPassword, password)
+	case isBcryptHash(hashedPassword):
+		if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return ErrPasswordMismatch
+			}
+			return err
+		}
+		return nil
+	default:
+		return ErrUnknownPasswordHash
+	}
 }
 
-// IsValidPassword checks minimum password requirements
-func IsValidPassword(password string) bool {
-	return len(password) >= MinPasswordLength
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
 }
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// minPersonalInfoLength is the shortest name or email part a password is
+// checked for, so that short names do not rule out ordinary words
+const minPersonalInfoLength = 3
+
+// Password policy violation codes
+const (
+	PasswordTooShort         = "too_short"
+	PasswordTooLong          = "too_long"
+	PasswordMissingUppercase = "missing_uppercase"
+	PasswordMissingLowercase = "missing_lowercase"
+	PasswordMissingDigit     = "missing_digit"
+	PasswordMissingSymbol    = "missing_symbol"
+	PasswordContainsPersonal = "contains_personal_info"
+	PasswordBreached         = "breached"
+	PasswordRecentlyUsed     = "recently_used"
+)
+
+// PasswordViolation is one rule a password breaks
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicy lists the rules new passwords must follow. MaxLength is in
+// bytes and should not exceed 72, beyond which bcrypt ignores the rest of the
+// password; zero disables it. A nil Breached skips that check.
+type PasswordPolicy struct {
+	MinLength          int
+	MaxLength          int
+	RequireUppercase   bool
+	RequireLowercase   bool
+	RequireDigit       bool
+	RequireSymbol      bool
+	RejectPersonalInfo bool
+	Breached           *BreachedPasswords
+}
+
+// PasswordOwner is what a password is checked against so it does not give
+// away its owner's identity
+type PasswordOwner struct {
+	Email     string
+	FirstName string
+	LastName  string
+}
+
+// Check returns every rule the password breaks, or nil if it follows them
+// all. An error means the breached password ranges could not be read.
+func (p *PasswordPolicy) Check(password string, owner PasswordOwner) ([]PasswordViolation, error) {
+	var violations []PasswordViolation
+	add := func(code, message string) {
+		violations = append(violations, PasswordViolation{Code: code, Message: message})
+	}
+
+	if len([]rune(password)) < p.MinLength {
+		add(PasswordTooShort, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		add(PasswordTooLong, fmt.Sprintf("must be at most %d bytes long", p.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUppercase && !upper {
+		add(PasswordMissingUppercase, "must contain an uppercase letter")
+	}
+	if p.RequireLowercase && !lower {
+		add(PasswordMissingLowercase, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		add(PasswordMissingDigit, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		add(PasswordMissingSymbol, "must contain a symbol")
+	}
+
+	if p.RejectPersonalInfo && containsPersonalInfo(password, owner) {
+		add(PasswordContainsPersonal, "must not contain your email address or name")
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			add(PasswordBreached, "appears in a list of breached passwords")
+		}
+	}
+
+	return violations, nil
+}
+
+func containsPersonalInfo(password string, owner PasswordOwner) bool {
+	lowered := strings.ToLower(password)
+
+	// Email local parts often join several names, so each is checked too
+	local, _, _ := strings.Cut(owner.Email, "@")
+	parts := []string{owner.FirstName, owner.LastName, local}
+	parts = append(parts, strings.FieldsFunc(local, func(r rune) bool {
+		return r == '.' || r == '_' || r == '-' || r == '+'
+	})...)
+
+	for _, part := range parts {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if len([]rune(part)) >= minPersonalInfoLength && strings.Contains(lowered, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func violationCodes(violations []PasswordViolation) []string {
+	codes := []string{}
+	for _, v := range violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy := &PasswordPolicy{
+		MinLength:          12,
+		MaxLength:          72,
+		RequireUppercase:   true,
+		RequireLowercase:   true,
+		RequireDigit:       true,
+		RequireSymbol:      true,
+		RejectPersonalInfo: true,
+	}
+	owner := PasswordOwner{Email: "jane.doe@example.com", FirstName: "Jane", LastName: "Doe"}
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"compliant", "Correct-Horse-42", []string{}},
+		{"too short", "Ab1!", []string{PasswordTooShort}},
+		{"too long", "Aa1!" + strings.Repeat("x", 69), []string{PasswordTooLong}},
+		{"missing uppercase", "correct-horse-42", []string{PasswordMissingUppercase}},
+		{"missing lowercase", "CORRECT-HORSE-42", []string{PasswordMissingLowercase}},
+		{"missing digit", "Correct-Horse-xx", []string{PasswordMissingDigit}},
+		{"missing symbol", "CorrectHorse42x", []string{PasswordMissingSymbol}},
+		{"space counts as symbol", "Correct Horse 42", []string{}},
+		{"several at once", "abc", []string{PasswordTooShort, PasswordMissingUppercase, PasswordMissingDigit, PasswordMissingSymbol}},
+		{"first name", "Hello-JANE-1234", []string{PasswordContainsPersonal}},
+		{"email local part", "Jane.Doe-1234!", []string{PasswordContainsPersonal}},
+		{"email domain is not personal", "Example-Horse-42", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := policy.Check(tt.password, owner)
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if got := violationCodes(violations); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyLengthCountsCharacters(t *testing.T) {
+	policy := &PasswordPolicy{MinLength: 4, MaxLength: 8}
+
+	// Four characters but eight bytes: long enough, and not over MaxLength
+	violations, err := policy.Check("ÄÖÜß", PasswordOwner{})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("Check = %v, want no violations", violationCodes(violations))
+	}
+
+	violations, err = policy.Check("ÄÖÜßé", PasswordOwner{})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if got := violationCodes(violations); !reflect.DeepEqual(got, []string{PasswordTooLong}) {
+		t.Errorf("Check = %v, want [%s]", got, PasswordTooLong)
+	}
+}
+
+func TestPasswordPolicyIgnoresShortNames(t *testing.T) {
+	policy := &PasswordPolicy{RejectPersonalInfo: true}
+	owner := PasswordOwner{Email: "al@example.com", FirstName: "Al", LastName: "Li"}
+
+	violations, err := policy.Check("Always-Lively-1", owner)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("Check = %v, want no violations", violationCodes(violations))
+	}
+}
+
+// writeRange writes a range file holding the given passwords with their counts
+func writeRange(t *testing.T, dir string, counts map[string]int) {
+	t.Helper()
+	files := map[string][]string{}
+	for password, count := range counts {
+		sum := sha1.Sum([]byte(password))
+		hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+		prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+		files[prefix] = append(files[prefix], suffix+":"+strconv.Itoa(count))
+	}
+	for prefix, lines := range files {
+		path := filepath.Join(dir, prefix+".txt")
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+}
+
+func TestBreachedPasswords(t *testing.T) {
+	dir := t.TempDir()
+	writeRange(t, dir, map[string]int{"Password123!": 4021, "Padding-Only-1": 0})
+
+	breached, err := OpenBreachedPasswords(dir)
+	if err != nil {
+		t.Fatalf("OpenBreachedPasswords: %v", err)
+	}
+
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{"Password123!", true},
+		{"password123!", false},
+		{"Padding-Only-1", false},
+		{"Correct-Horse-42", false},
+	}
+	for _, tt := range tests {
+		got, err := breached.Contains(tt.password)
+		if err != nil {
+			t.Fatalf("Contains(%q): %v", tt.password, err)
+		}
+		if got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+
+	policy := &PasswordPolicy{Breached: breached}
+	violations, err := policy.Check("Password123!", PasswordOwner{})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if got := violationCodes(violations); !reflect.DeepEqual(got, []string{PasswordBreached}) {
+		t.Errorf("Check = %v, want [%s]", got, PasswordBreached)
+	}
+}
+
+func TestBreachedPasswordsRejectsMalformedRange(t *testing.T) {
+	dir := t.TempDir()
+	sum := sha1.Sum([]byte("Password123!"))
+	prefix := strings.ToUpper(hex.EncodeToString(sum[:]))[:breachedPrefixLength]
+	if err := os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte("not a range line\n"), 0o644); err != nil {
+		t.Fatalf("write range: %v", err)
+	}
+
+	breached, err := OpenBreachedPasswords(dir)
+	if err != nil {
+		t.Fatalf("OpenBreachedPasswords: %v", err)
+	}
+	if _, err := breached.Contains("Password123!"); err == nil {
+		t.Error("Contains read a malformed range without error")
+	}
+
+	if _, err := OpenBreachedPasswords(filepath.Join(dir, prefix+".txt")); err == nil {
+		t.Error("OpenBreachedPasswords accepted a file instead of a directory")
+	}
+}
@@ -0,0 +1,26 @@
+-- Drop view
+DROP VIEW IF EXISTS account_ledger_balances;
+
+-- Drop triggers
+DROP TRIGGER IF EXISTS prevent_postings_update_delete ON postings;
+DROP TRIGGER IF EXISTS check_postings_balanced ON postings;
+
+-- Drop functions
+DROP FUNCTION IF EXISTS prevent_posting_modification();
+DROP FUNCTION IF EXISTS check_journal_entry_balanced();
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_postings_system_account_id;
+DROP INDEX IF EXISTS idx_postings_account_id;
+DROP INDEX IF EXISTS idx_postings_journal_entry_id;
+DROP INDEX IF EXISTS idx_journal_entries_created_at;
+DROP INDEX IF EXISTS idx_journal_entries_transaction_id;
+
+-- Drop ledger tables
+DROP TABLE IF EXISTS postings;
+DROP TABLE IF EXISTS journal_entries;
+DROP TABLE IF EXISTS system_accounts;
+
+-- Drop enums
+DROP TYPE IF EXISTS journal_entry_type;
+DROP TYPE IF EXISTS posting_direction;
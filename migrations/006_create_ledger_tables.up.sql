@@ -0,0 +1,127 @@
+-- Create ledger enums
+CREATE TYPE posting_direction AS ENUM ('debit', 'credit');
+CREATE TYPE journal_entry_type AS ENUM ('transaction', 'opening_balance');
+
+-- System accounts are the bank's own side of every posting
+CREATE TABLE IF NOT EXISTS system_accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(50) UNIQUE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO system_accounts (code, name) VALUES
+    ('external_cash', 'External cash clearing'),
+    ('fee_income', 'Fee income'),
+    ('interest_expense', 'Interest expense');
+
+-- Create journal_entries table
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID REFERENCES transactions(id),
+    entry_type journal_entry_type NOT NULL DEFAULT 'transaction',
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create postings table
+CREATE TABLE IF NOT EXISTS postings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    journal_entry_id UUID NOT NULL REFERENCES journal_entries(id),
+    account_id UUID REFERENCES accounts(id),
+    system_account_id UUID REFERENCES system_accounts(id),
+    direction posting_direction NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    -- Constraints
+    CONSTRAINT chk_posting_amount_positive CHECK (amount > 0),
+    CONSTRAINT chk_posting_single_account CHECK (
+        (account_id IS NULL) <> (system_account_id IS NULL)
+    )
+);
+
+-- Create indexes
+CREATE INDEX idx_journal_entries_transaction_id ON journal_entries(transaction_id);
+CREATE INDEX idx_journal_entries_created_at ON journal_entries(created_at);
+CREATE INDEX idx_postings_journal_entry_id ON postings(journal_entry_id);
+CREATE INDEX idx_postings_account_id ON postings(account_id, created_at);
+CREATE INDEX idx_postings_system_account_id ON postings(system_account_id);
+
+-- Every journal entry must balance per currency by the time its transaction commits
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced()
+RETURNS TRIGGER AS $$
+DECLARE
+    unbalanced RECORD;
+BEGIN
+    SELECT currency,
+           SUM(CASE WHEN direction = 'debit' THEN amount ELSE 0 END) AS debits,
+           SUM(CASE WHEN direction = 'credit' THEN amount ELSE 0 END) AS credits
+    INTO unbalanced
+    FROM postings
+    WHERE journal_entry_id = NEW.journal_entry_id
+    GROUP BY currency
+    HAVING SUM(CASE WHEN direction = 'debit' THEN amount ELSE -amount END) <> 0
+    LIMIT 1;
+
+    IF FOUND THEN
+        RAISE EXCEPTION 'journal entry % is unbalanced in %: debits %, credits %',
+            NEW.journal_entry_id, unbalanced.currency, unbalanced.debits, unbalanced.credits;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER check_postings_balanced
+    AFTER INSERT ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- Postings are append-only; corrections are made with compensating entries
+CREATE OR REPLACE FUNCTION prevent_posting_modification()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'postings are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER prevent_postings_update_delete
+    BEFORE UPDATE OR DELETE ON postings
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_posting_modification();
+
+-- Balance of each customer account derived from its postings
+CREATE OR REPLACE VIEW account_ledger_balances AS
+SELECT a.id AS account_id,
+       a.currency,
+       COALESCE(SUM(CASE WHEN p.direction = 'credit' THEN p.amount ELSE -p.amount END), 0) AS ledger_balance
+FROM accounts a
+LEFT JOIN postings p ON p.account_id = a.id
+GROUP BY a.id, a.currency;
+
+-- Open the ledger with the balances accounts already hold
+DO $$
+DECLARE
+    acct RECORD;
+    entry_id UUID;
+    cash_id UUID;
+BEGIN
+    SELECT id INTO cash_id FROM system_accounts WHERE code = 'external_cash';
+
+    FOR acct IN SELECT id, balance, currency FROM accounts WHERE balance <> 0 LOOP
+        entry_id := gen_random_uuid();
+
+        INSERT INTO journal_entries (id, entry_type, description)
+        VALUES (entry_id, 'opening_balance', 'Opening balance carried over from accounts.balance');
+
+        INSERT INTO postings (journal_entry_id, system_account_id, direction, amount, currency)
+        VALUES (entry_id, cash_id, 'debit', acct.balance, acct.currency);
+
+        INSERT INTO postings (journal_entry_id, account_id, direction, amount, currency)
+        VALUES (entry_id, acct.id, 'credit', acct.balance, acct.currency);
+    END LOOP;
+END;
+$$;
@@ -0,0 +1,17 @@
+-- Create idempotency_keys table
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_method VARCHAR(10) NOT NULL,
+    request_path VARCHAR(255) NOT NULL,
+    request_fingerprint VARCHAR(64) NOT NULL,
+    response_status INTEGER,
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+-- Create index for purging expired keys
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
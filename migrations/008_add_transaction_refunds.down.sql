@@ -0,0 +1,9 @@
+-- Enum values added to journal_entry_type and audit_action cannot be dropped
+-- without recreating the types, so they are left in place.
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_transactions_original_transaction_id;
+
+-- Drop constraint and column
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS chk_refund_original;
+ALTER TABLE transactions DROP COLUMN IF EXISTS original_transaction_id;
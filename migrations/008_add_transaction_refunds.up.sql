@@ -0,0 +1,16 @@
+-- Link refunds to the transaction they refund
+ALTER TABLE transactions ADD COLUMN original_transaction_id UUID REFERENCES transactions(id);
+
+ALTER TABLE transactions ADD CONSTRAINT chk_refund_original CHECK (
+    transaction_type <> 'refund' OR original_transaction_id IS NOT NULL
+);
+
+-- Create index for summing refunds of a transaction
+CREATE INDEX idx_transactions_original_transaction_id ON transactions(original_transaction_id);
+
+-- Compensating journal entries for reversals
+ALTER TYPE journal_entry_type ADD VALUE IF NOT EXISTS 'reversal';
+
+-- Audit actions for reversals and refunds
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'transaction_reversed';
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'transaction_refunded';
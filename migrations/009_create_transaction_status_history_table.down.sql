@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_transaction_status_history_actor_id;
+DROP INDEX IF EXISTS idx_transaction_status_history_transaction_id;
+
+-- Drop transaction_status_history table
+DROP TABLE IF EXISTS transaction_status_history;
@@ -0,0 +1,19 @@
+-- Create transaction_status_history table
+CREATE TABLE IF NOT EXISTS transaction_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    from_status transaction_status,
+    to_status transaction_status NOT NULL,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX idx_transaction_status_history_transaction_id ON transaction_status_history(transaction_id, created_at);
+CREATE INDEX idx_transaction_status_history_actor_id ON transaction_status_history(actor_id);
+
+-- Seed history for transactions that existed before status tracking
+INSERT INTO transaction_status_history (transaction_id, from_status, to_status, reason, created_at)
+SELECT id, NULL, status, 'Recorded before status history was tracked', updated_at
+FROM transactions;
@@ -0,0 +1,8 @@
+-- Drop triggers
+DROP TRIGGER IF EXISTS prevent_account_statements_update_delete ON account_statements;
+
+-- Drop functions
+DROP FUNCTION IF EXISTS prevent_statement_modification();
+
+-- Drop account_statements table
+DROP TABLE IF EXISTS account_statements;
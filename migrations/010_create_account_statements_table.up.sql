@@ -0,0 +1,33 @@
+-- Create account_statements table
+-- Issued monthly statements are stored so they never change once delivered
+CREATE TABLE IF NOT EXISTS account_statements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    opening_balance DECIMAL(15,2) NOT NULL,
+    closing_balance DECIMAL(15,2) NOT NULL,
+    total_credits DECIMAL(15,2) NOT NULL,
+    total_debits DECIMAL(15,2) NOT NULL,
+    total_fees DECIMAL(15,2) NOT NULL,
+    transaction_count INTEGER NOT NULL,
+    transactions JSONB NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT uq_account_statements_period UNIQUE (account_id, period_start, period_end),
+    CONSTRAINT chk_statement_period CHECK (period_start < period_end),
+    CONSTRAINT chk_statement_balances CHECK (opening_balance + total_credits - total_debits - total_fees = closing_balance)
+);
+
+-- Statements are immutable once issued
+CREATE OR REPLACE FUNCTION prevent_statement_modification()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'issued statements are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER prevent_account_statements_update_delete
+    BEFORE UPDATE OR DELETE ON account_statements
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_statement_modification();
@@ -0,0 +1,7 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_fraud_alerts_assigned_to_status;
+
+-- Drop constraint and columns
+ALTER TABLE fraud_alerts DROP CONSTRAINT IF EXISTS chk_fraud_alert_resolution;
+ALTER TABLE fraud_alerts DROP COLUMN IF EXISTS assigned_at;
+ALTER TABLE fraud_alerts DROP COLUMN IF EXISTS assigned_to;
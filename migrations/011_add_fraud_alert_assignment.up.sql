@@ -0,0 +1,11 @@
+-- Track which analyst is working an alert
+ALTER TABLE fraud_alerts ADD COLUMN assigned_to UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE fraud_alerts ADD COLUMN assigned_at TIMESTAMP WITH TIME ZONE;
+
+-- Closed alerts must record when they were closed
+ALTER TABLE fraud_alerts ADD CONSTRAINT chk_fraud_alert_resolution CHECK (
+    status NOT IN ('resolved', 'false_positive') OR resolved_at IS NOT NULL
+);
+
+-- Create indexes
+CREATE INDEX idx_fraud_alerts_assigned_to_status ON fraud_alerts(assigned_to, status);
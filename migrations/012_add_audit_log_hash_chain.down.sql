@@ -0,0 +1,32 @@
+-- Drop triggers
+DROP TRIGGER IF EXISTS prevent_audit_checkpoints_update_delete ON audit_checkpoints;
+DROP TRIGGER IF EXISTS prevent_audit_logs_update_delete ON audit_logs;
+
+-- Drop functions
+DROP FUNCTION IF EXISTS prevent_audit_modification();
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_audit_checkpoints_seq;
+
+-- Drop tables
+DROP TABLE IF EXISTS audit_checkpoints;
+DROP TABLE IF EXISTS audit_chain_head;
+
+-- Restore the original foreign keys
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_transaction_id_fkey;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_transaction_id_fkey
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE SET NULL;
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_account_id_fkey;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_account_id_fkey
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE SET NULL;
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_user_id_fkey;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- Drop constraints and columns
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS chk_audit_log_hash;
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS uq_audit_logs_seq;
+ALTER TABLE audit_logs ALTER COLUMN created_at DROP NOT NULL;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS hash;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS prev_hash;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS seq;
@@ -0,0 +1,72 @@
+-- Chain every audit entry to the one before it
+ALTER TABLE audit_logs ADD COLUMN seq BIGINT;
+ALTER TABLE audit_logs ADD COLUMN prev_hash CHAR(64);
+ALTER TABLE audit_logs ADD COLUMN hash CHAR(64);
+
+-- Entries written before the chain existed are numbered but stay unhashed
+UPDATE audit_logs
+SET seq = numbered.seq
+FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, id) AS seq FROM audit_logs) AS numbered
+WHERE audit_logs.id = numbered.id;
+
+ALTER TABLE audit_logs ALTER COLUMN seq SET NOT NULL;
+ALTER TABLE audit_logs ALTER COLUMN created_at SET NOT NULL;
+ALTER TABLE audit_logs ADD CONSTRAINT uq_audit_logs_seq UNIQUE (seq);
+ALTER TABLE audit_logs ADD CONSTRAINT chk_audit_log_hash CHECK ((prev_hash IS NULL) = (hash IS NULL));
+
+-- Deleting a user, account or transaction must not rewrite hashed entries
+ALTER TABLE audit_logs DROP CONSTRAINT audit_logs_user_id_fkey;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+ALTER TABLE audit_logs DROP CONSTRAINT audit_logs_account_id_fkey;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_account_id_fkey
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE RESTRICT;
+ALTER TABLE audit_logs DROP CONSTRAINT audit_logs_transaction_id_fkey;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_transaction_id_fkey
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE RESTRICT;
+
+-- Create audit_chain_head table
+-- A single row holding the latest link; writers lock it to append in order
+CREATE TABLE IF NOT EXISTS audit_chain_head (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE,
+    seq BIGINT NOT NULL,
+    hash CHAR(64),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT chk_audit_chain_head_single_row CHECK (id)
+);
+
+INSERT INTO audit_chain_head (seq) SELECT COALESCE(MAX(seq), 0) FROM audit_logs;
+
+-- Create audit_checkpoints table
+-- Signed snapshots of the chain head; a checkpoint past the current head
+-- shows that entries were removed
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    seq BIGINT NOT NULL,
+    head_hash CHAR(64) NOT NULL,
+    key_id VARCHAR(16) NOT NULL,
+    signature TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Create indexes
+CREATE INDEX idx_audit_checkpoints_seq ON audit_checkpoints(seq);
+
+-- Audit entries and checkpoints are append-only
+CREATE OR REPLACE FUNCTION prevent_audit_modification()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER prevent_audit_logs_update_delete
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_audit_modification();
+
+CREATE TRIGGER prevent_audit_checkpoints_update_delete
+    BEFORE UPDATE OR DELETE ON audit_checkpoints
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_audit_modification();
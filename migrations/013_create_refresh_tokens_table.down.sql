@@ -0,0 +1,10 @@
+-- Enum values added to audit_action cannot be dropped without recreating the
+-- type, so they are left in place.
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_refresh_tokens_expires_at;
+DROP INDEX IF EXISTS idx_refresh_tokens_user_id;
+DROP INDEX IF EXISTS idx_refresh_tokens_family_id;
+
+-- Drop refresh_tokens table
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,24 @@
+-- Create refresh_tokens table
+-- One row per issued refresh token (keyed by its jti). Tokens from one login
+-- form a family; each refresh uses up a token and issues its replacement.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id UUID NOT NULL,
+    replaced_by UUID REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoked_reason VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT chk_refresh_token_revocation CHECK ((revoked_at IS NULL) = (revoked_reason IS NULL))
+);
+
+-- Create indexes
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+
+-- Presenting a used refresh token again is audited
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'refresh_token_reused';
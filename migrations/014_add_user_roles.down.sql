@@ -0,0 +1,9 @@
+-- The 'user_role_changed' audit_action value cannot be dropped without
+-- recreating the type, so it is left in place.
+
+-- Drop index
+DROP INDEX IF EXISTS idx_users_role;
+
+-- Drop role column and type
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+DROP TYPE IF EXISTS user_role;
@@ -0,0 +1,12 @@
+-- Add roles to users
+-- Every user is a customer unless staff are promoted. The first admin has to
+-- be granted directly: UPDATE users SET role = 'admin' WHERE email = '...';
+CREATE TYPE user_role AS ENUM ('customer', 'teller', 'fraud_analyst', 'compliance_auditor', 'admin');
+
+ALTER TABLE users ADD COLUMN role user_role NOT NULL DEFAULT 'customer';
+
+-- Create index on staff roles
+CREATE INDEX idx_users_role ON users(role) WHERE role <> 'customer';
+
+-- Role changes are audited
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'user_role_changed';
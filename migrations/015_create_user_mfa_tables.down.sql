@@ -0,0 +1,9 @@
+-- Enum values added to audit_action cannot be dropped without recreating the
+-- type, so they are left in place.
+
+-- Drop mfa_recovery_codes table
+DROP TABLE IF EXISTS mfa_recovery_codes;
+
+-- Drop user_mfa table
+DROP TRIGGER IF EXISTS update_user_mfa_updated_at ON user_mfa;
+DROP TABLE IF EXISTS user_mfa;
@@ -0,0 +1,32 @@
+-- Create user_mfa table
+-- One row per user who started TOTP enrollment. The secret is encrypted by
+-- the application; enabled_at stays NULL until the first code is confirmed.
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_ciphertext BYTEA NOT NULL,
+    enabled_at TIMESTAMP WITH TIME ZONE,
+    last_used_step BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER update_user_mfa_updated_at
+    BEFORE UPDATE ON user_mfa
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Create mfa_recovery_codes table (codes are stored as SHA-256 hashes)
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT uq_mfa_recovery_codes_user_code UNIQUE (user_id, code_hash)
+);
+
+-- Two-factor changes and failed challenges are audited
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'mfa_enabled';
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'mfa_disabled';
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'mfa_challenge_failed';
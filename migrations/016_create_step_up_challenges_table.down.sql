@@ -0,0 +1,12 @@
+-- Enum values added to audit_action cannot be dropped without recreating the
+-- type, so they are left in place.
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_step_up_challenges_pending_expiry;
+DROP INDEX IF EXISTS idx_step_up_challenges_user_id;
+
+-- Drop step_up_challenges table
+DROP TABLE IF EXISTS step_up_challenges;
+
+-- Drop enum
+DROP TYPE IF EXISTS step_up_status;
@@ -0,0 +1,28 @@
+-- Create step-up challenge status enum
+CREATE TYPE step_up_status AS ENUM ('pending', 'confirmed', 'failed', 'expired');
+
+-- Create step_up_challenges table
+-- A transfer held until the user proves a second factor again. reasons lists
+-- what triggered the challenge (amount, new payee, fraud review).
+CREATE TABLE IF NOT EXISTS step_up_challenges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    transaction_id UUID NOT NULL UNIQUE REFERENCES transactions(id) ON DELETE CASCADE,
+    reasons TEXT[] NOT NULL,
+    status step_up_status NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0 CHECK (attempts >= 0),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT chk_step_up_completion CHECK ((status = 'pending') = (completed_at IS NULL))
+);
+
+-- Create indexes
+CREATE INDEX idx_step_up_challenges_user_id ON step_up_challenges(user_id);
+CREATE INDEX idx_step_up_challenges_pending_expiry ON step_up_challenges(expires_at) WHERE status = 'pending';
+
+-- Step-up challenges and their outcomes are audited
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'step_up_required';
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'step_up_confirmed';
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'step_up_failed';
@@ -0,0 +1,2 @@
+-- Drop verification tracking column
+ALTER TABLE users DROP COLUMN IF EXISTS verification_sent_at;
@@ -0,0 +1,4 @@
+-- Add email verification tracking to users
+-- verification_sent_at is when the last verification email went out and is
+-- used to throttle resends. Existing users stay unverified until they verify.
+ALTER TABLE users ADD COLUMN verification_sent_at TIMESTAMP WITH TIME ZONE;
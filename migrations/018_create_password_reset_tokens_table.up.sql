@@ -0,0 +1,15 @@
+-- Create password_reset_tokens table
+-- Only a SHA-256 hash of each emailed token is kept. A token is spent by a
+-- successful reset, and every other outstanding token of the user with it.
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens(user_id, created_at DESC);
+CREATE INDEX idx_password_reset_tokens_expires_at ON password_reset_tokens(expires_at);
@@ -0,0 +1,12 @@
+-- Enum values added to audit_action cannot be dropped without recreating the
+-- type, so they are left in place.
+
+-- Drop index
+DROP INDEX IF EXISTS idx_users_locked_until;
+
+-- Drop lockout tracking columns
+ALTER TABLE users DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE users DROP COLUMN IF EXISTS lockout_count;
+ALTER TABLE users DROP COLUMN IF EXISTS last_failed_login_at;
+ALTER TABLE users DROP COLUMN IF EXISTS failure_window_started_at;
+ALTER TABLE users DROP COLUMN IF EXISTS failed_login_attempts;
@@ -0,0 +1,18 @@
+-- Add login lockout tracking to users
+-- failed_login_attempts counts wrong passwords in the failure window that
+-- began at failure_window_started_at. lockout_count is how many times the
+-- account has been locked since its last successful login, and sets how long
+-- the next lock lasts.
+ALTER TABLE users ADD COLUMN failed_login_attempts INTEGER NOT NULL DEFAULT 0 CHECK (failed_login_attempts >= 0);
+ALTER TABLE users ADD COLUMN failure_window_started_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN last_failed_login_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN lockout_count INTEGER NOT NULL DEFAULT 0 CHECK (lockout_count >= 0);
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP WITH TIME ZONE;
+
+-- Create index
+CREATE INDEX idx_users_locked_until ON users(locked_until) WHERE locked_until IS NOT NULL;
+
+-- Failed logins, lockouts and unlocks are audited
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'login_failed';
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'user_locked';
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'user_unlocked';
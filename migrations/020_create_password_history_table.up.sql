@@ -0,0 +1,12 @@
+-- Create password_history table
+-- The hashes of a user's previous passwords, newest first, so that recently
+-- used passwords can be refused. The current password stays in users.
+CREATE TABLE IF NOT EXISTS password_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX idx_password_history_user_id ON password_history(user_id, created_at DESC);
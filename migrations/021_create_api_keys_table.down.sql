@@ -0,0 +1,8 @@
+-- Enum values added to audit_action cannot be dropped without recreating the
+-- type, so they are left in place.
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_api_keys_user_id;
+
+-- Drop api_keys table
+DROP TABLE IF EXISTS api_keys;
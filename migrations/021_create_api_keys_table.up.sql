@@ -0,0 +1,26 @@
+-- Create api_keys table
+-- Keys let business account holders call the API from their own systems.
+-- Only a SHA-256 hash of each key is kept; prefix is the key's public,
+-- unique start and is used to find it. scopes lists what the key may do and
+-- allowed_ips, when not empty, the addresses and CIDR ranges it may be used from.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(32) NOT NULL UNIQUE,
+    key_hash CHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    allowed_ips TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    last_used_ip INET,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id, created_at DESC);
+
+-- Creating and revoking API keys is audited
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'api_key_created';
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'api_key_revoked';
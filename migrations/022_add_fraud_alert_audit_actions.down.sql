@@ -0,0 +1,2 @@
+-- Enum values added to audit_action cannot be dropped without recreating the
+-- type, so they are left in place.
@@ -0,0 +1,3 @@
+-- Assigning fraud alerts and moving them through the case workflow are audited
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'fraud_alert_assigned';
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'fraud_alert_updated';
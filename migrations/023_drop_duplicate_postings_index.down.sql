@@ -0,0 +1,2 @@
+-- idx_postings_account_id already covers (account_id, created_at), so the
+-- duplicate is not recreated.
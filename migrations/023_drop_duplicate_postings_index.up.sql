@@ -0,0 +1,3 @@
+-- Drop the postings index older databases got from migration 010; it
+-- duplicated idx_postings_account_id on (account_id, created_at)
+DROP INDEX IF EXISTS idx_postings_account_id_created_at;
@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_password_reset_requests_requested_at;
+DROP INDEX IF EXISTS idx_password_reset_requests_email;
+
+-- Drop password_reset_requests table
+DROP TABLE IF EXISTS password_reset_requests;
@@ -0,0 +1,13 @@
+-- Create password_reset_requests table
+-- Forgot-password requests wait here until a worker emails the token, so a
+-- burst of requests is worked through rather than dropped. An address has at
+-- most one request waiting.
+CREATE TABLE IF NOT EXISTS password_reset_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email VARCHAR(255) NOT NULL,
+    requested_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX idx_password_reset_requests_email ON password_reset_requests(LOWER(email));
+CREATE INDEX idx_password_reset_requests_requested_at ON password_reset_requests(requested_at);
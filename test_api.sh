@@ -1,6 +1,16 @@
 #!/bin/bash
 
+# Exercises the API end to end. Accounts and money movements need a verified
+# email address, so start the server with MAIL_BACKEND=file: the script reads
+# the verification token from the newest email in MAIL_FILE_DIR. Run it from
+# the server's working directory or point MAIL_FILE_DIR at the same place.
+
 BASE_URL="http://localhost:8080/api/v1"
+MAIL_FILE_DIR=${MAIL_FILE_DIR:-./mail}
+EMAIL="john.doe@example.com"
+# Both passwords meet the default PASSWORD_* policy
+PASSWORD="Maple-Harbor-2048"
+NEW_PASSWORD="Quiet-Lantern-7391"
 
 echo "🚀 Testing Financial Transaction System API"
 echo "============================================="
@@ -14,13 +24,13 @@ echo ""
 echo "👤 Testing user registration..."
 REGISTER_RESPONSE=$(curl -s -X POST "$BASE_URL/auth/register" \
   -H "Content-Type: application/json" \
-  -d '{
-    "email": "john.doe@example.com",
-    "password": "securepassword123",
-    "first_name": "John",
-    "last_name": "Doe",
-    "phone": "+1234567890"
-  }')
+  -d "{
+    \"email\": \"$EMAIL\",
+    \"password\": \"$PASSWORD\",
+    \"first_name\": \"John\",
+    \"last_name\": \"Doe\",
+    \"phone\": \"+1234567890\"
+  }")
 
 echo "$REGISTER_RESPONSE" | jq '.'
 
@@ -29,14 +39,29 @@ ACCESS_TOKEN=$(echo "$REGISTER_RESPONSE" | jq -r '.access_token')
 echo "🔑 Access Token: ${ACCESS_TOKEN:0:50}..."
 echo ""
 
+# Test email verification
+echo "📧 Testing email verification..."
+VERIFICATION_MAIL=$(grep -l "^To: $EMAIL" "$MAIL_FILE_DIR"/*.eml 2>/dev/null | xargs -r grep -l "^Subject: Verify your email address" | sort | tail -n 1)
+if [ -n "$VERIFICATION_MAIL" ]; then
+  # The token, or a link carrying it, is two lines below the instruction
+  VERIFICATION_TOKEN=$(grep -A 2 "verify your email address:" "$VERIFICATION_MAIL" | tail -n 1 | tr -d '\r' | sed 's/.*token=//')
+  curl -s -X POST "$BASE_URL/auth/verify-email" \
+    -H "Content-Type: application/json" \
+    -d "{\"token\": \"$VERIFICATION_TOKEN\"}" | jq '.'
+else
+  echo "⚠️ No verification email in $MAIL_FILE_DIR; start the server with MAIL_BACKEND=file."
+  echo "   Account and transaction requests below will be refused with 403."
+fi
+echo ""
+
 # Test user login
 echo "🔐 Testing user login..."
 LOGIN_RESPONSE=$(curl -s -X POST "$BASE_URL/auth/login" \
   -H "Content-Type: application/json" \
-  -d '{
-    "email": "john.doe@example.com",
-    "password": "securepassword123"
-  }')
+  -d "{
+    \"email\": \"$EMAIL\",
+    \"password\": \"$PASSWORD\"
+  }")
 
 echo "$LOGIN_RESPONSE" | jq '.'
 echo ""
@@ -58,15 +83,40 @@ curl -s -X PUT "$BASE_URL/users/profile" \
   }' | jq '.'
 echo ""
 
+# Test account creation
+echo "🏦 Testing account creation..."
+ACCOUNT_RESPONSE=$(curl -s -X POST "$BASE_URL/accounts" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $ACCESS_TOKEN" \
+  -d '{
+    "account_type": "checking",
+    "account_name": "John'"'"'s Checking Account",
+    "currency": "USD",
+    "daily_limit": 5000.00,
+    "monthly_limit": 50000.00
+  }')
+
+echo "$ACCOUNT_RESPONSE" | jq '.'
+ACCOUNT_ID=$(echo "$ACCOUNT_RESPONSE" | jq -r '.id')
+echo ""
+
+# Test deposit
+echo "💵 Testing deposit..."
+curl -s -X POST "$BASE_URL/transactions/deposit" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $ACCESS_TOKEN" \
+  -d "{\"to_account_id\": \"$ACCOUNT_ID\", \"amount\": 100.00, \"currency\": \"USD\"}" | jq '.'
+echo ""
+
 # Test change password
 echo "🔒 Testing change password..."
 curl -s -X POST "$BASE_URL/users/change-password" \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $ACCESS_TOKEN" \
-  -d '{
-    "current_password": "securepassword123",
-    "new_password": "newsecurepassword456"
-  }' | jq '.'
+  -d "{
+    \"current_password\": \"$PASSWORD\",
+    \"new_password\": \"$NEW_PASSWORD\"
+  }" | jq '.'
 echo ""
 
 # Test refresh token